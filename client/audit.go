@@ -0,0 +1,81 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// Summarize returns a summary of the body of a request to an API endpoint, for
+// an audit log, in which every secret is replaced by its public webcash, so
+// that what was submitted can be reconstructed from it without anyone being
+// able to spend from it.  It is nil for requests without a body, or of an
+// endpoint it doesn't know.
+func Summarize(path string, body []byte) interface{} {
+	switch path {
+	case "/api/v1/replace":
+		var request struct {
+			Inputs  []string `json:"webcashes"`
+			Outputs []string `json:"new_webcashes"`
+		}
+		if json.Unmarshal(body, &request) != nil {
+			return "unreadable"
+		}
+		return map[string]interface{}{
+			"inputs":  redact(request.Inputs),
+			"outputs": redact(request.Outputs),
+		}
+	case "/api/v1/mining_report":
+		var report struct {
+			Preimage string      `json:"preimage"`
+			Work     json.Number `json:"work"`
+		}
+		if json.Unmarshal(body, &report) != nil {
+			return "unreadable"
+		}
+		hash := sha256.Sum256([]byte(report.Preimage))
+		summary := map[string]interface{}{
+			"hash": hex.EncodeToString(hash[:]),
+			"work": report.Work,
+		}
+		var payload struct {
+			Webcash    []string    `json:"webcash"`
+			Subsidy    []string    `json:"subsidy"`
+			Difficulty json.Number `json:"difficulty"`
+			Timestamp  json.Number `json:"timestamp"`
+		}
+		decoded, err := base64.StdEncoding.DecodeString(report.Preimage)
+		if err == nil && json.Unmarshal(decoded, &payload) == nil {
+			summary["webcash"] = redact(payload.Webcash)
+			summary["subsidy"] = redact(payload.Subsidy)
+			summary["difficulty"] = payload.Difficulty
+			summary["timestamp"] = payload.Timestamp
+		}
+		webcash.Wipe(decoded)
+		return summary
+	case "/api/v1/health_check":
+		var request []string
+		if json.Unmarshal(body, &request) != nil {
+			return "unreadable"
+		}
+		return map[string]interface{}{"webcash": request}
+	}
+	return nil
+}
+
+// redact returns the public webcash of claim codes.  What does not parse is
+// replaced by "unreadable", as it could be anything.
+func redact(codes []string) []string {
+	public := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if sk, err := webcash.ParseSecretWebcash(code); err == nil {
+			public = append(public, webcash.FromSecret(sk).String())
+		} else {
+			public = append(public, "unreadable")
+		}
+	}
+	return public
+}
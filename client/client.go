@@ -0,0 +1,381 @@
+// Package client implements the webcash server's HTTP API.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// The public webcash server.
+const DefaultServer = "https://webcash.org"
+
+// ErrMalformedResponse is wrapped by errors returned when the server's reply
+// could not be understood.
+var ErrMalformedResponse = errors.New("malformed server response")
+
+// A ServerError is a well-formed rejection of a request by the server.
+type ServerError struct {
+	// The API endpoint the request was made of.
+	Path string
+	// The HTTP status code of the response.
+	StatusCode int
+	// The error message given by the server.
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("%s: server returned %d: %s", e.Path, e.StatusCode, e.Message)
+}
+
+// Is matches the server's error messages against the webcash package's
+// sentinel errors, so that callers can write errors.Is(err,
+// webcash.ErrSecretAlreadySpent).
+func (e *ServerError) Is(target error) bool {
+	msg := strings.ToLower(e.Message)
+	switch target {
+	case webcash.ErrSecretAlreadySpent:
+		return strings.Contains(msg, "spent")
+	case webcash.ErrTermsNotAccepted:
+		return strings.Contains(msg, "terms")
+	}
+	return false
+}
+
+// A Client makes requests of a webcash server.  Its methods are safe for
+// concurrent use.
+type Client struct {
+	// The base URL of the server, e.g. DefaultServer.
+	Server string
+	// The HTTP client used to make requests.  Connections are pooled and
+	// reused by the client's transport.
+	HTTPClient *http.Client
+}
+
+// New returns a client for the given server, using an HTTP client with the
+// given connection pool settings.
+func New(server string, pool PoolConfig) *Client {
+	return &Client{
+		Server:     server,
+		HTTPClient: NewHTTPClient(pool),
+	}
+}
+
+// PoolConfig holds the connection pool limits of an HTTP client.  A limit of
+// zero means no limit, as with http.Transport.
+type PoolConfig struct {
+	// Maximum number of idle connections kept open, across all hosts.
+	MaxIdleConns int
+	// Maximum number of idle connections kept open to any one host.
+	MaxIdleConnsPerHost int
+	// Maximum number of connections to any one host, idle or not.
+	MaxConnsPerHost int
+	// How long an idle connection is kept open.
+	IdleConnTimeout time.Duration
+}
+
+// DefaultPoolConfig is suitable for a single miner talking to one server.
+var DefaultPoolConfig = PoolConfig{
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 8,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// NewHTTPClient creates an HTTP client with the given connection pool limits,
+// which keeps count of how often pooled connections are reused.
+func NewHTTPClient(pool PoolConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = pool.MaxIdleConns
+	transport.MaxIdleConnsPerHost = pool.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = pool.MaxConnsPerHost
+	transport.IdleConnTimeout = pool.IdleConnTimeout
+	return &http.Client{
+		Transport: &reuse_counting_transport{inner: transport},
+	}
+}
+
+// A reuse_counting_transport wraps an http.RoundTripper, recording whether
+// each request was sent over a fresh or a pooled connection.
+type reuse_counting_transport struct {
+	inner http.RoundTripper
+	// Counters of how many requests were made over new and reused
+	// connections.
+	conns_new    uint64
+	conns_reused uint64
+}
+
+func (t *reuse_counting_transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				atomic.AddUint64(&t.conns_reused, 1)
+			} else {
+				atomic.AddUint64(&t.conns_new, 1)
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	return t.inner.RoundTrip(req)
+}
+
+// ConnReuse reports how many requests have been made, and how many of those
+// reused a pooled connection.  Both are zero if the client's HTTPClient was
+// not created by NewHTTPClient.
+func (c *Client) ConnReuse() (reused, total uint64) {
+	t, ok := c.http_client().Transport.(*reuse_counting_transport)
+	if !ok {
+		return 0, 0
+	}
+	reused = atomic.LoadUint64(&t.conns_reused)
+	return reused, reused + atomic.LoadUint64(&t.conns_new)
+}
+
+func (c *Client) http_client() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// TermsOfService fetches the text of the server's terms of service.
+func (c *Client) TermsOfService() (string, error) {
+	resp, err := c.http_client().Get(c.Server + "/terms/text")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// Target fetches the server's current protocol settings.
+func (c *Client) Target() (webcash.ProtocolSettings, error) {
+	resp, err := c.http_client().Get(c.Server + "/api/v1/target")
+	if err != nil {
+		return webcash.ProtocolSettings{}, err
+	}
+	defer resp.Body.Close()
+
+	var settings webcash.ProtocolSettings
+	err = json.NewDecoder(resp.Body).Decode(&settings)
+	if err != nil {
+		return webcash.ProtocolSettings{}, fmt.Errorf("%w: target: %v", ErrMalformedResponse, err)
+	}
+
+	return settings, nil
+}
+
+// ServerStats are the server's statistics of issuance so far.
+type ServerStats struct {
+	// The number of mining reports accepted since the server began.
+	MiningReports uint64 `json:"mining_reports"`
+	// The current epoch and difficulty, as given by Target.
+	Epoch      uint16 `json:"epoch"`
+	Difficulty uint8  `json:"difficulty_target_bits"`
+}
+
+// Stats fetches the server's issuance statistics.
+func (c *Client) Stats() (ServerStats, error) {
+	resp, err := c.http_client().Get(c.Server + "/api/v1/stats")
+	if err != nil {
+		return ServerStats{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ServerStats{}, &ServerError{Path: "/api/v1/stats", StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	}
+
+	var stats ServerStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return ServerStats{}, fmt.Errorf("%w: stats: %v", ErrMalformedResponse, err)
+	}
+	return stats, nil
+}
+
+type MiningReport struct {
+	// The hash of the solution.
+	Hash webcash.Uint256
+	// The base64-encoded mining payload.
+	Preimage string
+}
+
+func (report MiningReport) MarshalJSON() ([]byte, error) {
+	// Serialize preimage as string
+	preimage, err := json.Marshal(report.Preimage)
+	if err != nil {
+		return nil, err
+	}
+	// Convert hash to decimal notation
+	work := new(big.Int).SetBytes(report.Hash[:]).String()
+	// Serialize as JSON
+	return []byte(fmt.Sprintf(`{"preimage":%s,"work":%s,"legalese":{"terms":true}}`, preimage, string(work))), nil
+}
+
+// The server's response to a mining report.
+type MiningReportResponse struct {
+	// The HTTP status code of the response.
+	StatusCode int
+	// The error message given by the server, if any.
+	Error string
+	// The difficulty the server now requires, if it said.
+	Difficulty    uint8
+	HasDifficulty bool
+}
+
+// Accepted reports whether the server accepted the mining report.  A report
+// whose secrets the server has already seen is taken as accepted, since that
+// means an earlier submission of the same report got through.
+func (resp MiningReportResponse) Accepted() bool {
+	return resp.StatusCode == 200 || (resp.StatusCode == 400 && resp.Error == "Didn't use a new secret value.")
+}
+
+// SubmitMiningReport sends a mining report to the server.  An error is
+// returned if no well-formed response was received, which is likely to be a
+// transient problem; a well-formed rejection is not an error, but is reported
+// by the response's Accepted method.
+func (c *Client) SubmitMiningReport(report MiningReport) (MiningReportResponse, error) {
+	// Serialize the mining report as JSON
+	body, err := json.Marshal(report)
+	if err != nil {
+		// Should never happen!
+		return MiningReportResponse{}, fmt.Errorf("failed to serialize mining report: %w", err)
+	}
+
+	// Send the mining report to the server, then wipe it, as the preimage
+	// holds the secret mined.
+	resp, err := c.http_client().Post(c.Server+"/api/v1/mining_report", "application/json", bytes.NewReader(body))
+	webcash.Wipe(body)
+	if err != nil {
+		return MiningReportResponse{}, fmt.Errorf("invalid server response to mining report request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read the response body
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return MiningReportResponse{}, fmt.Errorf("%w: invalid message body in response to mining report request: %v", ErrMalformedResponse, err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return MiningReportResponse{}, fmt.Errorf("%w: response to mining report request is not a JSON object: %v", ErrMalformedResponse, err)
+	}
+
+	response := MiningReportResponse{StatusCode: resp.StatusCode}
+	if msg, ok := result["error"]; ok {
+		response.Error = fmt.Sprint(msg)
+	}
+	if difficulty, ok := result["difficulty_target"].(float64); ok {
+		response.Difficulty = uint8(difficulty)
+		response.HasDifficulty = true
+	}
+	return response, nil
+}
+
+// post_json sends request to the given API endpoint as JSON, and decodes the
+// JSON response into response.  A rejection by the server is returned as a
+// *ServerError.
+func (c *Client) post_json(path string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to serialize %s request: %w", path, err)
+	}
+	// Requests such as replacements hold secrets.
+	resp, err := c.http_client().Post(c.Server+path, "application/json", bytes.NewReader(body))
+	webcash.Wipe(body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: invalid message body in response to %s: %v", ErrMalformedResponse, path, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(body, &failure); err != nil || failure.Error == "" {
+			failure.Error = http.StatusText(resp.StatusCode)
+		}
+		return &ServerError{Path: path, StatusCode: resp.StatusCode, Message: failure.Error}
+	}
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("%w: response to %s is not valid JSON: %v", ErrMalformedResponse, path, err)
+	}
+	return nil
+}
+
+// Replace atomically spends the input webcash and creates the outputs, which
+// must be of equal total value.  If an input has already been spent, the
+// error matches webcash.ErrSecretAlreadySpent.
+func (c *Client) Replace(inputs, outputs []webcash.SecretWebcash) error {
+	request := struct {
+		Inputs   []string        `json:"webcashes"`
+		Outputs  []string        `json:"new_webcashes"`
+		Legalese map[string]bool `json:"legalese"`
+	}{
+		Legalese: map[string]bool{"terms": true},
+	}
+	for _, sk := range inputs {
+		request.Inputs = append(request.Inputs, sk.String())
+	}
+	for _, sk := range outputs {
+		request.Outputs = append(request.Outputs, sk.String())
+	}
+
+	var response struct {
+		Status string `json:"status"`
+	}
+	return c.post_json("/api/v1/replace", request, &response)
+}
+
+// HealthStatus is the server's record of a public webcash.
+type HealthStatus struct {
+	// Whether the webcash has been spent, or nil if the server has no record
+	// of it.
+	Spent *bool `json:"spent"`
+	// The amount on record, or nil if the server has no record of it.
+	Amount *webcash.Amount `json:"amount"`
+}
+
+// HealthCheck asks the server whether each of the given public webcash exists
+// and has been spent.  The results are indexed by public hash.
+func (c *Client) HealthCheck(pks []webcash.PublicWebcash) (map[webcash.Uint256]HealthStatus, error) {
+	request := make([]string, 0, len(pks))
+	for _, pk := range pks {
+		request = append(request, pk.String())
+	}
+
+	var response struct {
+		Status  string                  `json:"status"`
+		Results map[string]HealthStatus `json:"results"`
+	}
+	if err := c.post_json("/api/v1/health_check", request, &response); err != nil {
+		return nil, err
+	}
+
+	results := make(map[webcash.Uint256]HealthStatus, len(response.Results))
+	for code, result := range response.Results {
+		pk, err := webcash.ParsePublicWebcash(code)
+		if err != nil {
+			return nil, fmt.Errorf("%w: health check: %v", ErrMalformedResponse, err)
+		}
+		results[pk.Hash] = result
+	}
+	return results, nil
+}
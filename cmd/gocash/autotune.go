@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/maaku/gocash/miner"
+)
+
+// The improvement in hashrate a change in thread count must show to be kept,
+// so that measurement noise doesn't make the count wander.
+const autotune_threshold = 1.02
+
+// How long to let the hashrate settle after resizing before measuring it.
+const autotune_settle = 5 * time.Second
+
+// autotune_thread searches for the number of mining threads with the best
+// sustained hashrate, starting from the configured count.  It tries one
+// thread more and one fewer, keeping whichever does best, until neither
+// improves on the current count; it then checks again every ten intervals,
+// in case conditions have changed.  This matters on hyperthreaded and
+// heterogeneous CPUs, where the best count is often not the number of CPUs.
+func autotune_thread(ctx context.Context, m *miner.Miner, workers int, cpus []int, interval time.Duration) {
+	// measure runs n threads for an interval and returns their hashrate, or
+	// a negative rate if cancelled first.
+	measure := func(n int) float64 {
+		m.Resize(n, cpus)
+		if !sleep_ctx(ctx, autotune_settle) {
+			return -1
+		}
+		start, before := time.Now(), m.Attempts()
+		if !sleep_ctx(ctx, interval) {
+			return -1
+		}
+		return float64(m.Attempts()-before) / time.Since(start).Seconds()
+	}
+
+	best := m.Resize(workers, cpus)
+	max := 2 * runtime.NumCPU()
+	for {
+		best_rate := measure(best)
+		if best_rate < 0 {
+			return
+		}
+		improved := false
+		for _, n := range []int{best + 1, best - 1} {
+			if n < 1 || n > max {
+				continue
+			}
+			rate := measure(n)
+			if rate < 0 {
+				return
+			}
+			if rate > best_rate*autotune_threshold {
+				say("autotune: %d mining threads do better than %d (%s vs %s)", n, best, format_hashrate(rate), format_hashrate(best_rate))
+				best, improved = n, true
+				break
+			}
+		}
+		m.Resize(best, cpus)
+		if !improved {
+			say("autotune: keeping %d mining threads (%s)", best, format_hashrate(best_rate))
+			if !sleep_ctx(ctx, 10*interval) {
+				return
+			}
+		}
+	}
+}
+
+// sleep_ctx waits for d, returning false if ctx is cancelled first.
+func sleep_ctx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
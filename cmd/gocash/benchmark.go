@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/maaku/gocash/miner"
+	"github.com/maaku/gocash/webcash"
+)
+
+// The difficulty mined against in benchmark mode: low enough that solutions
+// turn up every few seconds, so that their handling is exercised too.
+const benchmark_difficulty = 24
+
+// benchmark_settings stand in for the server's, with rewards of a realistic
+// size so that the payloads are too.
+var benchmark_settings = webcash.ProtocolSettings{
+	Difficulty:    benchmark_difficulty,
+	Ratio:         1,
+	TotalReward:   20_000_000_000_000,
+	ServerSubsidy: 1_000_000_000_000,
+}
+
+// run_benchmark mines against benchmark_settings for the given duration
+// without contacting the server, checking each solution found but
+// submitting none, and reports the hashrate of each thread and in total.
+func run_benchmark(duration time.Duration, gomaxprocs, workers int, cpu_list string, max_cpu, batch_size int) int {
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(c)
+		select {
+		case <-c:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	say("Using SHA256 algorithm: %s", miner.Algorithm())
+	say("Benchmarking for %v at difficulty %d, without contacting the server", duration, benchmark_difficulty)
+
+	solutions := miner.NewSolutionQueue(16)
+	m := miner.New(ctx, benchmark_settings, solutions)
+	m.Log = func(format string, args ...interface{}) {
+		say(format, args...)
+	}
+
+	// Check solutions the way they would be before submission, including
+	// that the preimage really hashes to the claimed hash.
+	var found, invalid int
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case soln := <-solutions.C():
+				if webcash.Uint256(sha256.Sum256([]byte(soln.Preimage))) != soln.Hash || !webcash.CheckProofOfWork(soln.Hash, benchmark_difficulty) {
+					say("Error: invalid solution %v", soln.Hash)
+					invalid++
+					continue
+				}
+				found++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	started := time.Now()
+	if err := apply_runtime_settings(m, gomaxprocs, workers, cpu_list, max_cpu, batch_size, 0, time.Second, time.Second); err != nil {
+		say("Error: %v", err)
+		return 2
+	}
+	<-ctx.Done()
+	elapsed := time.Since(started)
+	counts := m.ThreadAttempts()
+	m.Wait()
+	wg.Wait()
+
+	var total uint64
+	for id, attempts := range counts {
+		say("thread %d: %s", id, get_speed_string(attempts, elapsed))
+		total += attempts
+	}
+	say("total: %s from %d threads over %v, %d solutions found", get_speed_string(total, elapsed), len(counts), elapsed.Round(time.Millisecond), found)
+	if invalid > 0 {
+		return 1
+	}
+	return 0
+}
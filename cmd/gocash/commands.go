@@ -0,0 +1,322 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maaku/gocash/internal/i18n"
+	"github.com/maaku/gocash/internal/price"
+	"github.com/maaku/gocash/internal/stats"
+	"github.com/maaku/gocash/internal/style"
+	"github.com/maaku/gocash/miner"
+	"github.com/maaku/gocash/webcash"
+)
+
+// g_commands are the subcommands, run as `gocash <command> [args]`.  Each
+// returns the process exit status.  Run without a command, gocash mines.
+var g_commands = map[string]func(args []string) int{
+	"balance":  run_balance,
+	"check":    run_check,
+	"gpus":     run_gpus,
+	"history":  run_history,
+	"init":     run_init,
+	"insert":   run_insert,
+	"label":    run_label,
+	"list":     run_list,
+	"merge":    run_merge,
+	"paper":    run_paper,
+	"paths":    run_paths,
+	"pay":      run_pay,
+	"recover":  run_recover,
+	"request":  run_request,
+	"selftest": run_selftest,
+	"stats":    run_stats,
+	"status":   run_status,
+	"wallet":   run_wallet,
+	"watch":    run_watch,
+	"wipe":     run_wipe,
+}
+
+// command_names returns the subcommands, for usage messages.
+func command_names() string {
+	names := make([]string, 0, len(g_commands))
+	for name := range g_commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parse_time_bound parses a time given either as RFC 3339 or as a duration
+// before now, e.g. "24h".  The empty string is the zero time.
+func parse_time_bound(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected RFC 3339 or a duration such as \"24h\"", value)
+	}
+	return t, nil
+}
+
+// run_stats prints the lifetime totals, or with a subcommand, summarizes or
+// exports a range of the recorded stats.
+func run_stats(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return run_stats_summary(args)
+	}
+	if args[0] == "summary" {
+		return run_stats_summary(args[1:])
+	}
+	if args[0] != "export" {
+		say("Usage: gocash stats [export|summary] [flags]")
+		return 2
+	}
+	flags := flag.NewFlagSet("stats export", flag.ContinueOnError)
+	format := flags.String("format", "csv", T("output format, \"csv\" or \"json\""))
+	since := flags.String("since", "", T("only export samples after this time, RFC 3339 or a duration before now such as \"24h\""))
+	until := flags.String("until", "", T("only export samples before this time, RFC 3339 or a duration before now"))
+	input := flags.String("file", g_paths.StatsFile(), T("stats file to read"))
+	output := flags.String("o", "", T("file to write to (default: standard output)"))
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	now := time.Now()
+	from, err := parse_time_bound(*since, now)
+	if err != nil {
+		say("Error: -since: %v", err)
+		return 2
+	}
+	to, err := parse_time_bound(*until, now)
+	if err != nil {
+		say("Error: -until: %v", err)
+		return 2
+	}
+	var write func(io.Writer, []stats.Sample) error
+	switch *format {
+	case "csv":
+		write = stats.WriteCSV
+	case "json":
+		write = stats.WriteJSON
+	default:
+		say("Error: -format: unknown format %q", *format)
+		return 2
+	}
+
+	samples, err := stats.Open(*input).Load(from, to)
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+	}
+	err = write(out, samples)
+	if out != os.Stdout {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	return 0
+}
+
+func run_stats_summary(args []string) int {
+	flags := flag.NewFlagSet("stats summary", flag.ContinueOnError)
+	since := flags.String("since", "", T("only summarize samples after this time, RFC 3339 or a duration before now such as \"24h\""))
+	until := flags.String("until", "", T("only summarize samples before this time, RFC 3339 or a duration before now"))
+	input := flags.String("file", g_paths.StatsFile(), T("stats file to read"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	now := time.Now()
+	from, err := parse_time_bound(*since, now)
+	if err != nil {
+		say("Error: -since: %v", err)
+		return 2
+	}
+	to, err := parse_time_bound(*until, now)
+	if err != nil {
+		say("Error: -until: %v", err)
+		return 2
+	}
+	samples, err := stats.Open(*input).Load(from, to)
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+
+	sum := stats.Summarize(samples)
+	if len(samples) == 0 {
+		say("Nothing recorded in %s.", *input)
+		return 0
+	}
+	say("Since %s: %d hashes attempted, %v earned", sum.Since.Local().Format("2006-01-02 15:04"), sum.Attempts, sum.Earned)
+	say("Mined for %v at an average of %s", sum.Elapsed.Round(time.Second), format_hashrate(sum.Hashrate()))
+	say("%d mining reports answered: %d accepted, %d rejected (%.1f%% accepted)", sum.Accepted+sum.Rejected, sum.Accepted, sum.Rejected, 100*sum.AcceptanceRate())
+	say("Luck %s: %d solutions accepted where %.2f were expected", format_luck(sum.Luck(), sum.Expected), sum.Accepted, sum.Expected)
+	for _, cause := range []string{stats.CauseDifficulty, stats.CauseStale, stats.CauseDuplicate, stats.CauseTerms, stats.CauseOther} {
+		if n := sum.Causes[cause]; n > 0 {
+			fmt.Printf("  %-12s %d\n", T(cause), n)
+		}
+	}
+	return 0
+}
+
+// recent_hashrate returns the average hashrate recorded in the stats file over
+// the given period, or zero if there was no mining then.
+func recent_hashrate(period time.Duration) (float64, error) {
+	samples, err := g_stats.Load(time.Now().Add(-period), time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	var attempts uint64
+	var elapsed time.Duration
+	for _, s := range samples {
+		if s.Kind == stats.KindHashrate {
+			attempts += s.Attempts
+			elapsed += s.Elapsed
+		}
+	}
+	if elapsed <= 0 {
+		return 0, nil
+	}
+	return float64(attempts) / elapsed.Seconds(), nil
+}
+
+func run_status(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	epochs := flags.Bool("epochs", false, T("project the upcoming epochs and their rewards"))
+	count := flags.Int("epoch-count", 6, T("number of epochs to project, including the current one"))
+	price_source := flags.String("price", config_setting("price", ""), T("where to get the price of webcash, for showing what it is worth: \"fixed:<currency>:<price>\" or \"json:<currency>:<field>:<url>\""))
+	hashrate := flags.Float64("hashrate", 0, T("hashes per second to estimate mining rewards for (default: the average over the last day of recorded mining)"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	provider, err := price.Parse(*price_source)
+	if err != nil {
+		say("Error: -price: %v", err)
+		return 2
+	}
+
+	settings, err := g_client.Target()
+	if err != nil {
+		say("Error: unable to fetch mining target: %v", err)
+		return 1
+	}
+	say("Server: %s", g_client.Server)
+	say("Epoch %d, difficulty %d, ratio %v", settings.Epoch, settings.Difficulty, settings.Ratio)
+	say_as(style.Value, "Mining reward %v, of which %v is the server subsidy", settings.TotalReward, settings.ServerSubsidy)
+	if value := describe_value(provider, settings.TotalReward-settings.ServerSubsidy); value != "" {
+		say_as(style.Value, "The miner keeps %v%s", settings.TotalReward-settings.ServerSubsidy, value)
+	}
+	speed := *hashrate
+	if speed <= 0 {
+		if speed, err = recent_hashrate(24 * time.Hour); err != nil {
+			say("Warning: %v", err)
+		}
+	}
+	if speed > 0 {
+		yield := expected_yield(speed, settings)
+		say_as(style.Value, "At %s, expect a solution every %s and %v per day%s", format_hashrate(speed), format_expect(speed, settings.Difficulty), yield, describe_value(provider, yield))
+	}
+	if !*epochs {
+		return 0
+	}
+
+	// The position within the epoch is only known if the server publishes
+	// how many reports it has accepted.  Without it, assume the epoch has
+	// just begun, which makes the projected dates the latest they could be.
+	schedule := webcash.DefaultSchedule
+	var into_epoch uint64
+	if server_stats, err := g_client.Stats(); err == nil {
+		if epoch_start := uint64(settings.Epoch) * schedule.ReportsPerEpoch; server_stats.MiningReports > epoch_start {
+			into_epoch = server_stats.MiningReports - epoch_start
+		}
+		say("%d mining reports so far, %d of %d into this epoch", server_stats.MiningReports, into_epoch, schedule.ReportsPerEpoch)
+	} else {
+		say("Mining report count unavailable (%v); dates are the latest the epochs could begin.", err)
+	}
+
+	fmt.Println()
+	fmt.Printf("%-6s %-20s %20s %20s\n", T("epoch"), T("starts"), T("miner reward"), T("subsidy"))
+	for _, p := range schedule.Project(settings, into_epoch, time.Now(), *count) {
+		start := T("now")
+		if p.Epoch != settings.Epoch {
+			start = "~" + p.Start.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-6d %-20s %20v %20v\n", p.Epoch, start, p.MinerReward(), p.ServerSubsidy)
+	}
+	return 0
+}
+
+func run_paths(args []string) int {
+	flags := flag.NewFlagSet("paths", flag.ContinueOnError)
+	create := flags.Bool("create", false, T("create the directories, with private permissions, if they do not exist"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *create {
+		if err := g_paths.Create(); err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+	}
+	for _, p := range []struct{ name, path string }{
+		{"config", g_paths.Config},
+		{"config file", g_paths.ConfigFile()},
+		{"terms", g_paths.TermsFile()},
+		{"wallet", g_paths.Wallet},
+		{"wallet file", g_paths.WalletFile()},
+		{"wallet db", g_paths.WalletDB()},
+		{"mining log", g_paths.MiningLog()},
+		{"pending log", g_paths.PendingLog()},
+		{"log", g_paths.Log},
+		{"orphan log", g_paths.OrphanLog()},
+		{"stats", g_paths.StatsFile()},
+		{"events", g_paths.EventsFile()},
+		{"cache", g_paths.Cache},
+	} {
+		fmt.Printf("%-12s %s\n", T(p.name), p.path)
+	}
+	return 0
+}
+
+func run_gpus(args []string) int {
+	flags := flag.NewFlagSet("gpus", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	devices, err := miner.GPUDevices()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if len(devices) == 0 {
+		say("No OpenCL devices found.")
+		return 0
+	}
+	for _, dev := range devices {
+		fmt.Printf("%d  %s\n", dev.Index, i18n.Sprintf("%s (%s, %d compute units)", dev.Name, dev.Platform, dev.ComputeUnits))
+	}
+	return 0
+}
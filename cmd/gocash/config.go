@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Flags which take effect when the config file is reloaded, the schedule within
+// schedule_interval and the rest immediately.  All
+// others are only read at startup.
+var reloadable_flags = map[string]bool{
+	"gomaxprocs": true,
+	"workers":    true,
+	"cpus":       true,
+	"max-cpu":    true,
+	"batch-size": true,
+	"margin":     true,
+	"poll-min":   true,
+	"poll-max":   true,
+	"schedule":   true,
+	"webhook":    true,
+}
+
+// subcommand_settings are settings which only subcommands read, with
+// config_setting, and which mining accepts in the config file but ignores.
+var subcommand_settings = map[string]bool{
+	"coin-selection": true,
+}
+
+// read_config_file parses a config file of "name = value" lines, where each
+// name is that of a command-line flag without the leading dash.  Blank lines
+// and lines beginning with '#' are ignored.
+func read_config_file(path string) (map[string]string, error) {
+	return parse_config_file(path, func(name string) bool {
+		return name != "config" && (flag.Lookup(name) != nil || subcommand_settings[name])
+	})
+}
+
+// config_setting returns a setting from the default config file, or fallback
+// if it is not set there.  Subcommands use it for the defaults of the flags
+// they share with mining, since their flags are not set from the file.
+func config_setting(name, fallback string) string {
+	config, err := parse_config_file(g_paths.ConfigFile(), func(string) bool { return true })
+	if value, ok := config[name]; err == nil && ok {
+		return value
+	}
+	return fallback
+}
+
+// parse_config_file parses a config file, rejecting settings for which known
+// returns false.
+func parse_config_file(path string, known func(name string) bool) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line_num := 1; scanner.Scan(); line_num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected \"name = value\"", path, line_num)
+		}
+		name = strings.TrimSpace(name)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if !known(name) {
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, line_num, name)
+		}
+		config[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// apply_config_file sets every flag named in the config file to its configured
+// value, as read by read_config_flags, except for flags given explicitly on the
+// command line, which take precedence.  No flag is changed unless every value
+// is valid.
+func apply_config_file(path string, only_reloadable bool) error {
+	scratch, err := read_config_flags(path, only_reloadable)
+	if err != nil {
+		return err
+	}
+	return commit_config_flags(path, scratch)
+}
+
+// The flags given on the command line, as found by command_line_flags.
+var g_command_line_flags map[string]bool
+
+// command_line_flags returns the flags given explicitly on the command line.
+// It is first called before the config file sets any, since flag.Visit no
+// longer tells them apart once it has.
+func command_line_flags() map[string]bool {
+	if g_command_line_flags == nil {
+		g_command_line_flags = make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) {
+			g_command_line_flags[f.Name] = true
+		})
+	}
+	return g_command_line_flags
+}
+
+// read_config_flags reads the config file into a scratch set of the
+// command-line flags, each starting from the value in use, and returns it
+// without changing any flag.  Flags given explicitly on the command line are
+// left alone, as are, if only_reloadable is set, flags which cannot be changed
+// while running; a warning is printed for any of those whose configured value
+// differs from the value in use.  Flags of types the scratch set cannot check,
+// such as -wallet, are only checked when committed.
+func read_config_flags(path string, only_reloadable bool) (*flag.FlagSet, error) {
+	config, err := read_config_file(path)
+	if err != nil {
+		return nil, err
+	}
+
+	from_command_line := command_line_flags()
+	scratch := flag.NewFlagSet(path, flag.ContinueOnError)
+	flag.VisitAll(func(f *flag.Flag) {
+		getter, ok := f.Value.(flag.Getter)
+		if !ok {
+			scratch.Var(&deferred_value{f.Value.String()}, f.Name, f.Usage)
+			return
+		}
+		switch value := getter.Get().(type) {
+		case bool:
+			scratch.Bool(f.Name, value, f.Usage)
+		case int:
+			scratch.Int(f.Name, value, f.Usage)
+		case uint:
+			scratch.Uint(f.Name, value, f.Usage)
+		case int64:
+			scratch.Int64(f.Name, value, f.Usage)
+		case uint64:
+			scratch.Uint64(f.Name, value, f.Usage)
+		case float64:
+			scratch.Float64(f.Name, value, f.Usage)
+		case string:
+			scratch.String(f.Name, value, f.Usage)
+		case time.Duration:
+			scratch.Duration(f.Name, value, f.Usage)
+		default:
+			scratch.Var(&deferred_value{f.Value.String()}, f.Name, f.Usage)
+		}
+	})
+
+	for name, value := range config {
+		if from_command_line[name] || subcommand_settings[name] {
+			continue
+		}
+		if only_reloadable && !reloadable_flags[name] {
+			if flag.Lookup(name).Value.String() != value {
+				say("Setting %q changed in %s, but only takes effect on restart", name, path)
+			}
+			continue
+		}
+		if err := scratch.Set(name, value); err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, name, err)
+		}
+	}
+	return scratch, nil
+}
+
+// commit_config_flags sets each flag which the config file at path set in
+// scratch, as returned by read_config_flags, to its value there.
+func commit_config_flags(path string, scratch *flag.FlagSet) error {
+	var err error
+	scratch.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		if serr := flag.Set(f.Name, f.Value.String()); serr != nil {
+			err = fmt.Errorf("%s: %s: %v", path, f.Name, serr)
+		}
+	})
+	return err
+}
+
+// A deferred_value holds the value of a flag in a scratch set which cannot
+// check it, until it is committed.
+type deferred_value struct {
+	value string
+}
+
+func (v *deferred_value) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *deferred_value) Set(value string) error {
+	v.value = value
+	return nil
+}
+
+// flag_value returns the value of a flag in fs, which must be of a type
+// implementing flag.Getter.
+func flag_value(fs *flag.FlagSet, name string) interface{} {
+	return fs.Lookup(name).Value.(flag.Getter).Get()
+}
+
+// set_config_value sets a setting in the config file, creating the file if
+// need be.  An existing line for the setting is replaced in place, so the
+// rest of the file, comments and all, is left as it was.
+func set_config_value(path, name, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	setting := name + " = " + value
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	found := false
+	for i, line := range lines {
+		key, _, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok && !strings.HasPrefix(strings.TrimSpace(line), "#") && strings.TrimSpace(key) == name {
+			lines[i] = setting
+			found = true
+		}
+	}
+	if !found {
+		lines = append(lines, setting)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
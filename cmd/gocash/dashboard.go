@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maaku/gocash/miner"
+	"github.com/maaku/gocash/webcash"
+)
+
+//go:embed dashboard.html
+var dashboard_html string
+
+// The dashboard page, whose labels are translated as it is rendered.
+var dashboard_template = template.Must(template.New("dashboard").Funcs(template.FuncMap{"T": T}).Parse(dashboard_html))
+
+// How often the dashboard's hashrate history is sampled, and how many samples
+// it keeps: an hour's worth.
+const (
+	dashboard_interval = 10 * time.Second
+	dashboard_samples  = 360
+)
+
+// A dashboard_point is a sample of the hashrate history.
+type dashboard_point struct {
+	Time     time.Time `json:"time"`
+	Hashrate float64   `json:"hashrate"`
+}
+
+// A dashboard_status is what the dashboard page fetches from /status.json.
+type dashboard_status struct {
+	Server     string            `json:"server"`
+	Difficulty uint8             `json:"difficulty"`
+	Epoch      uint16            `json:"epoch"`
+	Threads    int               `json:"threads"`
+	Hashes     uint64            `json:"hashes"`
+	Now        string            `json:"hashrate_now"`
+	OneMinute  string            `json:"hashrate_1m"`
+	Fifteen    string            `json:"hashrate_15m"`
+	Accepted   uint64            `json:"accepted"`
+	Rejected   uint64            `json:"rejected"`
+	Luck       string            `json:"luck"`
+	Expect     string            `json:"expect"`
+	MiningLog  webcash.Amount    `json:"mining_log_total"`
+	History    []dashboard_point `json:"history"`
+	Log        []string          `json:"log"`
+}
+
+// serve_dashboard serves a web page of the miner's status on addr until ctx is
+// cancelled.  It samples the hashrate in the meantime, for the page's graph.
+func serve_dashboard(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	var meter miner.HashrateMeter
+	var mutex sync.Mutex // protects history
+	var history []dashboard_point
+	meter.Update(g_miner.Attempts(), time.Now())
+	go func() {
+		ticker := time.NewTicker(dashboard_interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				meter.Update(g_miner.Attempts(), now)
+				mutex.Lock()
+				if len(history) == dashboard_samples {
+					history = history[1:]
+				}
+				history = append(history, dashboard_point{Time: now, Hashrate: meter.Rates().Current})
+				mutex.Unlock()
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		dashboard_template.Execute(w, nil)
+	})
+	mux.HandleFunc("/status.json", func(w http.ResponseWriter, r *http.Request) {
+		settings := g_miner.Settings()
+		accepted := atomic.LoadUint64(&g_accepted_reports)
+		expected := math.Float64frombits(atomic.LoadUint64(&g_expected_solutions))
+		status := dashboard_status{
+			Server:     g_client.Server,
+			Difficulty: settings.Difficulty,
+			Epoch:      settings.Epoch,
+			Threads:    len(g_miner.ThreadAttempts()),
+			Hashes:     g_miner.Attempts(),
+			Accepted:   accepted,
+			Rejected:   atomic.LoadUint64(&g_rejected_reports),
+			Luck:       format_luck(float64(accepted)/expected, expected),
+			Log:        recent_lines(),
+		}
+		status.MiningLog, _ = mining_log_total()
+		rates := meter.Rates()
+		status.Now, status.OneMinute, status.Fifteen = format_hashrate(rates.Current), format_hashrate(rates.OneMinute), format_hashrate(rates.FifteenMinute)
+		status.Expect = format_expect(rates.FifteenMinute, settings.Difficulty)
+		mutex.Lock()
+		status.History = append([]dashboard_point(nil), history...)
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+
+	// Control of the miner.
+	mux.HandleFunc("/pause", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "expected POST", http.StatusMethodNotAllowed)
+			return
+		}
+		set_manual_pause(true)
+	})
+	mux.HandleFunc("/resume", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "expected POST", http.StatusMethodNotAllowed)
+			return
+		}
+		set_manual_pause(false)
+	})
+
+	// The live stream of events, as Server-Sent Events.
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		messages, unsubscribe := g_stream.Subscribe()
+		defer unsubscribe()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		flusher.Flush()
+		for {
+			select {
+			case msg := <-messages:
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, msg.Data)
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+
+	say("Serving the dashboard on http://%s/", listener.Addr())
+	return serve_http(ctx, listener, mux, "", "")
+}
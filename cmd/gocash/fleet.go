@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/internal/fleet"
+	"github.com/maaku/gocash/internal/i18n"
+	"github.com/maaku/gocash/internal/style"
+	"github.com/maaku/gocash/miner"
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+// The coordinator this instance mines for, if it is a worker, or nil.  Set up
+// in main().
+var g_fleet *fleet.Client
+
+// serve_fleet accepts workers on addr until ctx is cancelled, queueing the
+// solutions they send for submission along with this machine's own.  The
+// connection is TLS if a certificate and key are given.
+func serve_fleet(ctx context.Context, addr, token, cert, key string, solutions *miner.SolutionQueue) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	say("Accepting workers on %s", listener.Addr())
+	return serve_http(ctx, listener, &fleet.Server{
+		Token:     token,
+		Settings:  g_miner.Settings,
+		Solutions: solutions,
+		Log: func(format string, args ...interface{}) {
+			say(format, args...)
+		},
+	}, cert, key)
+}
+
+// own_reward returns the reward of an accepted solution as it is to be kept.
+// A worker knows the secret of the reward it found, so that reward is first
+// replaced with one the worker cannot spend: an output of the wallet's
+// receive chain, if there is a wallet, or else a random secret.  If it is not
+// known whether the replacement happened, the reward is returned as it is,
+// and the output is kept as well, for `gocash check` or a sweep to settle.
+func own_reward(soln miner.Solution) (webcash.SecretWebcash, error) {
+	if !soln.Remote {
+		return soln.Reward, nil
+	}
+	inputs := []webcash.SecretWebcash{soln.Reward}
+	var output webcash.SecretWebcash
+	var err error
+	if g_wallet != nil {
+		g_wallet_mutex.Lock()
+		defer g_wallet_mutex.Unlock()
+		if output, err = g_wallet.NewOutput(wallet.Receive, soln.Reward.Amount); err == nil {
+			g_wallet.SetLabel(output.Secret, "mining "+time.Now().Format("2006-01"))
+			err = wallet_replace(g_wallet, inputs, []webcash.SecretWebcash{output}, nil, wallet.LogEntry{
+				Type:   wallet.LogMined,
+				Amount: output.Amount,
+				Delta:  wallet.Delta(output.Amount),
+			})
+		}
+	} else {
+		var entropy [32]byte
+		if _, err = rand.Read(entropy[:]); err != nil {
+			return webcash.SecretWebcash{}, err
+		}
+		output = webcash.SecretWebcash{Secret: hex.EncodeToString(entropy[:]), Amount: soln.Reward.Amount}
+		if err = g_client.Replace(inputs, []webcash.SecretWebcash{output}); err != nil && !server_refused(err) {
+			if rerr := record_webcash(output); rerr != nil {
+				say("Error: failed to open %s: %v", g_paths.MiningLog(), rerr)
+			}
+		}
+	}
+	switch {
+	case err == nil:
+		return output, nil
+	case server_refused(err):
+		return webcash.SecretWebcash{}, fmt.Errorf("the reward of solution %v, from a worker, could not be replaced: %w", soln.Hash, err)
+	default:
+		say("Warning: the reward of solution %v, from a worker, may not have been replaced, so it is kept as it is: %v", soln.Hash, err)
+		return soln.Reward, nil
+	}
+}
+
+// forward_solution sends a solution to the coordinator, keeping a record of it
+// in the pending log until the coordinator has taken it.  An error is returned
+// only if sending failed in a way which might succeed if retried.
+func forward_solution(soln miner.Solution) error {
+	publish_found(soln)
+	if err := add_pending(soln); err != nil {
+		say("Error: failed to record pending solution in %s: %v", g_paths.PendingLog(), err)
+	}
+	err := g_fleet.Submit(soln)
+	var refused *client.ServerError
+	if errors.As(err, &refused) && refused.StatusCode == http.StatusBadRequest {
+		say("Coordinator refused solution %v: %s", soln.Hash, refused.Message)
+	} else if err != nil {
+		say("Error: %v", err)
+		return err
+	} else {
+		say_as(style.Success, "Sent solution %v to the coordinator", soln.Hash)
+		desktop_notify(i18n.Sprintf("Found a solution worth %v webcash", soln.Reward.Amount))
+	}
+	if err := remove_pending(soln.Hash); err != nil {
+		say("Error: %v", err)
+	}
+	return nil
+}
+
+// forward_pending sends the coordinator the solutions a previous run left
+// pending.  Those which still cannot be sent are left for the next run.
+func forward_pending() {
+	solutions, err := load_pending()
+	if err != nil {
+		say("Error: %v", err)
+		return
+	}
+	if len(solutions) > 0 {
+		say("Sending %d pending solutions from %s", len(solutions), g_paths.PendingLog())
+	}
+	for _, soln := range solutions {
+		if forward_solution(soln) != nil {
+			return
+		}
+	}
+}
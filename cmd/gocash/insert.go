@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/maaku/gocash/internal/style"
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+// A claim is a claim code read for a bulk insert, and what became of it.
+type claim struct {
+	// The line of the file it was read from.
+	line int
+	sk   webcash.SecretWebcash
+	// The wallet's secret which replaces it.
+	output webcash.SecretWebcash
+	// The memo of the webcash URI it was given as, if any.
+	memo string
+	err  error
+	// Whether the replacement failed such that it may have happened, the
+	// output being kept as unconfirmed.
+	uncertain bool
+}
+
+// read_claim_codes reads the claim codes or webcash URIs in a file, one per
+// line, or from stdin if path is "-".  Blank lines and lines starting with "#" are skipped.
+// Lines which do not parse are returned with their error.
+func read_claim_codes(path string) ([]claim, error) {
+	var r io.Reader = g_stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var claims []claim
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sk, memo, err := parse_claim(line)
+		claims = append(claims, claim{line: n, sk: sk, memo: memo, err: err})
+	}
+	return claims, scanner.Err()
+}
+
+// insert_claim_codes claims every claim code in a file, as insert does one,
+// with at most parallel replacements in flight at a time.  What became of
+// each code is reported by line, and each one claimed is logged, with the
+// memo of its URI if memo is empty.
+func insert_claim_codes(path string, parallel int, memo, label string) int {
+	claims, err := read_claim_codes(path)
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	w, err := load_spendable_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	if label == "" {
+		label = memo
+	}
+
+	// The outputs are derived, and saved as unconfirmed, before any
+	// replacement is sent, as wallet_replace does for one.
+	seen := make(map[string]int)
+	var pending []*claim
+	for i := range claims {
+		c := &claims[i]
+		if c.err != nil {
+			continue
+		}
+		held, err := holds(w, c.sk)
+		if err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+		switch {
+		case held:
+			c.err = errors.New("already in the wallet")
+			continue
+		case seen[c.sk.Secret] != 0:
+			c.err = fmt.Errorf("the same claim code as line %d", seen[c.sk.Secret])
+			continue
+		}
+		seen[c.sk.Secret] = c.line
+		if c.output, err = w.NewOutput(wallet.Receive, c.sk.Amount); err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+		if label != "" {
+			w.SetLabel(c.output.Secret, label)
+		} else {
+			w.SetLabel(c.output.Secret, c.memo)
+		}
+		pending = append(pending, c)
+	}
+	if len(pending) > 0 {
+		if err := save_wallet(w); err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+	}
+
+	g := new(errgroup.Group)
+	g.SetLimit(parallel)
+	for _, c := range pending {
+		c := c
+		g.Go(func() error {
+			c.err = g_client.Replace([]webcash.SecretWebcash{c.sk}, []webcash.SecretWebcash{c.output})
+			return nil
+		})
+	}
+	g.Wait()
+
+	// The wallet is settled in the order of the file, so that the log is too.
+	var inserted webcash.Amount
+	count := 0
+	for _, c := range pending {
+		inputs, outputs := []webcash.SecretWebcash{c.sk}, []webcash.SecretWebcash{c.output}
+		if c.err != nil {
+			if server_refused(c.err) {
+				w.Abandon(outputs)
+			} else {
+				c.uncertain = true
+			}
+			continue
+		}
+		w.Replaced(inputs, outputs, nil)
+		entry := wallet.LogEntry{
+			Type:   wallet.LogInsert,
+			Amount: c.sk.Amount,
+			Delta:  wallet.Delta(c.sk.Amount),
+			Memo:   memo,
+		}
+		if entry.Memo == "" {
+			entry.Memo = c.memo
+		}
+		// The code is claimed whatever becomes of the log and the save,
+		// so they are only warned of, as by wallet_replace.
+		if err := w.AddLog(entry); err != nil {
+			say("Warning: %v", err)
+		}
+		inserted += c.sk.Amount
+		count++
+	}
+	if len(pending) > 0 {
+		if err := save_wallet(w); err != nil {
+			warn_unsaved(err)
+		}
+	}
+
+	failed := 0
+	for _, c := range claims {
+		if c.uncertain {
+			failed++
+			say_as(style.Error, "  line %d: %v (it may have been claimed; `gocash check` will tell)", c.line, c.err)
+		} else if c.err != nil {
+			failed++
+			say_as(style.Error, "  line %d: %v", c.line, c.err)
+		} else {
+			say("  line %d: inserted %v", c.line, webcash.FromSecret(c.sk))
+		}
+	}
+	say_as(style.Success, "Inserted %v webcash from %d of %d claim codes, for a balance of %v", inserted, count, len(claims), w.Balance())
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
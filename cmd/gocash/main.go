@@ -0,0 +1,1245 @@
+// Command gocash is a webcash miner.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/internal/events"
+	"github.com/maaku/gocash/internal/fleet"
+	"github.com/maaku/gocash/internal/i18n"
+	"github.com/maaku/gocash/internal/notify"
+	"github.com/maaku/gocash/internal/paths"
+	"github.com/maaku/gocash/internal/price"
+	"github.com/maaku/gocash/internal/schedule"
+	"github.com/maaku/gocash/internal/stats"
+	"github.com/maaku/gocash/internal/style"
+	"github.com/maaku/gocash/miner"
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+// The server connection, and the mining threads, shared by every part of the
+// program.  Both are set up in main().
+var g_client *client.Client
+var g_miner *miner.Miner
+
+// Where gocash keeps its files, resolved at startup.
+var g_paths paths.Paths
+
+// The source of prices for showing what mined webcash is worth, or nil.
+var g_price price.Provider
+
+// describe_value returns how much amt is worth according to the price
+// source, e.g. " (about 1.23 USD)", or the empty string if there is no
+// source or it cannot be reached.
+func describe_value(provider price.Provider, amt webcash.Amount) string {
+	if provider == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	quote, err := provider.Quote(ctx)
+	if err != nil {
+		say("Warning: unable to get price from %s: %v", provider.Name(), err)
+		return ""
+	}
+	return i18n.Sprintf(" (about %s)", quote.Format(amt))
+}
+
+// The persistent record of hashrate samples and solutions, which `gocash
+// stats export` reads back.  Set up in main().
+var g_stats *stats.Store
+
+// The audit trail of significant operations, as JSON lines.  Set up in
+// main().
+var g_events *events.Log
+
+// The live stream of events served by the dashboard at /events.  It carries
+// those of the audit trail, and also hashrate samples and solutions found.
+var g_stream events.Stream
+
+// emit records an event in the audit trail, and publishes it to the live
+// stream.  As with stats, failure is only reported.
+func emit(event string, fields events.Fields) {
+	if err := g_events.Emit(event, fields); err != nil {
+		say("Error: failed to record event: %v", err)
+	}
+	g_stream.Publish(event, fields)
+}
+
+// publish_found announces a solution on the live stream, without its secrets.
+func publish_found(soln miner.Solution) {
+	g_stream.Publish("solution_found", events.Fields{
+		"hash":       soln.Hash.String(),
+		"difficulty": webcash.ApparentDifficulty(soln.Hash),
+		"amount":     soln.Reward.Amount,
+	})
+}
+
+// new_client returns a client for the given server whose API calls are
+// recorded in the audit trail, and timed for the metrics.
+func new_client(server string, pool client.PoolConfig) *client.Client {
+	c := client.New(server, pool)
+	c.HTTPClient.Transport = &events.Transport{
+		Inner:     &latency_transport{inner: c.HTTPClient.Transport},
+		Log:       g_events,
+		Summarize: client.Summarize,
+		OnError: func(err error) {
+			say("Error: failed to record event: %v", err)
+		},
+	}
+	return c
+}
+
+// record_stats adds a sample to the stats store.  Failure to record is not
+// worth interrupting mining over, so it is only reported.
+func record_stats(sample stats.Sample) {
+	if sample.Time.IsZero() {
+		sample.Time = time.Now()
+	}
+	if err := g_stats.Record(sample); err != nil {
+		say("Error: failed to record stats: %v", err)
+	}
+}
+
+// get_conn_reuse_string reports the percentage of requests which reused a
+// pooled connection.
+func get_conn_reuse_string() string {
+	reused, total := g_client.ConnReuse()
+	if total == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%% of %d", 100*float64(reused)/float64(total), total)
+}
+
+func get_speed_string(attempts uint64, elapsed time.Duration) string {
+	if elapsed == 0 {
+		return "0.00 H/s"
+	}
+	return format_hashrate(float64(attempts) / elapsed.Seconds())
+}
+
+// format_hashrate formats a speed in hashes per second with a metric prefix.
+func format_hashrate(speed float64) string {
+	if speed < 1_000 {
+		return fmt.Sprintf("%.2f H/s", speed)
+	}
+	if speed < 1_000_000 {
+		return fmt.Sprintf("%.2f KH/s", speed/1_000)
+	}
+	if speed < 1_000_000_000 {
+		return fmt.Sprintf("%.2f MH/s", speed/1_000_000)
+	}
+	if speed < 1_000_000_000_000 {
+		return fmt.Sprintf("%.2f GH/s", speed/1_000_000_000)
+	}
+	return fmt.Sprintf("%.2f TH/s", speed/1_000_000_000_000)
+}
+
+func get_expect_string(attempts uint64, elapsed time.Duration, difficulty uint8) string {
+	if elapsed == 0 {
+		return "unknown"
+	}
+	return format_expect(float64(attempts)/elapsed.Seconds(), difficulty)
+}
+
+// format_expect formats the expected time to find a solution at the given
+// difficulty and speed in hashes per second.
+func format_expect(speed float64, difficulty uint8) string {
+	expect := math.Round(math.Exp2(float64(difficulty)) / speed)
+	if expect >= math.Exp2(64) {
+		return "never"
+	}
+	sec := uint64(expect)
+	min := sec / 60
+	hr := min / 60
+	day := hr / 24
+	var res string
+	if day > 0 {
+		res += fmt.Sprintf("%dd ", day)
+	}
+	if hr > 0 {
+		res += fmt.Sprintf("%dh ", hr%24)
+	}
+	if min > 0 {
+		res += fmt.Sprintf("%dm ", min%60)
+	}
+	if sec > 0 {
+		res += fmt.Sprintf("%ds", sec%60)
+	}
+	return res
+}
+
+// Signalled to request that the update thread fetch fresh protocol settings as
+// soon as possible, e.g. because the server rejected a mining report.
+var g_refresh_settings = make(chan struct{}, 1)
+
+// request_settings_refresh asks the update thread to poll the server for new
+// protocol settings without waiting for its next scheduled poll.
+func request_settings_refresh() {
+	select {
+	case g_refresh_settings <- struct{}{}:
+	default: // a refresh is already pending
+	}
+}
+
+func submit_solution(soln miner.Solution) error {
+	resp, err := g_client.SubmitMiningReport(client.MiningReport{
+		Hash:     soln.Hash,
+		Preimage: soln.Preimage,
+	})
+	if err != nil {
+		// A network error or malformed server response could be
+		// transient, and should not cause us to drop the solution.  The
+		// caller will re-attempt the submission.
+		say("Error: %v", err)
+		emit("mining_report", events.Fields{
+			"outcome": "failed",
+			"hash":    soln.Hash.String(),
+			"error":   err.Error(),
+		})
+		return err
+	}
+
+	// Update difficulty, if necessary
+	if resp.HasDifficulty {
+		old_difficulty := g_miner.SetDifficulty(resp.Difficulty)
+		if resp.Difficulty != old_difficulty {
+			say("Difficulty adjustment occurred!  Server says difficulty=%d", resp.Difficulty)
+			emit("difficulty_change", events.Fields{"old": old_difficulty, "new": resp.Difficulty})
+		}
+	}
+
+	// Handle server rejection by saving the proof-of-work solution to the
+	// orphan log.
+	if !resp.Accepted() {
+		// Server rejected the solution.  Save it to the orphan log.
+		say("Server rejected MiningReport: %d %s", resp.StatusCode, resp.Error)
+		emit("mining_report", events.Fields{
+			"outcome":    "rejected",
+			"hash":       soln.Hash.String(),
+			"difficulty": webcash.ApparentDifficulty(soln.Hash),
+			"status":     resp.StatusCode,
+			"error":      resp.Error,
+		})
+		record_stats(stats.Sample{
+			Kind:       stats.KindReject,
+			Difficulty: g_miner.Settings().Difficulty,
+			Best:       webcash.ApparentDifficulty(soln.Hash),
+			Reason:     resp.Error,
+		})
+		atomic.AddUint64(&g_rejected_reports, 1)
+		if !g_rejecting {
+			g_rejecting = true
+			desktop_notify(i18n.Sprintf("The server is rejecting mining reports: %s", resp.Error))
+		}
+		// Our view of the difficulty may be stale, so check right away.
+		request_settings_refresh()
+		// Save the solution to the orphan log.  With a wallet, the secret
+		// of the reward is left out, as everywhere outside the wallet.
+		f, err := os.OpenFile(g_paths.OrphanLog(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			say("Error: failed to open %s: %v", g_paths.OrphanLog(), err)
+			// Do not return error to prevent the solution from being requeued.
+			return nil
+		}
+		if logs_secrets() {
+			io.WriteString(f, fmt.Sprintln(soln))
+		} else {
+			io.WriteString(f, fmt.Sprintln(soln.Hash, webcash.FromSecret(soln.Reward), soln.Difficulty, soln.Timestamp.UTC().Format(time.RFC3339), resp.Error))
+		}
+		f.Close()
+		// No error is returned to prevent the solution from being requeued.
+		return nil
+	}
+
+	atomic.AddUint64(&g_accepted_reports, 1)
+	g_rejecting = false
+	emit("mining_report", events.Fields{
+		"outcome":    "accepted",
+		"hash":       soln.Hash.String(),
+		"difficulty": webcash.ApparentDifficulty(soln.Hash),
+		"amount":     soln.Reward.Amount,
+	})
+	record_stats(stats.Sample{
+		Kind:       stats.KindSolution,
+		Difficulty: g_miner.Settings().Difficulty,
+		Best:       webcash.ApparentDifficulty(soln.Hash),
+		Amount:     soln.Reward.Amount,
+	})
+
+	if value := describe_value(g_price, soln.Reward.Amount); value != "" {
+		say_as(style.Success, "Mined %v%s", soln.Reward.Amount, value)
+	}
+
+	// Keep the newly generated coin in the wallet, if there is one, and in
+	// the log.  Neither failure returns an error, or else the solution
+	// would be requeued, and one doesn't keep the webcash from the other.
+	reward, err := own_reward(soln)
+	if err != nil {
+		say("Error: %v", err)
+		return nil
+	}
+	if err := deposit_mined(reward); err != nil {
+		say("Error: failed to add mined webcash to %s: %v", g_store.Path(), err)
+	}
+	if err := record_webcash(reward); err != nil {
+		say("Error: failed to open %s: %v", g_paths.MiningLog(), err)
+	}
+	notify_webhook(soln)
+
+	return nil
+}
+
+// Whether to show desktop notifications, and whether the server's latest
+// answer to a mining report was a rejection, so that a run of rejections is
+// only notified once.  g_rejecting is only used by the update thread.
+var g_notify bool
+var g_rejecting bool
+
+// desktop_notify shows a desktop notification, if they are enabled, without
+// waiting for it to be shown.
+func desktop_notify(message string) {
+	if !g_notify {
+		return
+	}
+	go func() {
+		if err := notify.Send("gocash", message); err != nil {
+			say("Warning: desktop notification: %v", err)
+		}
+	}()
+}
+
+// Where to post accepted mining reports, or nil.  Set up in main(), and
+// changed by a config reload.
+var g_webhook atomic.Pointer[events.Webhook]
+
+// parse_webhook returns the webhook posting to url, or nil if url is empty.
+func parse_webhook(url string) (*events.Webhook, error) {
+	if url == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, errors.New("-webhook: expected an http or https URL")
+	}
+	return &events.Webhook{URL: url}, nil
+}
+
+// notify_webhook posts an accepted solution to the webhook, if there is one,
+// with the new balance of the wallet, or without a wallet the total of the
+// mining log, spent or not.  The claim code is left out.
+// It does not wait for the post, so that a slow endpoint doesn't hold up
+// submission.
+func notify_webhook(soln miner.Solution) {
+	webhook := g_webhook.Load()
+	if webhook == nil {
+		return
+	}
+	fields := events.Fields{
+		"hash":       soln.Hash.String(),
+		"amount":     soln.Reward.Amount,
+		"difficulty": webcash.ApparentDifficulty(soln.Hash),
+		"text":       i18n.Sprintf("Mined %v webcash", soln.Reward.Amount),
+	}
+	if g_wallet != nil {
+		g_wallet_mutex.Lock()
+		fields["balance"] = g_wallet.Balance()
+		g_wallet_mutex.Unlock()
+	} else if total, err := mining_log_total(); err == nil {
+		fields["mined_total"] = total
+	}
+	go func() {
+		if err := webhook.Post("mining_report_accepted", fields); err != nil {
+			say("Warning: webhook: %v", err)
+		}
+	}()
+}
+
+// Whether to write secrets to the mining, pending and orphan logs even when
+// mining into a wallet, which keeps them itself.
+var g_plaintext_log bool
+
+// logs_secrets reports whether the logs of mining hold secrets in plaintext,
+// as they must without a wallet, being then the only record of them.
+func logs_secrets() bool {
+	return g_wallet == nil || g_plaintext_log
+}
+
+// record_webcash appends mined webcash to the log of it: the claim code, or if
+// the wallet keeps that, only the public webcash.
+func record_webcash(sk webcash.SecretWebcash) error {
+	f, err := os.OpenFile(g_paths.MiningLog(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if logs_secrets() {
+		_, err = io.WriteString(f, fmt.Sprintln(sk))
+	} else {
+		_, err = io.WriteString(f, fmt.Sprintln(webcash.FromSecret(sk)))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// mining_log_total returns the total of the webcash in the mining log, spent
+// since or not.  Without a wallet, it is the nearest thing to a balance.
+func mining_log_total() (webcash.Amount, error) {
+	_, logged, err := read_mining_log(g_paths.MiningLog())
+	var total webcash.Amount
+	for _, pk := range logged {
+		total += pk.Amount
+	}
+	return total, err
+}
+
+// The number of extra leading zero bits beyond the difficulty a solution must
+// have to be submitted, and the number of solutions skipped for lack of them.
+// Accessed atomically so that the margin can be changed by a config reload.
+var g_margin uint32
+var g_marginal_skipped uint64
+
+// process_solution checks that a solution is still worth submitting, and if so
+// submits it to the server.  An error is returned only if submission failed in
+// a way which might succeed if retried.
+func process_solution(soln miner.Solution) error {
+	settings := g_miner.Settings()
+
+	// Do not submit work less than the current difficulty
+	if soln.Difficulty < settings.Difficulty {
+		say("Ignoring solution as difficulty commitment is too low: (%d < %d)", soln.Difficulty, settings.Difficulty)
+		return nil
+	}
+	if webcash.ApparentDifficulty(soln.Hash) < settings.Difficulty {
+		say("Ignoring solution as apparent difficulty is too low: (%d < %d)", webcash.ApparentDifficulty(soln.Hash), settings.Difficulty)
+		return nil
+	}
+	if margin := uint8(atomic.LoadUint32(&g_margin)); webcash.ApparentDifficulty(soln.Hash) < settings.Difficulty+margin {
+		skipped := atomic.AddUint64(&g_marginal_skipped, 1)
+		say("Skipping marginal solution: (%d < %d + %d margin), %d skipped so far", webcash.ApparentDifficulty(soln.Hash), settings.Difficulty, margin, skipped)
+		emit("mining_report", events.Fields{
+			"outcome":    "skipped",
+			"hash":       soln.Hash.String(),
+			"difficulty": webcash.ApparentDifficulty(soln.Hash),
+		})
+		return nil
+	}
+
+	// Do not submit work which claims the rewards of an earlier epoch
+	if soln.Reward.Amount != settings.TotalReward-settings.ServerSubsidy {
+		say("Ignoring solution as it was mined for a previous epoch: (reward %v != %v)", soln.Reward.Amount, settings.TotalReward-settings.ServerSubsidy)
+		return nil
+	}
+
+	// Do not submit stale work
+	now := time.Now()
+	if soln.Timestamp.Before(now.Add(-2 * time.Hour)) {
+		say("Ignoring solution as timestamp is too old: (%v < %v)", soln.Timestamp, now.Add(-2*time.Hour))
+		return nil
+	}
+
+	// Submit the solution to the server, keeping a record of it on disk until
+	// the server has given its answer.
+	// Only the public side of the reward is shown, as what is shown may end
+	// up in a log; the preimage holds the secret.
+	say_as(style.Success, "GOT SOLUTION!!! %v %v", soln.Hash, webcash.FromSecret(soln.Reward))
+	desktop_notify(i18n.Sprintf("Found a solution worth %v webcash", soln.Reward.Amount))
+	publish_found(soln)
+	if err := add_pending(soln); err != nil {
+		say("Error: failed to record pending solution in %s: %v", g_paths.PendingLog(), err)
+	}
+	if err := submit_solution(soln); err != nil {
+		return err
+	}
+	if err := remove_pending(soln.Hash); err != nil {
+		say("Error: %v", err)
+	}
+	return nil
+}
+
+// next_poll_interval adapts the settings polling interval: it drops to the
+// minimum whenever the settings have just changed, since further adjustments
+// (e.g. around an epoch boundary) tend to follow in quick succession, and
+// otherwise backs off gradually towards the maximum.
+func next_poll_interval(current, min, max time.Duration, changed bool) time.Duration {
+	if changed {
+		return min
+	}
+	next := current + current/2
+	if next > max {
+		next = max
+	}
+	if next < min {
+		next = min
+	}
+	return next
+}
+
+// The bounds on the settings polling interval, as time.Duration nanoseconds.
+// Accessed atomically so that they can be changed by a config reload.
+var g_poll_min int64
+var g_poll_max int64
+
+// expected_yield estimates the webcash a miner keeps per day at the given
+// speed in hashes per second.
+func expected_yield(speed float64, settings webcash.ProtocolSettings) webcash.Amount {
+	per_day := 24 * 60 * 60 * speed / math.Exp2(float64(settings.Difficulty))
+	return webcash.Amount(per_day * float64(settings.TotalReward-settings.ServerSubsidy))
+}
+
+// The number of mining reports accepted and rejected by the server since
+// startup.
+var g_accepted_reports, g_rejected_reports uint64
+
+// The number of solutions expected from the hashes computed since startup, as
+// the bits of a float64.  Only the update thread adds to it.
+var g_expected_solutions uint64
+
+// format_luck formats luck, the solutions found as a fraction of those
+// expected, as a percentage.
+func format_luck(luck, expected float64) string {
+	if expected <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.0f%%", 100*luck)
+}
+
+// status_thread prints the miner's hashrates and totals every interval.
+func status_thread(ctx context.Context, interval time.Duration) {
+	var meter miner.HashrateMeter
+	meter.Update(g_miner.Attempts(), time.Now())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			meter.Update(g_miner.Attempts(), now)
+			rates := meter.Rates()
+			settings := g_miner.Settings()
+			accepted := atomic.LoadUint64(&g_accepted_reports)
+			expected := math.Float64frombits(atomic.LoadUint64(&g_expected_solutions))
+			// Estimates are from the 15-minute average, which is the steadiest.
+			say("hashrate now=%s 1m=%s 15m=%s attempts=%d accepted=%d rejected=%d luck=%s expect=%s yield=%v/day", format_hashrate(rates.Current), format_hashrate(rates.OneMinute), format_hashrate(rates.FifteenMinute), rates.Total, accepted, atomic.LoadUint64(&g_rejected_reports), format_luck(float64(accepted)/expected, expected), format_expect(rates.FifteenMinute, settings.Difficulty), expected_yield(rates.FifteenMinute, settings))
+		}
+	}
+}
+
+// update_thread periodically fetches the protocol settings, and hands solutions
+// to process as they are found.  Miners fetch from the server and submit to
+// it; workers in a fleet do both through their coordinator.
+func update_thread(ctx context.Context, solutions *miner.SolutionQueue, fetch func() (webcash.ProtocolSettings, error), process func(miner.Solution) error) {
+	// Record start time
+	last_settings_fetch := time.Now()
+
+	timeout := time.Duration(atomic.LoadInt64(&g_poll_min))
+	watchdog := time.NewTimer(timeout)
+
+	// A solution which failed to submit due to a (possibly) transient error,
+	// and when to try it again.  No further solutions are taken from the
+	// queue until it is resolved, which applies backpressure to the miners.
+	var retry *miner.Solution
+	var retry_timer <-chan time.Time
+
+	for {
+		// https://medium.com/@oboturov/golang-time-after-is-not-garbage-collected-4cbc94740082
+		watchdog.Reset(time.Until(last_settings_fetch.Add(timeout)))
+
+		incoming := solutions.C()
+		if retry != nil {
+			incoming = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			say("closing update thread")
+			return
+
+		case <-retry_timer:
+			soln := *retry
+			retry, retry_timer = nil, nil
+			if err := process(soln); err != nil {
+				say("Possible transient error, or server timeout?  Waiting to re-attempt.")
+				retry, retry_timer = &soln, time.After(8*time.Second)
+			}
+
+		case soln := <-incoming:
+			if err := process(soln); err != nil {
+				say("Possible transient error, or server timeout?  Waiting to re-attempt.")
+				retry, retry_timer = &soln, time.After(8*time.Second)
+			}
+
+		case <-g_refresh_settings:
+			// Poll as soon as the minimum interval since the last fetch has
+			// passed, rather than hammering the server.
+			timeout = time.Duration(atomic.LoadInt64(&g_poll_min))
+
+		case <-watchdog.C:
+			settings, err := fetch()
+
+			// Update the watchdog timer to the current time, before checking
+			// the result of the fetch, so that there is a delay between
+			// attempts.
+			now := time.Now()
+			old_last_settings_fetch := last_settings_fetch
+			last_settings_fetch = now
+
+			// If we failed to fetch the settings, wait before trying again.
+			if err != nil {
+				say("Error: %v", err)
+				continue
+			}
+
+			// Update global state
+			old := g_miner.Settings()
+			changed := settings.Difficulty != old.Difficulty || settings.Epoch != old.Epoch || settings.TotalReward != old.TotalReward || settings.ServerSubsidy != old.ServerSubsidy
+			g_miner.SetSettings(settings)
+			if settings.Epoch != old.Epoch || settings.TotalReward != old.TotalReward || settings.ServerSubsidy != old.ServerSubsidy {
+				say("Epoch %d began: mining reward %v, of which %v is the server subsidy", settings.Epoch, settings.TotalReward, settings.ServerSubsidy)
+				emit("epoch_change", events.Fields{
+					"old":            old.Epoch,
+					"new":            settings.Epoch,
+					"total_reward":   settings.TotalReward,
+					"server_subsidy": settings.ServerSubsidy,
+				})
+			}
+			attempts, best := g_miner.TakeStats()
+
+			// Record how much time has elapsed since the last update
+			elapsed := now.Sub(old_last_settings_fetch)
+			expected := math.Float64frombits(atomic.LoadUint64(&g_expected_solutions)) + stats.ExpectedSolutions(attempts, settings.Difficulty)
+			atomic.StoreUint64(&g_expected_solutions, math.Float64bits(expected))
+			record_stats(stats.Sample{
+				Time:       now,
+				Kind:       stats.KindHashrate,
+				Difficulty: settings.Difficulty,
+				Attempts:   attempts,
+				Elapsed:    elapsed,
+				Best:       best,
+			})
+			g_stream.Publish("hashrate", events.Fields{
+				"difficulty": settings.Difficulty,
+				"attempts":   attempts,
+				"seconds":    elapsed.Seconds(),
+				"best":       best,
+			})
+			timeout = next_poll_interval(timeout, time.Duration(atomic.LoadInt64(&g_poll_min)), time.Duration(atomic.LoadInt64(&g_poll_max)), changed)
+
+			// Print the current difficulty and speed
+			say("server says difficulty=%v ratio=%v speed=%s expect=%v best=%d conn_reuse=%s queue=%v", settings.Difficulty, settings.Ratio, get_speed_string(attempts, elapsed), get_expect_string(attempts, elapsed, settings.Difficulty), best, get_conn_reuse_string(), solutions)
+		}
+	}
+}
+
+// serve_http serves handler on listener until ctx is cancelled, over TLS if a
+// certificate and key are given.
+func serve_http(ctx context.Context, listener net.Listener, handler http.Handler, cert, key string) error {
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	var err error
+	if cert != "" {
+		err = server.ServeTLS(listener, cert, key)
+	} else {
+		err = server.Serve(listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// start_gpus starts mining on the OpenCL devices selected by list, which is
+// empty for none, "all", or a list of device numbers.
+func start_gpus(m *miner.Miner, list string) error {
+	if list == "" {
+		return nil
+	}
+	devices, err := miner.GPUDevices()
+	if err != nil {
+		return err
+	}
+	selected := devices
+	if list != "all" {
+		numbers, err := miner.ParseCPUList(list)
+		if err != nil {
+			return fmt.Errorf("-gpus: %v", err)
+		}
+		selected = nil
+		for _, n := range numbers {
+			if n >= len(devices) {
+				return fmt.Errorf("-gpus: no GPU %d (see `gocash gpus`)", n)
+			}
+			selected = append(selected, devices[n])
+		}
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("-gpus: no OpenCL devices found")
+	}
+	for _, dev := range selected {
+		if err := m.StartGPU(dev); err != nil {
+			return err
+		}
+		say("Mining on GPU %d: %s", dev.Index, dev.Name)
+	}
+	return nil
+}
+
+// parse_byte_size parses a size in bytes with an optional unit suffix, using
+// the same suffixes as the GOMEMLIMIT environment variable (B, KiB, MiB, GiB,
+// TiB), as well as their decimal counterparts (KB, MB, GB, TB).
+func parse_byte_size(size string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"B", 1},
+	}
+	size = strings.TrimSpace(size)
+	scale := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(size, unit.suffix) {
+			size = strings.TrimSpace(strings.TrimSuffix(size, unit.suffix))
+			scale = unit.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	if n > math.MaxInt64/scale {
+		return 0, fmt.Errorf("size %q is too large", size)
+	}
+	return n * scale, nil
+}
+
+// apply_runtime_settings puts the current values of the reloadable settings
+// into effect.  If m is nil, the settings are only checked for validity.
+func apply_runtime_settings(m *miner.Miner, gomaxprocs, workers int, cpu_list string, max_cpu, batch_size, margin int, poll_min, poll_max time.Duration) error {
+	if workers < 0 {
+		return fmt.Errorf("-workers: must not be negative")
+	}
+	if max_cpu < 1 || max_cpu > 100 {
+		return fmt.Errorf("-max-cpu: must be a percentage from 1 to 100")
+	}
+	if err := miner.CheckBatchSize(batch_size); err != nil {
+		return fmt.Errorf("-batch-size: %v", err)
+	}
+	if margin < 0 || margin > 16 {
+		return fmt.Errorf("-margin: must be from 0 to 16 bits")
+	}
+	var cpus []int
+	if cpu_list != "" {
+		var err error
+		cpus, err = miner.ParseCPUList(cpu_list)
+		if err != nil {
+			return fmt.Errorf("-cpus: %v", err)
+		}
+	}
+	if m == nil {
+		return nil
+	}
+	if gomaxprocs <= 0 {
+		gomaxprocs = runtime.NumCPU()
+	}
+	runtime.GOMAXPROCS(gomaxprocs)
+	atomic.StoreUint32(&g_margin, uint32(margin))
+	atomic.StoreInt64(&g_poll_min, int64(poll_min))
+	atomic.StoreInt64(&g_poll_max, int64(poll_max))
+	m.SetMaxCPU(max_cpu)
+	m.SetBatchSize(batch_size)
+	say("Running %d mining threads", m.Resize(workers, cpus))
+	return nil
+}
+
+// apply_reloadable_settings puts the values of the reloadable flags in fs into
+// effect: those of apply_runtime_settings, the mining schedule and the webhook.
+// If m is nil, they are only checked for validity.
+func apply_reloadable_settings(fs *flag.FlagSet, m *miner.Miner) error {
+	sched, err := schedule.Parse(flag_value(fs, "schedule").(string))
+	if err != nil {
+		return fmt.Errorf("-schedule: %v", err)
+	}
+	webhook, err := parse_webhook(flag_value(fs, "webhook").(string))
+	if err != nil {
+		return err
+	}
+	err = apply_runtime_settings(m,
+		flag_value(fs, "gomaxprocs").(int),
+		flag_value(fs, "workers").(int),
+		flag_value(fs, "cpus").(string),
+		flag_value(fs, "max-cpu").(int),
+		flag_value(fs, "batch-size").(int),
+		flag_value(fs, "margin").(int),
+		flag_value(fs, "poll-min").(time.Duration),
+		flag_value(fs, "poll-max").(time.Duration))
+	if err != nil || m == nil {
+		return err
+	}
+	g_schedule.Store(&sched)
+	g_webhook.Store(webhook)
+	return nil
+}
+
+func main() {
+	// Flag usage is translated as the flags are defined, so the language of
+	// the environment is used for it even if -lang says otherwise.
+	i18n.SetLanguage(i18n.FromEnvironment())
+
+	var err error
+	g_paths, err = paths.Default()
+	if err != nil {
+		say("Error: %v", err)
+		os.Exit(1)
+	}
+	g_stats = stats.Open(g_paths.StatsFile())
+	g_events = events.Open(g_paths.EventsFile())
+	g_client = new_client(config_setting("server", client.DefaultServer), client.DefaultPoolConfig)
+
+	// Subcommands take the color settings from the config file; mining sets
+	// them again once its flags are parsed.
+	style.Configure(config_setting("color", "auto"), config_setting("theme", "dark"), os.Stdout)
+	set_plain(config_setting("plain", "false") == "true" || os.Getenv("TERM") == "dumb")
+
+	if err := select_wallet(config_setting("wallet", "default")); err != nil {
+		say("Error: %v: %v", g_paths.ConfigFile(), err)
+		os.Exit(2)
+	}
+	if backups := config_setting("wallet-backups", ""); backups != "" {
+		if g_wallet_backups, err = strconv.Atoi(backups); err != nil {
+			say("Error: %v: %v", g_paths.ConfigFile(), err)
+			os.Exit(2)
+		}
+	}
+
+	// Anything but mining is a subcommand.
+	if len(os.Args) > 1 {
+		if cmd, ok := g_commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+		if !strings.HasPrefix(os.Args[1], "-") {
+			say("Error: unknown command %q (commands: %s)", os.Args[1], command_names())
+			os.Exit(2)
+		}
+	}
+
+	server := flag.String("server", client.DefaultServer, T("base URL of the webcash server"))
+	fleet_listen := flag.String("fleet-listen", "", T("address to accept fleet workers on, e.g. \":8333\", making this instance the coordinator which submits their solutions"))
+	coordinator := flag.String("coordinator", "", T("base URL of a fleet coordinator to mine for, sending it solutions instead of submitting them to the server"))
+	fleet_token := flag.String("fleet-token", "", T("token shared by the coordinator and workers of a fleet; best set in the config file, where other users cannot read it"))
+	fleet_cert := flag.String("fleet-cert", "", T("TLS certificate file for -fleet-listen"))
+	fleet_key := flag.String("fleet-key", "", T("TLS private key file for -fleet-listen"))
+	gomaxprocs := flag.Int("gomaxprocs", 0, T("maximum number of CPUs executing simultaneously (default: all)"))
+	workers := flag.Int("workers", 0, T("number of mining threads (default: one per CPU, or per CPU in -cpus)"))
+	benchmark := flag.Duration("benchmark", 0, T("measure the hashrate for this long against a synthetic target, without contacting the server, and exit"))
+	flag.String("schedule", "", T("only mine within these daily windows of local time, e.g. \"22:00-07:00\" or \"00:00-08:00,12:00-13:00\""))
+	pause_load := flag.Int("pause-load", 0, T("pause mining while other programs use more than this percentage of the CPU (0 to disable; Linux only)"))
+	autotune := flag.Duration("autotune", 0, T("find the number of mining threads with the best hashrate, measuring each candidate for this long (0 to disable)"))
+	cpu_list := flag.String("cpus", "", T("comma-separated list of CPUs to pin mining threads to, e.g. \"0,2,4-7\""))
+	gpu_list := flag.String("gpus", "", T("OpenCL devices to also mine on, \"all\" or a comma-separated list of numbers as shown by `gocash gpus`"))
+	max_cpu := flag.Int("max-cpu", 100, T("percentage of the time each mining thread spends hashing, resting the remainder"))
+	batch_size := flag.Int("batch-size", miner.DefaultBatchSize, T("hashes computed per call into the SHA-256 library: 8, 40, 200 or 1000"))
+	sha256_impl := flag.String("sha256", "auto", T("SHA-256 implementation to mine with: \"auto\" for the fastest libsha2 has for this CPU, or \"go\" for Go's own crypto/sha256"))
+	idle := flag.Bool("idle", false, T("mine at the lowest scheduling priority, so that other programs come first"))
+	max_idle_conns := flag.Int("http-max-idle-conns", 100, T("maximum number of idle HTTP connections kept open (0 for no limit)"))
+	max_idle_conns_per_host := flag.Int("http-max-idle-conns-per-host", 8, T("maximum number of idle HTTP connections kept open per host"))
+	max_conns_per_host := flag.Int("http-max-conns-per-host", 0, T("maximum number of HTTP connections per host (0 for no limit)"))
+	idle_conn_timeout := flag.Duration("http-idle-timeout", 90*time.Second, T("how long an idle HTTP connection is kept open"))
+	flag.Duration("poll-min", 5*time.Second, T("shortest interval between difficulty checks, used after a change or rejected report"))
+	flag.Duration("poll-max", 60*time.Second, T("longest interval between difficulty checks while nothing is changing"))
+	dashboard_listen := flag.String("dashboard-listen", "", T("address to serve a status web page on, and a live stream of events at /events, e.g. \"localhost:8080\""))
+	metrics_listen := flag.String("metrics-listen", "", T("address to serve Prometheus metrics on at /metrics, e.g. \"localhost:9477\""))
+	pprof_addr := flag.String("pprof", "", T("port, or address, to serve runtime profiles on at /debug/pprof/ (a bare port is on localhost)"))
+	status_interval := flag.Duration("status-interval", 10*time.Second, T("interval between hashrate status lines (0 to disable)"))
+	flag.Int("margin", 0, T("extra leading zero bits beyond the difficulty a solution needs to be submitted, so that it is not rejected after a difficulty increase"))
+	queue_size := flag.Int("solution-queue", 16, T("number of found solutions which may await submission before mining pauses"))
+	memory_limit := flag.String("memory-limit", "", T("soft limit on total memory use, e.g. \"256MiB\" (overrides GOMEMLIMIT)"))
+	accept_terms := flag.Bool("accept-terms", false, T("accept the terms of service without prompting"))
+	terms_max_age := flag.Duration("terms-max-age", 7*24*time.Hour, T("how often to check the terms of service for changes"))
+	notify_desktop := flag.Bool("notify", false, T("show a desktop notification when a solution is found, and when the server starts rejecting mining reports"))
+	flag.String("webhook", "", T("URL to post a JSON notification to whenever a mining report is accepted"))
+	price_source := flag.String("price", "", T("where to get the price of webcash, for showing what it is worth: \"fixed:<currency>:<price>\" or \"json:<currency>:<field>:<url>\""))
+	color := flag.String("color", "auto", T("when to color output: \"auto\", \"always\" or \"never\"; NO_COLOR in the environment also turns it off"))
+	theme := flag.String("theme", "dark", T("color theme, \"dark\" or \"light\", to suit the terminal background"))
+	plain := flag.Bool("plain", g_plain, T("plain, line-oriented output with no color or control sequences, for screen readers and log collectors"))
+	config_file := flag.String("config", "", T("file of \"flag = value\" settings; send SIGHUP to reload (default: gocash.conf in the config directory, if it exists)"))
+	lang := flag.String("lang", "", T("language of messages, e.g. \"es\" (default: from LANG)"))
+	wallet_name := flag.String("wallet", "default", T(wallet_flag_usage))
+	flag.BoolVar(&g_wallet_wait, "wallet-wait", false, T(wallet_wait_usage))
+	flag.BoolVar(&g_plaintext_log, "plaintext-log", false, T("when mining into a wallet, also write mined claim codes and pending mining reports to the logs in plaintext, as without one"))
+	wallet_backups := flag.Int("wallet-backups", wallet.DefaultBackups, T("number of previous versions of the wallet file to keep beside it, as <file>.1 and so on"))
+	flag.Parse()
+
+	if *lang != "" && !i18n.SetLanguage(*lang) {
+		say("Warning: no translation for language %q, using English", *lang)
+	}
+
+	if *config_file == "" {
+		if _, err := os.Stat(g_paths.ConfigFile()); err == nil {
+			*config_file = g_paths.ConfigFile()
+		}
+	}
+	if *config_file != "" {
+		if err := apply_config_file(*config_file, false); err != nil {
+			say("Error: %v", err)
+			os.Exit(2)
+		}
+	}
+
+	if err := style.Configure(*color, *theme, os.Stdout); err != nil {
+		say("Error: %v", err)
+		os.Exit(2)
+	}
+	set_plain(*plain)
+	if err := select_wallet(*wallet_name); err != nil {
+		say("Error: -wallet: %v", err)
+		os.Exit(2)
+	}
+	g_wallet_backups = *wallet_backups
+
+	// The runtime already honors GOMEMLIMIT from the environment, but a flag
+	// is easier to manage from a service configuration.
+	if *memory_limit != "" {
+		limit, err := parse_byte_size(*memory_limit)
+		if err != nil {
+			say("Error: -memory-limit: %v", err)
+			os.Exit(2)
+		}
+		debug.SetMemoryLimit(limit)
+	}
+
+	g_client = new_client(strings.TrimSuffix(*server, "/"), client.PoolConfig{
+		MaxIdleConns:        *max_idle_conns,
+		MaxIdleConnsPerHost: *max_idle_conns_per_host,
+		MaxConnsPerHost:     *max_conns_per_host,
+		IdleConnTimeout:     *idle_conn_timeout,
+	})
+
+	// Workers get their settings from the coordinator and send it their
+	// solutions; everyone else deals with the server directly.
+	fetch, process := g_client.Target, process_solution
+	if *coordinator != "" {
+		g_fleet = &fleet.Client{
+			URL:   strings.TrimSuffix(*coordinator, "/"),
+			Token: *fleet_token,
+			HTTPClient: client.NewHTTPClient(client.PoolConfig{
+				MaxIdleConns:        *max_idle_conns,
+				MaxIdleConnsPerHost: *max_idle_conns_per_host,
+				MaxConnsPerHost:     *max_conns_per_host,
+				IdleConnTimeout:     *idle_conn_timeout,
+			}),
+		}
+		fetch, process = g_fleet.Work, forward_solution
+	}
+
+	// Check the reloadable settings up front, so that mistakes are caught
+	// before anything else is done.
+	if err := apply_reloadable_settings(flag.CommandLine, nil); err != nil {
+		say("Error: %v", err)
+		os.Exit(2)
+	}
+	if *pause_load < 0 || *pause_load > 100 {
+		say("Error: -pause-load: must be a percentage from 0 to 100")
+		os.Exit(2)
+	}
+	if *fleet_listen != "" && *coordinator != "" {
+		say("Error: -fleet-listen and -coordinator cannot be used together")
+		os.Exit(2)
+	}
+	if (*fleet_listen != "" || *coordinator != "") && *fleet_token == "" {
+		say("Error: -fleet-token is required with -fleet-listen or -coordinator")
+		os.Exit(2)
+	}
+	if (*fleet_cert == "") != (*fleet_key == "") {
+		say("Error: -fleet-cert and -fleet-key must be given together")
+		os.Exit(2)
+	}
+	if err := miner.SetImplementation(*sha256_impl); err != nil {
+		say("Error: -sha256: %v", err)
+		os.Exit(2)
+	}
+	var monitor *schedule.UsageMonitor
+	if *pause_load > 0 {
+		if monitor, err = schedule.NewUsageMonitor(); err != nil {
+			say("Error: -pause-load: %v", err)
+			os.Exit(2)
+		}
+	}
+
+	// Benchmarks need neither the server nor its terms, nor any files.
+	if *benchmark > 0 {
+		os.Exit(run_benchmark(*benchmark, *gomaxprocs, *workers, *cpu_list, *max_cpu, *batch_size))
+	}
+
+	if err := g_paths.Create(); err != nil {
+		say("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if g_price, err = price.Parse(*price_source); err != nil {
+		say("Error: -price: %v", err)
+		os.Exit(2)
+	}
+	g_notify = *notify_desktop
+
+	// The coordinator of a fleet accepts the terms on behalf of its workers.
+	if g_fleet == nil {
+		if err := ensure_terms_accepted(*terms_max_age, *accept_terms); err != nil {
+			say("Error: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	say("Using SHA256 algorithm: %s", miner.Algorithm())
+	if g_fleet != nil {
+		say("Mining for the coordinator at %s", g_fleet.URL)
+	} else {
+		say("Mined webcash is logged to %s", g_paths.MiningLog())
+	}
+
+	settings, err := fetch()
+	if err != nil {
+		say("Error: unable to fetch mining target: %v", err)
+		os.Exit(1)
+	}
+	fmt.Println(settings)
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done() // in case of early exit
+	started := time.Now()
+	g, gctx := errgroup.WithContext(ctx)
+
+	// goroutine to check for Ctrl-C, or a request from the service manager to
+	// stop.  Either stops mining cleanly.
+	g.Go(func() error {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(c)
+
+		select {
+		case sig := <-c:
+			say("caught signal %v, shutting down", sig)
+			done()
+			return nil
+		case <-ctx.Done():
+		}
+
+		say("closing signal handler")
+		return gctx.Err()
+	})
+
+	solutions := miner.NewSolutionQueue(*queue_size)
+	g_miner = miner.New(gctx, settings, solutions)
+	g_miner.Log = func(format string, args ...interface{}) {
+		say(format, args...)
+	}
+	g_miner.Idle = *idle
+	if g_fleet == nil {
+		store, err := open_store()
+		var w *wallet.Wallet
+		if err == nil {
+			w, err = store.Load(wallet_passphrase)
+		}
+		if err == nil && w.WatchOnly() {
+			say("The wallet %s is watch-only, so mining keeps secrets only in the mining log", store.Path())
+		} else if err == nil {
+			g_wallet = w
+			g_miner.KeepSecret = mining_secret
+			g_miner.ClaimSecret = claim_mining_secret
+			say("Mining into the wallet %s", store.Path())
+		} else if !errors.Is(err, os.ErrNotExist) {
+			say("Error: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	// Finish what a previous run left unsubmitted before finding more.
+	if g_fleet != nil {
+		forward_pending()
+	} else {
+		resubmit_pending()
+	}
+
+	// goroutine which reloads the config file on SIGHUP
+	g.Go(func() error {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGHUP)
+		defer signal.Stop(c)
+
+		for {
+			select {
+			case <-c:
+				if *config_file == "" {
+					say("caught SIGHUP, but no config file to reload")
+					continue
+				}
+				say("caught SIGHUP, reloading %s", *config_file)
+				// Nothing is changed unless the whole file is valid.
+				scratch, err := read_config_flags(*config_file, true)
+				if err == nil {
+					err = apply_reloadable_settings(scratch, nil)
+				}
+				if err == nil {
+					err = commit_config_flags(*config_file, scratch)
+				}
+				if err == nil {
+					err = apply_reloadable_settings(flag.CommandLine, g_miner)
+				}
+				if err != nil {
+					say("Error: config reload failed, keeping previous settings: %v", err)
+				}
+			case <-gctx.Done():
+				return nil
+			}
+		}
+	})
+
+	// goroutine which periodically queries the webcash server for change in
+	// difficulty or subsidy, and submits solution mining reports.
+	g.Go(func() error {
+		update_thread(gctx, solutions, fetch, process)
+		return nil
+	})
+
+	// goroutines which pause and resume mining on request.  The keyboard is
+	// read outside the group, as a read cannot be interrupted.
+	g.Go(func() error {
+		pause_signal_thread(gctx)
+		return nil
+	})
+	go keyboard_thread(gctx)
+
+	// goroutine which serves the dashboard
+	if *dashboard_listen != "" {
+		g.Go(func() error {
+			return serve_dashboard(gctx, *dashboard_listen)
+		})
+	}
+
+	// goroutine which serves metrics
+	if *metrics_listen != "" {
+		g.Go(func() error {
+			return serve_metrics(gctx, *metrics_listen)
+		})
+	}
+
+	// goroutine which serves profiles
+	if *pprof_addr != "" {
+		g.Go(func() error {
+			return serve_pprof(gctx, *pprof_addr)
+		})
+	}
+
+	// goroutine which takes solutions from the workers of a fleet
+	if *fleet_listen != "" {
+		g.Go(func() error {
+			return serve_fleet(gctx, *fleet_listen, *fleet_token, *fleet_cert, *fleet_key, solutions)
+		})
+	}
+
+	// goroutine which reports the hashrate
+	if *status_interval > 0 {
+		g.Go(func() error {
+			status_thread(gctx, *status_interval)
+			return nil
+		})
+	}
+
+	// goroutines which perform mining
+	if err := apply_reloadable_settings(flag.CommandLine, g_miner); err != nil {
+		say("Error: %v", err)
+		os.Exit(2)
+	}
+	// The schedule thread runs even without a schedule, in case a config
+	// reload sets one.
+	g.Go(func() error {
+		schedule_thread(gctx, *pause_load, monitor)
+		return nil
+	})
+	if *autotune > 0 {
+		// The list was already checked by apply_runtime_settings.
+		var cpus []int
+		if *cpu_list != "" {
+			cpus, _ = miner.ParseCPUList(*cpu_list)
+		}
+		g.Go(func() error {
+			autotune_thread(gctx, g_miner, *workers, cpus, *autotune)
+			return nil
+		})
+	}
+	if err := start_gpus(g_miner, *gpu_list); err != nil {
+		say("Error: %v", err)
+		os.Exit(2)
+	}
+	g.Go(func() error {
+		<-gctx.Done()
+		g_miner.Wait()
+		return nil
+	})
+
+	// wait for all goroutines to exit
+	err = g.Wait()
+	if err != nil {
+		say("Error: %v", err)
+	} else {
+		say("all goroutines exited")
+	}
+
+	flush_unsubmitted(solutions)
+	elapsed := time.Since(started)
+	attempts := g_miner.Attempts()
+	say("Mined for %v: %d hashes at %s, %d mining reports accepted, %d marginal solutions skipped", elapsed.Round(time.Second), attempts, get_speed_string(attempts, elapsed), atomic.LoadUint64(&g_accepted_reports), atomic.LoadUint64(&g_marginal_skipped))
+}
+
+// flush_unsubmitted moves any solutions still queued once mining has stopped
+// into the pending log, to be submitted on the next run.  A solution which was
+// awaiting a retry is already there.
+func flush_unsubmitted(solutions *miner.SolutionQueue) {
+	for {
+		select {
+		case soln := <-solutions.C():
+			if err := add_pending(soln); err != nil {
+				say("Error: failed to record pending solution in %s: %v", g_paths.PendingLog(), err)
+				// The secret is shown as there is nowhere else it is kept.
+				say("Unsubmitted solution: %v %v", soln.Hash, soln.Reward)
+				continue
+			}
+			say("Saved unsubmitted solution %v to %s", soln.Hash, g_paths.PendingLog())
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/maaku/gocash/internal/i18n"
+	_ "github.com/maaku/gocash/internal/i18n/locales"
+	"github.com/maaku/gocash/internal/style"
+)
+
+// T translates a user-facing message into the user's language.
+var T = i18n.T
+
+// say prints a user-facing message, translated into the user's language, on a
+// line of its own.  Errors and warnings are styled as such.
+func say(format string, args ...interface{}) {
+	say_as(role_of(format), format, args...)
+}
+
+// say_as prints a user-facing message like say, styled for the given role.
+func say_as(role style.Role, format string, args ...interface{}) {
+	text := i18n.Sprintf(format, args...)
+	if !g_secret_messages[format] {
+		remember(style.Strip(text))
+	}
+	show(style.Paint(role, text))
+}
+
+// Messages which show the secrets of mined webcash, and so are never kept
+// among the recent lines served by the dashboard.
+var g_secret_messages = map[string]bool{
+	"Recovered %v":                true,
+	"Unsubmitted solution: %v %v": true,
+	"Swept %v to e%v:secret:%s":   true,
+}
+
+// In plain mode, output is strictly line-oriented text: no color, no escape
+// sequences and no updating of lines in place, for screen readers, dumb
+// terminals and log collectors.  Anything which would draw on the terminal
+// must check it.
+var g_plain bool
+
+// set_plain turns plain mode on or off.
+func set_plain(plain bool) {
+	g_plain = plain
+	if plain {
+		style.Disable()
+	}
+}
+
+// show prints text on a line of its own.  In plain mode it is stripped of any
+// control sequences first, as text from the server could contain them too.
+func show(text string) {
+	if g_plain {
+		text = style.Strip(text)
+	}
+	fmt.Println(text)
+}
+
+// The most recent lines shown, oldest first, for the dashboard.
+var g_recent = struct {
+	sync.Mutex
+	lines []string
+}{}
+
+// The number of lines kept in g_recent.
+const recent_line_count = 100
+
+// remember adds a line to g_recent, dropping the oldest if it is full.
+func remember(line string) {
+	g_recent.Lock()
+	defer g_recent.Unlock()
+	if len(g_recent.lines) == recent_line_count {
+		copy(g_recent.lines, g_recent.lines[1:])
+		g_recent.lines = g_recent.lines[:recent_line_count-1]
+	}
+	g_recent.lines = append(g_recent.lines, line)
+}
+
+// recent_lines returns a copy of the lines in g_recent.
+func recent_lines() []string {
+	g_recent.Lock()
+	defer g_recent.Unlock()
+	return append([]string(nil), g_recent.lines...)
+}
+
+// role_of infers the role of a message from its English text, which is the
+// same whatever language it is shown in.
+func role_of(format string) style.Role {
+	switch {
+	case strings.HasPrefix(format, "Error"):
+		return style.Error
+	case strings.HasPrefix(format, "Warning"):
+		return style.Warning
+	case strings.HasPrefix(format, "closing "), strings.HasPrefix(format, "caught "), strings.HasPrefix(format, "all goroutines"):
+		return style.Muted
+	}
+	return style.Normal
+}
+
+// All prompts share one reader, so that input typed ahead of a prompt, or
+// piped in, is not lost in the buffer of another.
+var g_stdin = bufio.NewReader(os.Stdin)
+
+// ask prints a translated question, without a trailing newline, and returns
+// the user's answer with surrounding space removed.
+func ask(question string, args ...interface{}) string {
+	fmt.Print(i18n.Sprintf(question, args...))
+	answer, _ := g_stdin.ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+// confirm asks a yes-or-no question, to which anything but yes is no.
+func confirm(question string, args ...interface{}) bool {
+	answer := strings.ToLower(ask(question, args...))
+	return answer == "y" || answer == "yes" || answer == T("y") || answer == T("yes")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// api_latency totals the time taken by requests to the server, by endpoint.
+var api_latency = struct {
+	sync.Mutex
+	count   map[string]uint64
+	seconds map[string]float64
+}{
+	count:   make(map[string]uint64),
+	seconds: make(map[string]float64),
+}
+
+// A latency_transport is an http.RoundTripper which adds the duration of
+// every request made through it to api_latency.
+type latency_transport struct {
+	inner http.RoundTripper
+}
+
+func (t *latency_transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.inner.RoundTrip(req)
+	seconds := time.Since(start).Seconds()
+	api_latency.Lock()
+	api_latency.count[req.URL.Path]++
+	api_latency.seconds[req.URL.Path] += seconds
+	api_latency.Unlock()
+	return resp, err
+}
+
+// serve_metrics serves the miner's metrics on addr, in the Prometheus text
+// format at /metrics, until ctx is cancelled.
+func serve_metrics(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		write_metrics(w)
+	})
+	say("Serving metrics on http://%s/metrics", listener.Addr())
+	return serve_http(ctx, listener, mux, "", "")
+}
+
+// write_metrics writes the current metrics in the Prometheus text format.
+func write_metrics(w io.Writer) {
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	settings := g_miner.Settings()
+
+	metric("gocash_hashes_total", "counter", "Hashes computed since startup.")
+	fmt.Fprintf(w, "gocash_hashes_total %d\n", g_miner.Attempts())
+	metric("gocash_mining_threads", "gauge", "Number of CPU mining threads running.")
+	fmt.Fprintf(w, "gocash_mining_threads %d\n", len(g_miner.ThreadAttempts()))
+	metric("gocash_difficulty", "gauge", "Difficulty required by the server, in leading zero bits.")
+	fmt.Fprintf(w, "gocash_difficulty %d\n", settings.Difficulty)
+	metric("gocash_epoch", "gauge", "Current subsidy epoch.")
+	fmt.Fprintf(w, "gocash_epoch %d\n", settings.Epoch)
+
+	metric("gocash_mining_reports_total", "counter", "Mining reports answered by the server since startup, by outcome.")
+	fmt.Fprintf(w, "gocash_mining_reports_total{outcome=\"accepted\"} %d\n", atomic.LoadUint64(&g_accepted_reports))
+	fmt.Fprintf(w, "gocash_mining_reports_total{outcome=\"rejected\"} %d\n", atomic.LoadUint64(&g_rejected_reports))
+	metric("gocash_marginal_solutions_skipped_total", "counter", "Solutions not submitted for lack of the -margin bits.")
+	fmt.Fprintf(w, "gocash_marginal_solutions_skipped_total %d\n", atomic.LoadUint64(&g_marginal_skipped))
+	metric("gocash_expected_solutions_total", "counter", "Solutions expected on average from the hashes computed since startup.")
+	fmt.Fprintf(w, "gocash_expected_solutions_total %g\n", math.Float64frombits(atomic.LoadUint64(&g_expected_solutions)))
+
+	if total, err := mining_log_total(); err == nil {
+		metric("gocash_mining_log_webcash", "gauge", "Total of the webcash in the mining log.")
+		fmt.Fprintf(w, "gocash_mining_log_webcash %s\n", total)
+	}
+
+	api_latency.Lock()
+	paths := make([]string, 0, len(api_latency.count))
+	for path := range api_latency.count {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	metric("gocash_api_request_duration_seconds", "summary", "Time taken by requests to the server, by endpoint.")
+	for _, path := range paths {
+		fmt.Fprintf(w, "gocash_api_request_duration_seconds_sum{path=%s} %g\n", strconv.Quote(path), api_latency.seconds[path])
+		fmt.Fprintf(w, "gocash_api_request_duration_seconds_count{path=%s} %d\n", strconv.Quote(path), api_latency.count[path])
+	}
+	api_latency.Unlock()
+}
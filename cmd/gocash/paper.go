@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"html/template"
+	"image/png"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maaku/gocash/internal/qr"
+	"github.com/maaku/gocash/internal/style"
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+//go:embed paper.html
+var paper_html string
+
+// The printable paper wallet, whose labels are translated as it is rendered.
+var paper_template = template.Must(template.New("paper").Funcs(template.FuncMap{"T": T}).Parse(paper_html))
+
+// A paper_wallet is what the paper wallet page shows.
+type paper_wallet struct {
+	Amount  webcash.Amount
+	Memo    string
+	Code    string
+	Public  string
+	Created string
+	// The claim code as a QR code, a PNG data URL.
+	QR template.URL
+}
+
+// run_paper pays webcash out of the wallet into a paper wallet: a page to
+// print, for a gift or for cold storage, with the claim code and its QR code.
+// The wallet watches the public webcash, so that `gocash watch check` tells
+// whether it has been claimed.
+func run_paper(args []string) int {
+	flags := flag.NewFlagSet("paper", flag.ContinueOnError)
+	wallet_flag(flags)
+	output := flags.String("o", "", T("write the page to `file` (default: paper-<hash>.html in the current directory)"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 1 {
+		say("Usage: gocash paper [-o file] <amount> [memo]")
+		return 2
+	}
+	amount, err := webcash.ParseAmount(strings.TrimPrefix(flags.Arg(0), "e"))
+	if err == nil && amount == 0 {
+		err = fmt.Errorf("%w: amount must be positive", webcash.ErrInvalidAmount)
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	memo := strings.Join(flags.Args()[1:], " ")
+
+	w, err := load_spendable_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	log_memo := memo
+	if log_memo == "" {
+		log_memo = T("paper wallet")
+	}
+	strategy, err := wallet.ParseStrategy(config_setting("coin-selection", wallet.DefaultStrategy))
+	if err == nil {
+		var payment webcash.SecretWebcash
+		if payment, err = make_payment(w, amount, strategy, log_memo); err == nil {
+			err = write_paper_wallet(w, payment, memo, *output)
+		}
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// write_paper_wallet renders the page of a payment made, records its public
+// webcash as watched, and saves the wallet.  If the page cannot be written,
+// the claim code is printed instead, as it is kept nowhere else.
+func write_paper_wallet(w *wallet.Wallet, payment webcash.SecretWebcash, memo, path string) error {
+	pk := webcash.FromSecret(payment)
+	if path == "" {
+		path = fmt.Sprintf("paper-%x.html", pk.Hash[:4])
+	}
+	w.Watch(pk)
+	if err := save_wallet(w); err != nil {
+		say("Error: %v", err)
+	}
+
+	page, err := render_paper_wallet(payment, memo)
+	if err == nil {
+		err = os.WriteFile(path, page, 0600)
+		webcash.Wipe(page)
+	}
+	if err != nil {
+		say("Error: unable to write the paper wallet: %v", err)
+		say("Its claim code, which is kept nowhere else:")
+		print_secret(payment, "")
+		return err
+	}
+	say_as(style.Success, "Paid %v webcash into a paper wallet, leaving a balance of %v", payment.Amount, w.Balance())
+	say("Print %s, then delete it: whoever has the file can spend the webcash.", path)
+	say("Check whether it has been claimed with `gocash watch check`.")
+	return nil
+}
+
+// render_paper_wallet renders the page of a paper wallet.
+func render_paper_wallet(payment webcash.SecretWebcash, memo string) ([]byte, error) {
+	code, err := qr.Encode(payment.String())
+	if err != nil {
+		return nil, err
+	}
+	var img bytes.Buffer
+	if err := png.Encode(&img, code.Image(qr_scale)); err != nil {
+		return nil, err
+	}
+	var page bytes.Buffer
+	err = paper_template.Execute(&page, paper_wallet{
+		Amount:  payment.Amount,
+		Memo:    memo,
+		Code:    payment.String(),
+		Public:  webcash.FromSecret(payment).String(),
+		Created: time.Now().Format("2006-01-02 15:04"),
+		QR:      template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(img.Bytes())),
+	})
+	return page.Bytes(), err
+}
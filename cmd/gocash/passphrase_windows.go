@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+var set_console_mode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// read_passphrase asks for a passphrase, without echoing what is typed if
+// stdin is a console.
+func read_passphrase(prompt string) ([]byte, error) {
+	fmt.Print(prompt)
+	const enable_echo_input = 0x4
+	handle := syscall.Handle(os.Stdin.Fd())
+	var mode uint32
+	if syscall.GetConsoleMode(handle, &mode) == nil {
+		if r, _, _ := set_console_mode.Call(uintptr(handle), uintptr(mode&^enable_echo_input)); r != 0 {
+			defer func() {
+				set_console_mode.Call(uintptr(handle), uintptr(mode))
+				fmt.Println()
+			}()
+		}
+	}
+	return read_line()
+}
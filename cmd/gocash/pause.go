@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Mining runs only while neither the scheduler nor the user has paused it.
+var g_pause struct {
+	sync.Mutex
+	scheduled bool
+	manual    bool
+}
+
+// set_scheduled_pause records whether the scheduler wants mining paused, and
+// pauses or resumes the miner to match.
+func set_scheduled_pause(paused bool) {
+	g_pause.Lock()
+	defer g_pause.Unlock()
+	g_pause.scheduled = paused
+	g_miner.SetPaused(g_pause.scheduled || g_pause.manual)
+}
+
+// set_manual_pause records whether the user wants mining paused, and pauses or
+// resumes the miner to match.  Mining paused by the scheduler stays paused.
+func set_manual_pause(paused bool) {
+	g_pause.Lock()
+	defer g_pause.Unlock()
+	if paused == g_pause.manual {
+		return
+	}
+	g_pause.manual = paused
+	g_miner.SetPaused(g_pause.scheduled || g_pause.manual)
+	if paused {
+		say("Mining paused by request")
+	} else if g_pause.scheduled {
+		say("Mining resumed by request, but remains paused by the schedule")
+	} else {
+		say("Mining resumed by request")
+	}
+}
+
+// toggle_manual_pause pauses mining if the user hasn't, and resumes it if
+// they have.
+func toggle_manual_pause() {
+	g_pause.Lock()
+	paused := g_pause.manual
+	g_pause.Unlock()
+	set_manual_pause(!paused)
+}
+
+// keyboard_thread toggles the pause whenever "p" is entered, if standard input
+// is a terminal.  It returns when input ends; a read in progress when ctx is
+// cancelled is abandoned along with the process.
+func keyboard_thread(ctx context.Context) {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	say("Enter \"p\" to pause or resume mining")
+	for ctx.Err() == nil {
+		line, err := g_stdin.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if strings.EqualFold(strings.TrimSpace(line), "p") {
+			toggle_manual_pause()
+		}
+	}
+}
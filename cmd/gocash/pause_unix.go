@@ -0,0 +1,25 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// pause_signal_thread pauses mining on SIGUSR1 and resumes it on SIGUSR2.
+func pause_signal_thread(ctx context.Context) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(c)
+	for {
+		select {
+		case sig := <-c:
+			set_manual_pause(sig == syscall.SIGUSR1)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
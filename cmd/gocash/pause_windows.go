@@ -0,0 +1,7 @@
+package main
+
+import "context"
+
+// Windows has no user signals; mining can still be paused from the keyboard
+// or the dashboard.
+func pause_signal_thread(ctx context.Context) {}
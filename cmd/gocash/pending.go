@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/maaku/gocash/internal/style"
+	"github.com/maaku/gocash/miner"
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+// The pending log holds every solution from just before it is submitted until
+// the server has accepted or rejected it, one JSON object per line, so that a
+// crash or network outage in between doesn't lose the mined webcash.  Only
+// the update thread and startup reconciliation use it, never at once.
+//
+// The preimage of a solution holds the secret of its reward, so when mining
+// into an encrypted wallet each solution is sealed under the wallet's key,
+// leaving only its hash readable.
+
+// A pending_entry is a line of the pending log: a solution, or a sealed one.
+type pending_entry struct {
+	Hash   webcash.Uint256 `json:"hash"`
+	Sealed []byte          `json:"sealed,omitempty"`
+}
+
+// add_pending records a solution as awaiting submission, syncing it to disk
+// before returning.
+func add_pending(soln miner.Solution) error {
+	line, err := json.Marshal(soln)
+	if err != nil {
+		return err
+	}
+	if !logs_secrets() && g_wallet.Encrypted() {
+		sealed, err := g_wallet.Seal(line)
+		webcash.Wipe(line)
+		if err != nil {
+			return err
+		}
+		if line, err = json.Marshal(pending_entry{Hash: soln.Hash, Sealed: sealed}); err != nil {
+			return err
+		}
+	}
+	defer webcash.Wipe(line)
+	f, err := os.OpenFile(g_paths.PendingLog(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// read_pending returns the lines of the pending log, and the hash of each, by
+// which it is resolved.  A line which does not parse has the zero hash.
+func read_pending() ([][]byte, []webcash.Uint256, error) {
+	f, err := os.Open(g_paths.PendingLog())
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var lines [][]byte
+	var hashes []webcash.Uint256
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		var entry pending_entry
+		json.Unmarshal(line, &entry)
+		lines = append(lines, line)
+		hashes = append(hashes, entry.Hash)
+	}
+	return lines, hashes, scanner.Err()
+}
+
+// load_pending returns the solutions awaiting submission, once each.  Lines
+// which do not parse, or are sealed under a key other than the wallet's, are
+// skipped, with a warning, and left in the log.
+func load_pending() ([]miner.Solution, error) {
+	lines, _, err := read_pending()
+	defer func() {
+		for _, line := range lines {
+			webcash.Wipe(line)
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	var solutions []miner.Solution
+	seen := make(map[webcash.Uint256]bool)
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var entry pending_entry
+		if err := json.Unmarshal(line, &entry); err == nil && entry.Sealed != nil {
+			if g_wallet == nil {
+				err = wallet.ErrNotEncrypted
+			} else {
+				line, err = g_wallet.Unseal(entry.Sealed)
+			}
+			if err != nil {
+				say("Warning: %s:%d: %v (`gocash recover` can find the webcash of a report which was accepted)", g_paths.PendingLog(), i+1, err)
+				continue
+			}
+			defer webcash.Wipe(line)
+		}
+		var soln miner.Solution
+		if err := json.Unmarshal(line, &soln); err != nil {
+			say("Warning: %s:%d: %v", g_paths.PendingLog(), i+1, err)
+			continue
+		}
+		if !seen[soln.Hash] {
+			seen[soln.Hash] = true
+			solutions = append(solutions, soln)
+		}
+	}
+	return solutions, nil
+}
+
+// remove_pending drops a solution from the pending log once it is resolved,
+// leaving every other line as it is.  The log is rewritten to a temporary file
+// which replaces it, so that a crash part way through leaves either the old
+// log or the new one.
+func remove_pending(hash webcash.Uint256) error {
+	lines, hashes, err := read_pending()
+	defer func() {
+		for _, line := range lines {
+			webcash.Wipe(line)
+		}
+	}()
+	if err != nil {
+		return err
+	}
+	path := g_paths.PendingLog()
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pending-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	kept := 0
+	for i, line := range lines {
+		if len(line) > 0 && hashes[i] != hash {
+			if _, err := tmp.Write(append(line, '\n')); err != nil {
+				tmp.Close()
+				return err
+			}
+			kept++
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if kept == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// resubmit_pending reconciles the solutions left pending by a previous run.
+// Those whose reward the server already knows of were accepted, and their
+// claim codes are recorded if the mining log lacks them; those it doesn't are
+// submitted again.  Solutions which still cannot be submitted are left for
+// the next run.
+func resubmit_pending() {
+	solutions, err := load_pending()
+	if err != nil {
+		say("Error: %v", err)
+		return
+	}
+	if len(solutions) == 0 {
+		return
+	}
+	say("Reconciling %d pending mining reports from %s", len(solutions), g_paths.PendingLog())
+
+	pks := make([]webcash.PublicWebcash, len(solutions))
+	for i, soln := range solutions {
+		pks[i] = webcash.FromSecret(soln.Reward)
+	}
+	status, err := g_client.HealthCheck(pks)
+	if err != nil {
+		say("Error: unable to check pending mining reports, will retry next run: %v", err)
+		return
+	}
+	_, logged, err := read_mining_log(g_paths.MiningLog())
+	if err != nil {
+		say("Error: %v", err)
+		return
+	}
+	in_log := make(map[webcash.Uint256]bool)
+	for _, pk := range logged {
+		in_log[pk.Hash] = true
+	}
+
+	for i, soln := range solutions {
+		if s, ok := status[pks[i].Hash]; ok && s.Spent != nil && soln.Remote && *s.Spent {
+			// The reward from a worker was replaced before the previous run
+			// stopped, or else the worker spent it.
+			say("Warning: the reward of solution %v, from a worker, is already spent", soln.Hash)
+		} else if ok && s.Spent != nil {
+			// Accepted before the previous run stopped.  It stays pending
+			// until it is kept somewhere: the wallet, if there is one, or
+			// else the log.
+			reward, err := own_reward(soln)
+			if err != nil {
+				say("Error: %v", err)
+				continue
+			}
+			if err := deposit_mined(reward); err != nil {
+				say("Error: failed to add mined webcash to %s: %v", g_store.Path(), err)
+				continue
+			}
+			if !in_log[webcash.FromSecret(reward).Hash] {
+				if err := record_webcash(reward); err != nil {
+					say("Error: failed to open %s: %v", g_paths.MiningLog(), err)
+					if g_wallet == nil {
+						continue
+					}
+				}
+				say_as(style.Success, "Recovered %v", webcash.FromSecret(reward))
+			}
+		} else if err := submit_solution(soln); err != nil {
+			continue
+		}
+		if err := remove_pending(soln.Hash); err != nil {
+			say("Error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+)
+
+// serve_pprof serves the runtime profiles of net/http/pprof on addr until ctx
+// is cancelled.  A bare port number is taken to be on localhost, as profiles
+// are not for sharing beyond the machine.
+func serve_pprof(ctx context.Context, addr string) error {
+	if _, err := strconv.Atoi(addr); err == nil {
+		addr = "localhost:" + addr
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	say("Serving profiles on http://%s/debug/pprof/", listener.Addr())
+	return serve_http(ctx, listener, mux, "", "")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+
+	"github.com/maaku/gocash/internal/qr"
+)
+
+// The pixels to a module of the QR codes written as images.
+const qr_scale = 8
+
+// print_qr prints text as a QR code, for a phone to scan from the terminal.
+func print_qr(text string) error {
+	code, err := qr.Encode(text)
+	if err != nil {
+		return err
+	}
+	fmt.Print(code.Terminal())
+	return nil
+}
+
+// write_qr writes text as a QR code to a PNG image at path, readable only by
+// the user, as it may hold a claim code.
+func write_qr(path, text string) error {
+	code, err := qr.Encode(text)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, code.Image(qr_scale))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// read_qr reads the text of the QR code in the image at path, a PNG, JPEG or
+// GIF.
+func read_qr(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	text, err := qr.Decode(img)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return text, nil
+}
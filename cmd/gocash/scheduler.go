@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/maaku/gocash/internal/i18n"
+	"github.com/maaku/gocash/internal/schedule"
+)
+
+// How often the scheduler checks whether mining should run.
+const schedule_interval = 30 * time.Second
+
+// The mining schedule.  Set up in main(), and changed by a config reload.
+var g_schedule atomic.Pointer[schedule.Schedule]
+
+// schedule_thread pauses mining outside the windows of g_schedule and, if
+// pause_load is nonzero, while other programs use more than that percentage
+// of the CPU, resuming it otherwise.  A change to the schedule takes effect
+// within schedule_interval.
+func schedule_thread(ctx context.Context, pause_load int, monitor *schedule.UsageMonitor) {
+	paused := false
+	for {
+		reason := ""
+		if sched := g_schedule.Load(); sched != nil && !sched.Active(time.Now()) {
+			reason = T("outside the mining schedule")
+		} else if monitor != nil {
+			usage, err := monitor.Sample()
+			if err != nil {
+				say("Error: %v", err)
+			} else if 100*usage > float64(pause_load) {
+				reason = i18n.Sprintf("other programs are using %.0f%% of the CPU", 100*usage)
+			}
+		}
+		if (reason != "") != paused {
+			paused = !paused
+			set_scheduled_pause(paused)
+			if paused {
+				say("Pausing mining: %s", reason)
+			} else {
+				say("Resuming mining")
+			}
+		}
+		if !sleep_ctx(ctx, schedule_interval) {
+			return
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/maaku/gocash/internal/style"
+	"github.com/maaku/gocash/webcash"
+)
+
+// new_secret returns webcash of the given amount under a fresh random secret.
+func new_secret(amount webcash.Amount) (webcash.SecretWebcash, error) {
+	var entropy [32]byte
+	if _, err := rand.Read(entropy[:]); err != nil {
+		return webcash.SecretWebcash{}, err
+	}
+	return webcash.SecretWebcash{Secret: hex.EncodeToString(entropy[:]), Amount: amount}, nil
+}
+
+// errSkipped marks a self-test step which could not be run, without that
+// being a failure.
+var errSkipped = errors.New("skipped")
+
+func run_selftest(args []string) int {
+	flags := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	replace := flags.Bool("replace", true, T("include a self-replace of the smallest unspent mined webcash"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	failed := false
+	step := func(name string, run func() (string, error)) bool {
+		detail, err := run()
+		switch {
+		case err == nil:
+			show(style.Paint(style.Success, "PASS") + "  " + T(name) + ": " + detail)
+			return true
+		case errors.Is(err, errSkipped):
+			show(style.Paint(style.Muted, "SKIP") + "  " + T(name) + ": " + detail)
+		default:
+			show(style.Paint(style.Error, "FAIL") + "  " + T(name) + ": " + err.Error())
+			failed = true
+		}
+		return false
+	}
+
+	step("fetch settings", func() (string, error) {
+		settings, err := g_client.Target()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(settings), nil
+	})
+
+	step("terms of service", func() (string, error) {
+		acceptance, err := read_terms_acceptance()
+		if err != nil {
+			return "", fmt.Errorf("%w (run `gocash init`)", webcash.ErrTermsNotAccepted)
+		}
+		terms, err := g_client.TermsOfService()
+		if err != nil {
+			return "", err
+		}
+		hash := sha256.Sum256([]byte(terms))
+		if hex.EncodeToString(hash[:]) != acceptance.Hash {
+			return "", fmt.Errorf("%w: the terms have changed since they were accepted", webcash.ErrTermsNotAccepted)
+		}
+		return T("accepted terms are current"), nil
+	})
+
+	// Find the smallest unspent webcash to exercise replace and health check
+	// with, so as little as possible is at stake.
+	var input, output webcash.SecretWebcash
+	found := step("find unspent webcash", func() (string, error) {
+		if !*replace {
+			return T("disabled by -replace=false"), errSkipped
+		}
+		secrets, _, err := read_mining_log(g_paths.MiningLog())
+		if err != nil {
+			return "", err
+		}
+		if len(secrets) == 0 {
+			return fmt.Sprintf(T("no mined webcash in %s"), g_paths.MiningLog()), errSkipped
+		}
+		pks := make([]webcash.PublicWebcash, len(secrets))
+		for i, sk := range secrets {
+			pks[i] = webcash.FromSecret(sk)
+		}
+		status, err := g_client.HealthCheck(pks)
+		if err != nil {
+			return "", err
+		}
+		for i, sk := range secrets {
+			s := status[pks[i].Hash]
+			if s.Spent != nil && !*s.Spent && (input.Amount == 0 || sk.Amount < input.Amount) {
+				input = sk
+			}
+		}
+		if input.Amount == 0 {
+			return T("all mined webcash is spent"), errSkipped
+		}
+		return fmt.Sprint(webcash.FromSecret(input)), nil
+	})
+
+	if found {
+		replaced := step("self-replace", func() (string, error) {
+			var err error
+			if output, err = new_secret(input.Amount); err != nil {
+				return "", err
+			}
+			// Record the new secret before the server learns of it, so that
+			// it is not lost if anything goes wrong from here on.
+			if err := record_webcash(output); err != nil {
+				return "", err
+			}
+			if err := g_client.Replace([]webcash.SecretWebcash{input}, []webcash.SecretWebcash{output}); err != nil {
+				return "", err
+			}
+			return fmt.Sprint(webcash.FromSecret(output)), nil
+		})
+		if replaced {
+			step("health check", func() (string, error) {
+				old_pk, new_pk := webcash.FromSecret(input), webcash.FromSecret(output)
+				status, err := g_client.HealthCheck([]webcash.PublicWebcash{old_pk, new_pk})
+				if err != nil {
+					return "", err
+				}
+				if s := status[old_pk.Hash]; s.Spent == nil || !*s.Spent {
+					return "", errors.New(T("the replaced webcash is not marked spent"))
+				}
+				if s := status[new_pk.Hash]; s.Spent == nil || *s.Spent {
+					return "", errors.New(T("the new webcash is not marked unspent"))
+				}
+				return T("replaced webcash is spent, new webcash is unspent"), nil
+			})
+		}
+	}
+
+	if failed {
+		say("Error: self-test failed")
+		return 1
+	}
+	say_as(style.Success, "Self-test passed.")
+	return 0
+}
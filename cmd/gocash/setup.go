@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+// run_init walks a new user through setting up gocash: where its files go,
+// the terms of service, and how much of the machine to mine with.  Choices
+// are saved to the config file, so running it again changes them.
+func run_init(args []string) int {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	accept_terms := flags.Bool("accept-terms", false, T("accept the terms of service without prompting"))
+	threads := flags.Int("threads", 0, T("number of CPUs to mine with, instead of asking"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	say("Welcome to gocash.  This will set up mining on this machine.")
+	if err := g_paths.Create(); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	say("Files are kept in %s, %s and %s (see `gocash paths`).", g_paths.Config, g_paths.Wallet, g_paths.Log)
+
+	// Always check the terms anew, rather than trusting a recent acceptance,
+	// so that the user sees them at least once here.
+	fmt.Println()
+	if err := ensure_terms_accepted(0, *accept_terms); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	say("The terms of service are accepted.")
+
+	fmt.Println()
+	cpus := runtime.NumCPU()
+	for *threads <= 0 || *threads > cpus {
+		answer := ask("This machine has %d CPUs.  How many should mine? [%d] ", cpus, cpus)
+		if answer == "" {
+			*threads = cpus
+			break
+		}
+		n, err := strconv.Atoi(answer)
+		if err != nil || n <= 0 || n > cpus {
+			say("Please enter a number from 1 to %d.", cpus)
+			continue
+		}
+		*threads = n
+	}
+	if err := set_config_value(g_paths.ConfigFile(), "workers", strconv.Itoa(*threads)); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	say("Saved to %s.", g_paths.ConfigFile())
+
+	fmt.Println()
+	say("Setup is complete.  Run `gocash` to start mining; mined webcash will be logged to %s.", g_paths.MiningLog())
+	return 0
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// A terms_acceptance records which version of the terms of service the user
+// agreed to, and when that was last confirmed against the server.
+type terms_acceptance struct {
+	// The SHA256 hash of the accepted terms text, hex-encoded.
+	Hash string `json:"sha256"`
+	// When the terms were last fetched and found to match Hash.
+	Checked time.Time `json:"checked"`
+}
+
+func read_terms_acceptance() (terms_acceptance, error) {
+	var acceptance terms_acceptance
+	data, err := os.ReadFile(g_paths.TermsFile())
+	if err != nil {
+		return acceptance, err
+	}
+	err = json.Unmarshal(data, &acceptance)
+	return acceptance, err
+}
+
+func write_terms_acceptance(acceptance terms_acceptance) error {
+	data, err := json.Marshal(acceptance)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(g_paths.TermsFile(), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to record acceptance of terms: %w", err)
+	}
+	return nil
+}
+
+// ensure_terms_accepted makes sure the user has agreed to the current terms of
+// service.  The terms are only fetched from the server if no acceptance is on
+// record, or if the record is older than max_age, in which case the terms are
+// checked for changes.  New or changed terms are printed and the user is asked
+// to accept them, unless accept is set.
+func ensure_terms_accepted(max_age time.Duration, accept bool) error {
+	acceptance, err := read_terms_acceptance()
+	if err == nil && time.Since(acceptance.Checked) < max_age {
+		return nil
+	}
+
+	terms, err := g_client.TermsOfService()
+	if err != nil {
+		return fmt.Errorf("unable to fetch terms of service: %w", err)
+	}
+	hash := sha256.Sum256([]byte(terms))
+	current := terms_acceptance{
+		Hash:    hex.EncodeToString(hash[:]),
+		Checked: time.Now(),
+	}
+
+	// Unchanged since they were accepted, so just note that we checked.
+	if acceptance.Hash == current.Hash {
+		return write_terms_acceptance(current)
+	}
+
+	show(terms)
+	if acceptance.Hash != "" {
+		say("The terms of service have changed since you last accepted them.")
+	}
+	if !accept && !confirm("Do you accept the terms of service? [y/N] ") {
+		return webcash.ErrTermsNotAccepted
+	}
+	return write_terms_acceptance(current)
+}
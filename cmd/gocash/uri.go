@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// parse_claim parses what is given to insert: a claim code, or a webcash URI
+// of a payment, which must not have expired.  The URI's memo, if any, is
+// returned with the webcash.
+func parse_claim(text string) (webcash.SecretWebcash, string, error) {
+	if !webcash.IsURI(text) {
+		sk, err := webcash.ParseSecretWebcash(text)
+		return sk, "", err
+	}
+	uri, err := webcash.ParseURI(text)
+	if err != nil {
+		return webcash.SecretWebcash{}, "", err
+	}
+	if uri.Webcash == nil {
+		return webcash.SecretWebcash{}, "", errors.New("that link is a request for payment; pay it with `gocash pay`")
+	}
+	if err := uri.Check(time.Now()); err != nil {
+		return webcash.SecretWebcash{}, "", err
+	}
+	return *uri.Webcash, uri.Memo, nil
+}
+
+// parse_request parses a webcash URI of a request for payment, which must not
+// have expired, for pay.
+func parse_request(text string) (webcash.Amount, string, error) {
+	uri, err := webcash.ParseURI(text)
+	if err != nil {
+		return 0, "", err
+	}
+	if uri.Webcash != nil {
+		return 0, "", errors.New("that link is a payment, not a request; claim it with `gocash insert`")
+	}
+	if uri.Amount == 0 {
+		return 0, "", errors.New("that request leaves the amount up to the payer; pay it with `gocash pay <amount>`")
+	}
+	if err := uri.Check(time.Now()); err != nil {
+		return 0, "", err
+	}
+	return uri.Amount, uri.Memo, nil
+}
+
+// parse_expiry parses how long a link is good for, e.g. "24h", returning when
+// it expires, or the zero time for the empty string, which is forever.
+func parse_expiry(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid expiry %q: expected a duration such as \"24h\"", value)
+	}
+	return time.Now().Add(d).Truncate(time.Second), nil
+}
+
+// run_request prints a webcash URI asking to be paid, for another user to
+// hand to `gocash pay`, or to their own client.
+func run_request(args []string) int {
+	flags := flag.NewFlagSet("request", flag.ContinueOnError)
+	expires := flags.String("expires", "", T("how long the request is good for, e.g. \"24h\" (default: forever)"))
+	show_qr := flags.Bool("qr", false, T("also show the request as a QR code, for a phone to scan"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 1 {
+		say("Usage: gocash request <amount> [memo]")
+		return 2
+	}
+	amount, err := webcash.ParseAmount(strings.TrimPrefix(flags.Arg(0), "e"))
+	if err == nil && amount == 0 {
+		err = fmt.Errorf("%w: amount must be positive", webcash.ErrInvalidAmount)
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	expiry, err := parse_expiry(*expires)
+	if err != nil {
+		say("Error: %v", err)
+		return 2
+	}
+	uri := webcash.URI{Amount: amount, Memo: strings.Join(flags.Args()[1:], " "), Expires: expiry}
+	fmt.Println(uri)
+	if *show_qr {
+		if err := print_qr(uri.String()); err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,1465 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/internal/i18n"
+	"github.com/maaku/gocash/internal/paths"
+	"github.com/maaku/gocash/internal/style"
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+// The wallet which mined webcash goes to, or nil if there is none.  Set up in
+// main(); the mining threads and the update thread share it, under
+// g_wallet_mutex.
+var g_wallet *wallet.Wallet
+var g_wallet_mutex sync.Mutex
+
+// The usage of -wallet, which mining and the wallet commands share.
+const wallet_flag_usage = "`name` of the wallet to use, e.g. \"savings\", kept in <name>_wallet.webcash (default: the wallet setting of the config file, or \"default\")"
+
+// The usage of -wallet-wait, likewise shared.
+const wallet_wait_usage = "if another gocash is using the wallet, wait for it to finish rather than failing"
+
+// Whether to wait for the wallet if another process holds it.
+var g_wallet_wait bool
+
+// wallet_flag adds -wallet to a command's flags, selecting which of the user's
+// wallets it works on, and -wallet-wait.
+func wallet_flag(flags *flag.FlagSet) {
+	flags.Func("wallet", T(wallet_flag_usage), select_wallet)
+	flags.BoolVar(&g_wallet_wait, "wallet-wait", false, T(wallet_wait_usage))
+}
+
+// select_wallet selects the named wallet for what follows.
+func select_wallet(name string) error {
+	if err := paths.CheckWalletName(name); err != nil {
+		return err
+	}
+	g_paths.WalletName = name
+	return nil
+}
+
+// Where the wallet is kept, once open_store has opened it.
+var g_store wallet.Store
+
+// The number of previous versions of a wallet file to keep.
+var g_wallet_backups = wallet.DefaultBackups
+
+// open_store opens where the wallet is kept: the SQLite database, if there is
+// one, and otherwise the wallet file, whether it exists yet or not.
+func open_store() (wallet.Store, error) {
+	if g_store != nil {
+		return g_store, nil
+	}
+	path := g_paths.WalletFile()
+	if _, err := os.Stat(g_paths.WalletDB()); err == nil {
+		path = g_paths.WalletDB()
+	}
+	store, err := open_locked(path)
+	if err != nil {
+		return nil, err
+	}
+	if file, ok := store.(wallet.FileStore); ok {
+		file.Backups = g_wallet_backups
+		store = file
+	}
+	g_store = store
+	return store, nil
+}
+
+// open_locked opens the store at path, explaining how to wait if another
+// process has it locked.
+func open_locked(path string) (wallet.Store, error) {
+	store, err := wallet.OpenStore(path, g_wallet_wait)
+	if errors.Is(err, wallet.ErrInUse) {
+		return nil, fmt.Errorf("%w; wait for it with -wallet-wait", err)
+	}
+	return store, err
+}
+
+// load_wallet reads the wallet, asking for its passphrase if it is encrypted.
+func load_wallet() (*wallet.Wallet, error) {
+	store, err := open_store()
+	if err != nil {
+		return nil, err
+	}
+	w, err := store.Load(wallet_passphrase)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no wallet at %s (create one with `gocash wallet create`)", store.Path())
+	}
+	return w, err
+}
+
+// load_spendable_wallet is load_wallet for the commands which spend from the
+// wallet or add to it, and so need its secrets.
+func load_spendable_wallet() (*wallet.Wallet, error) {
+	w, err := load_wallet()
+	if err == nil && w.WatchOnly() {
+		g_store.Close()
+		return nil, wallet.ErrWatchOnly
+	}
+	return w, err
+}
+
+// holds reports whether a wallet just loaded by load_wallet holds webcash,
+// by the store's index of it if there is one rather than searching the
+// wallet, which for a wallet of many thousands of secrets is slow.
+func holds(w *wallet.Wallet, sk webcash.SecretWebcash) (bool, error) {
+	if finder, ok := g_store.(wallet.Finder); ok {
+		_, found, err := finder.Lookup(webcash.FromSecret(sk).Hash)
+		return found, err
+	}
+	return w.Holds(sk.Secret), nil
+}
+
+// save_wallet writes a wallet loaded by load_wallet back to its store.
+func save_wallet(w *wallet.Wallet) error {
+	return g_store.Save(w)
+}
+
+// mining_secret derives the secret for the miner's share of a reward from the
+// wallet's mining chain, so that what is mined can be recovered from the
+// master secret.  It is the next secret of the chain, which is only taken
+// from it by claim_mining_secret once a solution is found: were each thread
+// and each run to take one, most would never be used, and recovery stops at
+// the first DefaultGapLimit unused in a row.
+func mining_secret() (string, error) {
+	g_wallet_mutex.Lock()
+	defer g_wallet_mutex.Unlock()
+	return g_wallet.DeriveSecret(wallet.Mining, g_wallet.WalletDepths[wallet.Mining.String()])
+}
+
+// claim_mining_secret takes the secret from mining_secret off the mining
+// chain once a solution claims it, and saves the wallet before the solution
+// is submitted, so that the secret is never mined for again.
+func claim_mining_secret(secret string) error {
+	g_wallet_mutex.Lock()
+	defer g_wallet_mutex.Unlock()
+	if _, err := g_wallet.NextSecret(wallet.Mining); err != nil {
+		return err
+	}
+	return save_wallet(g_wallet)
+}
+
+// deposit_mined adds mined webcash to the wallet, if there is one.
+func deposit_mined(sk webcash.SecretWebcash) error {
+	if g_wallet == nil {
+		return nil
+	}
+	g_wallet_mutex.Lock()
+	defer g_wallet_mutex.Unlock()
+	if g_wallet.Holds(sk.Secret) {
+		return nil
+	}
+	g_wallet.Webcash = append(g_wallet.Webcash, sk)
+	g_wallet.SetLabel(sk.Secret, "mining "+time.Now().Format("2006-01"))
+	if err := g_wallet.AddLog(wallet.LogEntry{Type: wallet.LogMined, Amount: sk.Amount, Delta: wallet.Delta(sk.Amount)}); err != nil {
+		return err
+	}
+	return save_wallet(g_wallet)
+}
+
+// wallet_replace replaces inputs from the wallet, or given to it, with outputs
+// from NewOutput, of which those in kept stay in the wallet and those in paid
+// leave it.  The wallet is saved before the request, with the outputs as
+// unconfirmed, and again afterwards with entry added to its log, along with
+// the labels of the inputs.  Once the server has made the replacement, no
+// error is returned, as the caller must still hand out what was paid: a
+// failure to log or save it is only warned of.
+func wallet_replace(w *wallet.Wallet, inputs, kept, paid []webcash.SecretWebcash, entry wallet.LogEntry) error {
+	if err := save_wallet(w); err != nil {
+		return err
+	}
+	entry.Labels = w.LabelsOf(inputs)
+	outputs := append(append([]webcash.SecretWebcash(nil), kept...), paid...)
+	if err := g_client.Replace(inputs, outputs); err != nil {
+		// If the server refused, the outputs will never exist.  Otherwise the
+		// replacement may have happened, and they are kept as unconfirmed.
+		if server_refused(err) {
+			w.Abandon(outputs)
+			if serr := save_wallet(w); serr != nil {
+				say("Error: %v", serr)
+			}
+		}
+		return err
+	}
+	w.Replaced(inputs, kept, paid)
+	if err := w.AddLog(entry); err != nil {
+		say("Warning: %v", err)
+	}
+	if err := save_wallet(w); err != nil {
+		warn_unsaved(err)
+	}
+	return nil
+}
+
+// warn_unsaved warns that a wallet could not be saved after a replacement.
+// The outputs are in the wallet saved before it, as unconfirmed, for check to
+// settle: it keeps those of the wallet, and leaves the payments to their
+// recipients.
+func warn_unsaved(err error) {
+	say("Warning: the replacement was made, but the wallet could not be saved: %v", err)
+	say("Run `gocash check` to bring the wallet up to date.")
+}
+
+// server_refused reports whether a replacement failed because the server
+// refused it, rather than for a reason which leaves it unknown whether it
+// happened.
+func server_refused(err error) bool {
+	var refused *client.ServerError
+	return errors.As(err, &refused) && refused.StatusCode < 500
+}
+
+// run_insert claims webcash given to the user, replacing it with a secret of
+// the wallet's so that the sender can no longer spend it.
+func run_insert(args []string) int {
+	flags := flag.NewFlagSet("insert", flag.ContinueOnError)
+	wallet_flag(flags)
+	label := flags.String("label", "", T("label for the webcash in the wallet, e.g. \"from Alice\" (default: the memo)"))
+	file := flags.String("file", "", T("insert every claim code in `file`, one per line, or \"-\" for stdin"))
+	parallel := flags.Int("parallel", 4, T("with -file, the number of claim codes to claim at a time"))
+	qr_image := flags.String("qr", "", T("read the claim code from the QR code in the `image`, a PNG, JPEG or GIF"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *file != "" {
+		if *parallel < 1 {
+			say("Error: -parallel must be at least 1")
+			return 2
+		}
+		return insert_claim_codes(*file, *parallel, strings.Join(flags.Args(), " "), *label)
+	}
+	code, rest := "", flags.Args()
+	if *qr_image != "" {
+		var err error
+		if code, err = read_qr(*qr_image); err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+	} else if len(rest) > 0 {
+		code, rest = rest[0], rest[1:]
+	} else {
+		say("Usage: gocash insert <claim code|webcash: link> [memo], or gocash insert -file <file> [memo]")
+		return 2
+	}
+	sk, memo, err := parse_claim(code)
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if len(rest) > 0 {
+		memo = strings.Join(rest, " ")
+	}
+
+	w, err := load_spendable_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	held, err := holds(w, sk)
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if held {
+		say("Error: that webcash is already in the wallet")
+		return 1
+	}
+	if *label == "" {
+		*label = memo
+	}
+	output, err := w.NewOutput(wallet.Receive, sk.Amount)
+	if err == nil {
+		w.SetLabel(output.Secret, *label)
+		err = wallet_replace(w, []webcash.SecretWebcash{sk}, []webcash.SecretWebcash{output}, nil, wallet.LogEntry{
+			Type:   wallet.LogInsert,
+			Amount: sk.Amount,
+			Delta:  wallet.Delta(sk.Amount),
+			Memo:   memo,
+		})
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	say_as(style.Success, "Inserted %v webcash, for a balance of %v", sk.Amount, w.Balance())
+	return 0
+}
+
+// make_payment pays amount out of the wallet into a new secret of the pay
+// chain, returning it, with inputs chosen by strategy and any change kept.
+func make_payment(w *wallet.Wallet, amount webcash.Amount, strategy wallet.Strategy, memo string) (webcash.SecretWebcash, error) {
+	inputs, err := w.Select(amount, strategy)
+	if err != nil {
+		return webcash.SecretWebcash{}, err
+	}
+	var total webcash.Amount
+	for _, sk := range inputs {
+		total += sk.Amount
+	}
+	payment, err := w.NewOutput(wallet.Pay, amount)
+	var kept []webcash.SecretWebcash
+	if err == nil && total > amount {
+		var change webcash.SecretWebcash
+		change, err = w.NewOutput(wallet.Change, total-amount)
+		kept = append(kept, change)
+	}
+	if err == nil {
+		err = wallet_replace(w, inputs, kept, []webcash.SecretWebcash{payment}, wallet.LogEntry{
+			Type:   wallet.LogPayment,
+			Amount: amount,
+			Delta:  -wallet.Delta(amount),
+			Memo:   memo,
+		})
+	}
+	return payment, err
+}
+
+// run_pay pays webcash out of the wallet, printing the claim code to give the
+// recipient.  Any change goes back to the wallet.
+func run_pay(args []string) int {
+	flags := flag.NewFlagSet("pay", flag.ContinueOnError)
+	wallet_flag(flags)
+	strategy_name := flags.String("coin-selection", config_setting("coin-selection", wallet.DefaultStrategy), T("how to choose the webcash to pay with: \"fewest-inputs\", \"minimize-change\", \"oldest-first\", or \"privacy\" to avoid linking mined webcash"))
+	show_qr := flags.Bool("qr", false, T("also show the claim code as a QR code, for a phone to scan"))
+	qr_png := flags.String("qr-png", "", T("also write the claim code as a QR code to a PNG image at `path`"))
+	as_uri := flags.Bool("uri", false, T("give the payment as a webcash: link, with the memo, rather than a bare claim code"))
+	expires := flags.String("expires", "", T("with -uri, how long the link is good for, e.g. \"24h\" (default: forever)"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	strategy, err := wallet.ParseStrategy(*strategy_name)
+	if err != nil {
+		say("Error: %v", err)
+		return 2
+	}
+	expiry, err := parse_expiry(*expires)
+	if err != nil {
+		say("Error: %v", err)
+		return 2
+	}
+	if flags.NArg() < 1 {
+		say("Usage: gocash pay <amount|webcash: request> [memo]")
+		return 2
+	}
+	var amount webcash.Amount
+	var memo string
+	if webcash.IsURI(flags.Arg(0)) {
+		amount, memo, err = parse_request(flags.Arg(0))
+	} else {
+		amount, err = webcash.ParseAmount(strings.TrimPrefix(flags.Arg(0), "e"))
+		if err == nil && amount == 0 {
+			err = fmt.Errorf("%w: amount must be positive", webcash.ErrInvalidAmount)
+		}
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if flags.NArg() > 1 {
+		memo = strings.Join(flags.Args()[1:], " ")
+	}
+
+	w, err := load_spendable_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	payment, err := make_payment(w, amount, strategy, memo)
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	shown := payment.String()
+	if *as_uri {
+		shown = webcash.NewPaymentURI(payment, memo, expiry).String()
+		say_as(style.Success, "Paid %v webcash, leaving a balance of %v.  Give the recipient this link:", amount, w.Balance())
+		fmt.Println(shown)
+	} else {
+		say_as(style.Success, "Paid %v webcash, leaving a balance of %v.  Give the recipient this claim code:", amount, w.Balance())
+		print_secret(payment, "")
+	}
+	// The payment is made whatever becomes of the QR code, so a failure to
+	// show one does not fail the command, lest a script pay again.
+	if *show_qr {
+		if err := print_qr(shown); err != nil {
+			say("Error: %v", err)
+		}
+	}
+	if *qr_png != "" {
+		if err := write_qr(*qr_png, shown); err != nil {
+			say("Error: %v", err)
+		} else {
+			say("Wrote the claim code as a QR code to %s", *qr_png)
+		}
+	}
+	return 0
+}
+
+// run_merge consolidates the wallet's many small secrets, from mining and
+// change, into few large ones, a group at a time, so that the wallet and the
+// health checks of it stay small.
+func run_merge(args []string) int {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	wallet_flag(flags)
+	group := flags.Int("group", 20, T("most secrets to merge in one request to the server"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *group < 2 {
+		say("Error: -group must be at least 2")
+		return 2
+	}
+	w, err := load_spendable_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	before := len(w.Webcash)
+	for len(w.Webcash) > 1 {
+		// Take the smallest first, so that the outputs of earlier merges,
+		// being larger, are only merged again at the end.
+		sorted := append([]webcash.SecretWebcash(nil), w.Webcash...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Amount < sorted[j].Amount })
+		if len(sorted) > *group {
+			sorted = sorted[:*group]
+		}
+		var total webcash.Amount
+		for _, sk := range sorted {
+			total += sk.Amount
+		}
+		output, err := w.NewOutput(wallet.Change, total)
+		if err == nil {
+			err = wallet_replace(w, sorted, []webcash.SecretWebcash{output}, nil, wallet.LogEntry{
+				Type:   wallet.LogMerge,
+				Amount: total,
+			})
+		}
+		if err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+		say("Merged %d secrets worth %v", len(sorted), total)
+	}
+	say_as(style.Success, "The wallet holds %v webcash in %d secrets, from %d before", w.Balance(), len(w.Webcash), before)
+	return 0
+}
+
+// wallet_passphrase returns the passphrase of an encrypted wallet: the value of
+// GOCASH_PASSPHRASE, for running unattended, or else what the user types.
+func wallet_passphrase() ([]byte, error) {
+	if passphrase, ok := os.LookupEnv("GOCASH_PASSPHRASE"); ok {
+		return []byte(passphrase), nil
+	}
+	return read_passphrase(T("Wallet passphrase: "))
+}
+
+// read_line reads a line from stdin, without its line ending.
+func read_line() ([]byte, error) {
+	line, err := g_stdin.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// new_passphrase asks for a new passphrase, twice to be sure of it.  The
+// caller wipes it once it is used.
+func new_passphrase() ([]byte, error) {
+	passphrase, err := read_passphrase(T("New passphrase: "))
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("the passphrase is empty")
+	}
+	again, err := read_passphrase(T("Repeat the passphrase: "))
+	defer webcash.Wipe(again)
+	if err != nil {
+		webcash.Wipe(passphrase)
+		return nil, err
+	}
+	if !bytes.Equal(again, passphrase) {
+		webcash.Wipe(passphrase)
+		return nil, errors.New("the passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// print_secret writes a claim code to stdout, followed by rest, from a buffer
+// which is wiped afterwards rather than left to the garbage collector.
+func print_secret(sk webcash.SecretWebcash, rest string) {
+	line := append(sk.Bytes(), rest...)
+	line = append(line, '\n')
+	os.Stdout.Write(line)
+	webcash.Wipe(line)
+}
+
+// run_wallet creates a wallet, or describes the one there is.  The file is
+// that of the Python reference client, so either client can use it, unless
+// the wallet is kept in a SQLite database instead.
+func run_wallet(args []string) int {
+	if len(args) == 0 {
+		args = []string{"info"}
+	}
+	switch args[0] {
+	case "backup":
+		return run_wallet_backup(args[1:])
+	case "convert":
+		return run_wallet_convert(args[1:])
+	case "create":
+		return run_wallet_create(args[1:])
+	case "info":
+		return run_wallet_info(args[1:])
+	case "keychain":
+		return run_wallet_keychain(args[1:])
+	case "list":
+		return run_wallet_list(args[1:])
+	case "passphrase":
+		return run_wallet_passphrase(args[1:])
+	case "restore":
+		return run_wallet_restore(args[1:])
+	}
+	say("Usage: gocash wallet [backup|convert|create|info|keychain|list|passphrase|restore]")
+	return 2
+}
+
+func run_wallet_create(args []string) int {
+	flags := flag.NewFlagSet("wallet create", flag.ContinueOnError)
+	wallet_flag(flags)
+	encrypt := flags.Bool("encrypt", false, T("encrypt the wallet with a passphrase"))
+	sqlite := flags.Bool("sqlite", false, T("keep the wallet in a SQLite database, for wallets of many thousands of secrets"))
+	watch := flags.Bool("watch", false, T("create a watch-only wallet, which holds no secrets"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	for _, path := range []string{g_paths.WalletFile(), g_paths.WalletDB()} {
+		if _, err := os.Stat(path); err == nil {
+			say("Error: %s already exists", path)
+			return 1
+		}
+	}
+	if *encrypt && *sqlite {
+		say("Error: -encrypt and -sqlite cannot be used together")
+		return 2
+	}
+	if err := g_paths.Create(); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	path := g_paths.WalletFile()
+	if *sqlite {
+		path = g_paths.WalletDB()
+	}
+	store, err := open_locked(path)
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer store.Close()
+	w, err := wallet.New()
+	if *watch {
+		w = wallet.NewWatchOnly()
+	}
+	if err == nil && *encrypt {
+		var passphrase []byte
+		if passphrase, err = new_passphrase(); err == nil {
+			err = w.SetPassphrase(passphrase)
+			webcash.Wipe(passphrase)
+		}
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if err := store.Save(w); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if *watch {
+		say_as(style.Success, "Created a watch-only wallet at %s", path)
+		say("Add public webcash to it with `gocash watch add`.")
+		return 0
+	}
+	say_as(style.Success, "Created a wallet at %s", path)
+	say("Back up this file: whoever has it can spend its webcash.")
+	return 0
+}
+
+// run_wallet_passphrase sets, changes or removes the wallet's passphrase.  The
+// previous file is kept as a backup, encrypted, until the next change.
+func run_wallet_passphrase(args []string) int {
+	flags := flag.NewFlagSet("wallet passphrase", flag.ContinueOnError)
+	wallet_flag(flags)
+	remove := flags.Bool("remove", false, T("remove the passphrase, leaving the wallet unencrypted"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	file, ok := g_store.(wallet.FileStore)
+	if !ok {
+		say("Error: only wallet files can be encrypted, not %s", g_store.Path())
+		return 1
+	}
+	path := file.Path()
+	backup := path + ".bak"
+	was_encrypted := w.Encrypted()
+	if *remove && !was_encrypted {
+		say("The wallet has no passphrase.")
+		return 0
+	}
+	var passphrase []byte
+	if !*remove {
+		if passphrase, err = new_passphrase(); err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+	}
+	err = w.SetPassphrase(passphrase)
+	webcash.Wipe(passphrase)
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+
+	// Keep the previous file as it was if it is encrypted, and otherwise
+	// an encrypted copy, so that the backup never holds plaintext secrets.
+	if was_encrypted {
+		err = copy_private(path, backup)
+	} else {
+		err = w.Save(backup)
+	}
+	if err != nil {
+		say("Error: unable to back up the wallet, so it is unchanged: %v", err)
+		return 1
+	}
+	// The old file is not kept as a previous version, which would hold the
+	// secrets unencrypted if the wallet was, and any previous versions are
+	// removed for the same reason.
+	if err := w.Save(path); err != nil {
+		say("Error: %v", err)
+		say("The previous wallet is kept as %s", backup)
+		return 1
+	}
+	if !was_encrypted {
+		for _, version := range file.Versions() {
+			if err := shred(version); err != nil {
+				say("Error: %v", err)
+			}
+		}
+	}
+	if *remove {
+		say_as(style.Success, "Removed the passphrase of %s", path)
+		say("Warning: its secrets are now stored unencrypted.")
+	} else {
+		say_as(style.Success, "Set the passphrase of %s", path)
+	}
+	say("The previous wallet is kept as %s", backup)
+	return 0
+}
+
+// run_wallet_keychain moves the wallet's master secret into the platform's
+// keychain, or back into the wallet.
+func run_wallet_keychain(args []string) int {
+	flags := flag.NewFlagSet("wallet keychain", flag.ContinueOnError)
+	wallet_flag(flags)
+	remove := flags.Bool("remove", false, T("keep the master secret in the wallet again, and remove it from the keychain"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	path := g_store.Path()
+
+	if *remove {
+		if w.Keychain == "" {
+			say("The master secret of %s is not in the keychain.", path)
+			return 0
+		}
+		account := w.MoveFromKeychain()
+		if err := g_store.Save(w); err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+		if err := wallet.RemoveKeychain(account); err != nil {
+			say("Error: unable to remove the master secret from the keychain: %v", err)
+			return 1
+		}
+		say_as(style.Success, "Moved the master secret of %s back from the keychain", path)
+		return 0
+	}
+
+	if w.Keychain != "" {
+		say("The master secret of %s is already in the keychain.", path)
+		return 0
+	}
+	if err := w.MoveToKeychain(); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if err := g_store.Save(w); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	// The previous versions still hold the master secret.
+	if file, ok := g_store.(wallet.FileStore); ok {
+		for _, version := range file.Versions() {
+			if err := shred(version); err != nil {
+				say("Error: %v", err)
+			}
+		}
+	}
+	say_as(style.Success, "Moved the master secret of %s into the keychain", path)
+	say("The wallet now needs the keychain to derive its secrets; `gocash wallet backup` makes a copy which does not.")
+	return 0
+}
+
+// run_wallet_backup writes an encrypted copy of the wallet, for offline
+// storage, under a passphrase of its own.
+func run_wallet_backup(args []string) int {
+	flags := flag.NewFlagSet("wallet backup", flag.ContinueOnError)
+	wallet_flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		say("Usage: gocash wallet backup <file>")
+		return 2
+	}
+	path := flags.Arg(0)
+	if _, err := os.Stat(path); err == nil {
+		say("Error: %s already exists", path)
+		return 1
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	say("Choose a passphrase for the backup.  Without it, the backup cannot be restored.")
+	passphrase, err := new_passphrase()
+	if err == nil {
+		err = w.Backup(path, passphrase)
+		webcash.Wipe(passphrase)
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	say_as(style.Success, "Backed up %v webcash in %d secrets to %s", w.Balance(), len(w.Webcash), path)
+	say("Restore it with `gocash wallet restore %s`.", path)
+	return 0
+}
+
+// run_wallet_restore restores a backup as the selected wallet, which must not
+// exist yet.  A wallet file stays encrypted with the backup's passphrase.
+func run_wallet_restore(args []string) int {
+	flags := flag.NewFlagSet("wallet restore", flag.ContinueOnError)
+	wallet_flag(flags)
+	sqlite := flags.Bool("sqlite", false, T("keep the wallet in a SQLite database, for wallets of many thousands of secrets"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		say("Usage: gocash wallet restore <file>")
+		return 2
+	}
+	for _, path := range []string{g_paths.WalletFile(), g_paths.WalletDB()} {
+		if _, err := os.Stat(path); err == nil {
+			say("Error: %s already exists; restore as another wallet with -wallet", path)
+			return 1
+		}
+	}
+	w, err := wallet.Load(flags.Arg(0), func() ([]byte, error) {
+		return read_passphrase(T("Backup passphrase: "))
+	})
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	path := g_paths.WalletFile()
+	if *sqlite {
+		path = g_paths.WalletDB()
+		w.SetPassphrase(nil)
+	}
+	if err := g_paths.Create(); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if err := convert_wallet(w, path); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	say_as(style.Success, "Restored %v webcash in %d secrets to %s", w.Balance(), len(w.Webcash), path)
+	if w.Encrypted() {
+		say("It is encrypted with the backup's passphrase; change it with `gocash wallet passphrase`.")
+	}
+	return 0
+}
+
+// run_wallet_convert moves the wallet from its file to a SQLite database, or
+// back.  What it was moved from is kept with ".old" appended to its name, for
+// the user to delete once they are satisfied.
+func run_wallet_convert(args []string) int {
+	flags := flag.NewFlagSet("wallet convert", flag.ContinueOnError)
+	wallet_flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	from := g_store.Path()
+	to := g_paths.WalletFile()
+	if _, ok := g_store.(wallet.FileStore); ok {
+		to = g_paths.WalletDB()
+	}
+	if err := convert_wallet(w, to); err != nil {
+		g_store.Close()
+		say("Error: %v", err)
+		return 1
+	}
+	if err := g_store.Close(); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if err := os.Rename(from, from+".old"); err != nil {
+		say("Error: %v", err)
+		say("Remove %s, or gocash will keep using it.", from)
+		return 1
+	}
+	say_as(style.Success, "Moved the wallet to %s", to)
+	say("The previous wallet is kept as %s", from+".old")
+	return 0
+}
+
+// convert_wallet writes w to a new store at path, which must not exist.
+func convert_wallet(w *wallet.Wallet, path string) error {
+	if w.Encrypted() && strings.HasSuffix(path, ".db") {
+		return errors.New("a SQLite wallet cannot be encrypted; remove the passphrase first")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	store, err := open_locked(path)
+	if err != nil {
+		return err
+	}
+	err = store.Save(w)
+	if cerr := store.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// copy_private copies a file to dst, readable only by the user, syncing it to
+// disk before returning.
+func copy_private(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func run_wallet_info(args []string) int {
+	flags := flag.NewFlagSet("wallet info", flag.ContinueOnError)
+	wallet_flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	say("Wallet %s, version %s, schema %d", g_store.Path(), w.Version, w.Schema)
+	if w.Encrypted() {
+		say("Encrypted with a passphrase")
+	}
+	if w.Keychain != "" {
+		say("Master secret kept in the keychain")
+	}
+	if len(w.Watched) > 0 || w.WatchOnly() {
+		say("Watching %d public webcash", len(w.Watched))
+	}
+	say("Balance: %v webcash in %d secrets", w.Balance(), len(w.Webcash))
+	if len(w.Unconfirmed) > 0 {
+		say("Unconfirmed: %d secrets", len(w.Unconfirmed))
+	}
+	if len(w.Archived) > 0 {
+		say("Archived: %d spent secrets", len(w.Archived))
+	}
+	say("Log: %d entries", len(w.Log))
+	chains := make([]string, 0, len(w.WalletDepths))
+	for chain := range w.WalletDepths {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	for _, chain := range chains {
+		fmt.Printf("  %-8s %d\n", chain, w.WalletDepths[chain])
+	}
+	return 0
+}
+
+// run_wallet_list lists the user's wallets, marking the one selected.
+func run_wallet_list(args []string) int {
+	flags := flag.NewFlagSet("wallet list", flag.ContinueOnError)
+	wallet_flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	files := make(map[string]string)
+	for _, suffix := range []string{"_wallet.webcash", "_wallet.db"} {
+		matches, err := filepath.Glob(filepath.Join(g_paths.Wallet, "*"+suffix))
+		if err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+		for _, path := range matches {
+			name := strings.TrimSuffix(filepath.Base(path), suffix)
+			if _, ok := files[name]; !ok && paths.CheckWalletName(name) == nil {
+				files[name] = path
+			}
+		}
+	}
+	if len(files) == 0 {
+		say("No wallets in %s (create one with `gocash wallet create`)", g_paths.Wallet)
+		return 0
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mark := " "
+		if files[name] == g_paths.WalletFile() || files[name] == g_paths.WalletDB() {
+			mark = "*"
+		}
+		fmt.Printf("%s %-16s %s\n", mark, name, files[name])
+	}
+	return 0
+}
+
+// run_balance prints the wallet's balance, and with -verify, checks every
+// secret with the server, reporting those spent or not worth what the wallet
+// says.
+func run_balance(args []string) int {
+	flags := flag.NewFlagSet("balance", flag.ContinueOnError)
+	wallet_flag(flags)
+	verify := flags.Bool("verify", false, T("check every secret with the server"))
+	batch := flags.Int("batch", wallet.DefaultBatchSize, T("number of secrets checked per request to the server"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	say("Balance: %v webcash in %d secrets", w.Balance(), len(w.Webcash))
+	if len(w.Unconfirmed) > 0 {
+		say("Unconfirmed: %d secrets", len(w.Unconfirmed))
+	}
+	if !*verify {
+		return 0
+	}
+
+	say("Verifying %d secrets with the server...", len(w.Webcash))
+	discrepancies, err := w.Verify(g_client, *batch)
+	var missing webcash.Amount
+	for _, d := range discrepancies {
+		missing += d.Held.Amount
+		pk := webcash.FromSecret(d.Held)
+		switch {
+		case d.Status.Spent == nil:
+			say("  %v: unknown to the server", pk)
+		case *d.Status.Spent:
+			say("  %v: already spent", pk)
+		case d.Status.Amount != nil:
+			say("  %v: worth %v on the server", pk, *d.Status.Amount)
+		default:
+			say("  %v: of no amount on the server", pk)
+		}
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if len(discrepancies) > 0 {
+		say_as(style.Warning, "%d secrets, holding %v webcash, are not as the wallet says", len(discrepancies), missing)
+		return 1
+	}
+	say_as(style.Success, "Verified: every secret is unspent and worth what the wallet says")
+	return 0
+}
+
+// run_watch tracks whether public webcash is spent, for a watch-only wallet:
+// it adds public webcash to the wallet, checks what it watches with the
+// server, or exports a watch-only copy of a wallet which holds secrets.
+func run_watch(args []string) int {
+	if len(args) == 0 {
+		args = []string{"check"}
+	}
+	switch args[0] {
+	case "add":
+		return run_watch_add(args[1:])
+	case "check":
+		return run_watch_check(args[1:])
+	case "export":
+		return run_watch_export(args[1:])
+	}
+	say("Usage: gocash watch [add|check|export]")
+	return 2
+}
+
+func run_watch_add(args []string) int {
+	flags := flag.NewFlagSet("watch add", flag.ContinueOnError)
+	wallet_flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		say("Usage: gocash watch add <public webcash>...")
+		return 2
+	}
+	pks := make([]webcash.PublicWebcash, 0, flags.NArg())
+	for _, code := range flags.Args() {
+		pk, err := webcash.ParsePublicWebcash(code)
+		if err != nil {
+			say("Error: %v", err)
+			return 1
+		}
+		pks = append(pks, pk)
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	added := w.Watch(pks...)
+	if err := save_wallet(w); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	say_as(style.Success, "Watching %d more, %d in all", added, len(w.Watched))
+	return 0
+}
+
+func run_watch_check(args []string) int {
+	flags := flag.NewFlagSet("watch check", flag.ContinueOnError)
+	wallet_flag(flags)
+	batch := flags.Int("batch", wallet.DefaultBatchSize, T("number of secrets checked per request to the server"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	if len(w.Watched) == 0 {
+		say("The wallet watches nothing.")
+		return 0
+	}
+	statuses, err := w.CheckWatched(g_client, *batch)
+	unspent := 0
+	var total webcash.Amount
+	for i, s := range statuses {
+		pk := w.Watched[i]
+		switch {
+		case s.Spent == nil:
+			say("  %v: unknown to the server", pk)
+		case *s.Spent:
+			say("  %v: spent", pk)
+		case s.Amount != nil && *s.Amount != pk.Amount:
+			say("  %v: worth %v on the server", pk, *s.Amount)
+		default:
+			say("  %v: unspent", pk)
+			unspent++
+			total += pk.Amount
+		}
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	say("%d of %d watched are unspent, holding %v webcash", unspent, len(w.Watched), total)
+	return 0
+}
+
+func run_watch_export(args []string) int {
+	flags := flag.NewFlagSet("watch export", flag.ContinueOnError)
+	wallet_flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		say("Usage: gocash watch export <file>")
+		return 2
+	}
+	path := flags.Arg(0)
+	if _, err := os.Stat(path); err == nil {
+		say("Error: %s already exists", path)
+		return 1
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	watch := w.WatchOnlyCopy()
+	if err := watch.Save(path); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	say_as(style.Success, "Wrote a watch-only wallet of %d public webcash to %s", len(watch.Watched), path)
+	say("It holds no secrets; use it as the wallet file of the box which watches.")
+	return 0
+}
+
+// run_list lists the secrets in the wallet with their amounts and labels,
+// optionally only those whose label contains the given text.
+func run_list(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	wallet_flag(flags)
+	label := flags.String("label", "", T("only list secrets whose label contains this text, ignoring case"))
+	secrets := flags.Bool("secrets", false, T("show the claim codes of the secrets, rather than their public hashes"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	want := strings.ToLower(*label)
+	count := 0
+	var total webcash.Amount
+	for _, sk := range w.Webcash {
+		if !strings.Contains(strings.ToLower(w.Label(sk.Secret)), want) {
+			continue
+		}
+		count++
+		total += sk.Amount
+		var rest string
+		if label := w.Label(sk.Secret); label != "" {
+			rest = "  " + label
+		}
+		if *secrets {
+			print_secret(sk, rest)
+		} else {
+			fmt.Println(webcash.FromSecret(sk).String() + rest)
+		}
+	}
+	say("%d secrets, holding %v webcash", count, total)
+	return 0
+}
+
+// run_label sets the label of a secret in the wallet, given by a prefix of its
+// public hash as shown by `gocash list`.  Without a label, the label is
+// removed.
+func run_label(args []string) int {
+	flags := flag.NewFlagSet("label", flag.ContinueOnError)
+	wallet_flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 1 {
+		say("Usage: gocash label <public hash> [label]")
+		return 2
+	}
+	prefix := strings.ToLower(flags.Arg(0))
+	if i := strings.LastIndex(prefix, ":"); i >= 0 {
+		prefix = prefix[i+1:]
+	}
+	label := strings.Join(flags.Args()[1:], " ")
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	var matches []webcash.SecretWebcash
+	for _, sk := range w.Webcash {
+		hash := webcash.FromSecret(sk).Hash
+		if prefix != "" && strings.HasPrefix(hex.EncodeToString(hash[:]), prefix) {
+			matches = append(matches, sk)
+		}
+	}
+	if len(matches) != 1 {
+		say("Error: %d secrets in the wallet match %q", len(matches), flags.Arg(0))
+		return 1
+	}
+	w.SetLabel(matches[0].Secret, label)
+	if err := save_wallet(w); err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if label == "" {
+		say_as(style.Success, "Removed the label of %v", webcash.FromSecret(matches[0]))
+	} else {
+		say_as(style.Success, "Labelled %v %q", webcash.FromSecret(matches[0]), label)
+	}
+	return 0
+}
+
+// run_history prints the wallet's log, oldest first: each transaction's time,
+// type, change to the balance, resulting balance and memo.
+func run_history(args []string) int {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	wallet_flag(flags)
+	since := flags.String("since", "", T("only show transactions after this time, RFC 3339 or a duration before now such as \"24h\""))
+	last := flags.Int("n", 0, T("only show the last n transactions"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	start, err := parse_time_bound(*since, time.Now())
+	if err != nil {
+		say("Error: %v", err)
+		return 2
+	}
+	w, err := load_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+	var entries []wallet.LogEntry
+	for _, entry := range w.History() {
+		if !entry.Timestamp.Before(start) {
+			entries = append(entries, entry)
+		}
+	}
+	if *last > 0 && len(entries) > *last {
+		entries = entries[len(entries)-*last:]
+	}
+	if len(entries) == 0 {
+		say("No transactions.")
+		return 0
+	}
+	for _, entry := range entries {
+		when := "-"
+		if !entry.Timestamp.IsZero() {
+			when = entry.Timestamp.Local().Format("2006-01-02 15:04")
+		}
+		note := entry.Memo
+		if len(entry.Labels) > 0 {
+			note = strings.TrimSpace(note + " [" + strings.Join(entry.Labels, ", ") + "]")
+		}
+		line := fmt.Sprintf("%-16s  %-8s %16v %16v  %s", when, entry.Type, entry.Delta, entry.Balance, note)
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+	return 0
+}
+
+// run_check checks the wallet against the server: spent secrets are archived,
+// unconfirmed outputs which exist are taken in, mismatched amounts are
+// reported, and the chains' depths are moved past any secrets in use.
+func run_check(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	wallet_flag(flags)
+	gap := flags.Int("gap", wallet.DefaultGapLimit, T("number of unused secrets in a row after which a chain is taken to end"))
+	batch := flags.Int("batch", wallet.DefaultBatchSize, T("number of secrets checked per request to the server"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	w, err := load_spendable_wallet()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer g_store.Close()
+
+	say("Checking %d secrets with the server...", len(w.Webcash)+len(w.Unconfirmed))
+	before := w.Balance()
+	result, err := w.Check(g_client, *gap, *batch)
+	for _, sk := range result.Archived {
+		say("  %v: spent, archived", webcash.FromSecret(sk))
+	}
+	for _, sk := range result.Confirmed {
+		say("  %v: unconfirmed output found on the server, now held", webcash.FromSecret(sk))
+	}
+	for _, sk := range result.Paid {
+		say("  %v: payment found on the server, left to its recipient", webcash.FromSecret(sk))
+	}
+	if len(result.Paid) > 0 {
+		say("If a payment was never handed out, `gocash recover -sweep-payments` takes it back.")
+	}
+	for _, d := range result.Mismatched {
+		pk := webcash.FromSecret(d.Held)
+		if d.Status.Spent == nil || d.Status.Amount == nil {
+			say("  %v: unknown to the server", pk)
+		} else {
+			say("  %v: worth %v on the server", pk, *d.Status.Amount)
+		}
+	}
+	for _, r := range result.Repaired {
+		if !r.Taken {
+			say("  %s: depth advanced to %d", r.Chain, r.Depth)
+		} else {
+			say("  %s: depth advanced to %d, %d unspent found", r.Chain, r.Depth, len(r.Found))
+		}
+	}
+	after := w.Balance()
+	if after != before {
+		delta := wallet.Delta(after) - wallet.Delta(before)
+		amount := webcash.Amount(delta)
+		if delta < 0 {
+			amount = webcash.Amount(-delta)
+		}
+		if lerr := w.AddLog(wallet.LogEntry{Type: wallet.LogCheck, Amount: amount, Delta: delta}); lerr != nil {
+			say("Error: %v", lerr)
+			return 1
+		}
+	}
+	if serr := save_wallet(w); serr != nil {
+		say("Error: %v", serr)
+		return 1
+	}
+	if err != nil {
+		say("Error: the check stopped early: %v", err)
+		return 1
+	}
+	if len(result.Mismatched) > 0 {
+		say_as(style.Warning, "Checked %d secrets: %d do not match the server, for a balance of %v", result.Checked, len(result.Mismatched), after)
+		return 1
+	}
+	say_as(style.Success, "Checked %d secrets, for a balance of %v", result.Checked, after)
+	return 0
+}
+
+// run_recover rebuilds a wallet from its master secret, finding what the server
+// holds for the secrets derived from it.  Without a wallet, one is created
+// around the given master secret.
+func run_recover(args []string) int {
+	flags := flag.NewFlagSet("recover", flag.ContinueOnError)
+	wallet_flag(flags)
+	gap := flags.Int("gap", wallet.DefaultGapLimit, T("number of unused secrets in a row after which a chain is taken to end"))
+	batch := flags.Int("batch", wallet.DefaultBatchSize, T("number of secrets checked per request to the server"))
+	sweep := flags.Bool("sweep-payments", false, T("also take back payments which their recipients have not claimed yet"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		say("Usage: gocash recover [flags] [master secret]")
+		return 2
+	}
+
+	store, err := open_store()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	defer store.Close()
+	path := store.Path()
+	w, err := store.Load(wallet_passphrase)
+	if errors.Is(err, os.ErrNotExist) {
+		master := flags.Arg(0)
+		if master == "" {
+			master = ask("Master secret: ")
+		}
+		if w, err = wallet.New(); err == nil {
+			w.MasterSecret = master
+			_, err = w.DeriveSecret(wallet.Receive, 0)
+		}
+		if err == nil {
+			err = g_paths.Create()
+		}
+	} else if err == nil && flags.NArg() == 1 && flags.Arg(0) != w.MasterSecret {
+		err = fmt.Errorf("%s already has a different master secret", path)
+	}
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+
+	say("Scanning for webcash, stopping each chain after %d unused secrets...", *gap)
+	before := w.Balance()
+	results, err := w.Recover(g_client, *gap, *batch, *sweep)
+	var found, unclaimed int
+	var total webcash.Amount
+	for _, result := range results {
+		fmt.Printf("  %-8s %s\n", result.Chain, i18n.Sprintf("%d secrets used, %d unspent found, depth %d", result.Used, len(result.Found), result.Depth))
+		if !result.Taken {
+			unclaimed += len(result.Found)
+			continue
+		}
+		for _, sk := range result.Found {
+			total += sk.Amount
+		}
+		found += len(result.Found)
+	}
+	if unclaimed > 0 {
+		say("%d payments have not been claimed by their recipients, and were left to them; use -sweep-payments to take them back.", unclaimed)
+	}
+	if found > 0 {
+		if lerr := w.AddLog(wallet.LogEntry{Type: wallet.LogRecover, Amount: total, Delta: wallet.Delta(w.Balance()) - wallet.Delta(before)}); lerr != nil {
+			say("Error: %v", lerr)
+			return 1
+		}
+	}
+	if serr := store.Save(w); serr != nil {
+		say("Error: %v", serr)
+		return 1
+	}
+	if err != nil {
+		say("Error: recovery stopped early: %v", err)
+		say("Saved what was found so far to %s; run recover again to continue.", path)
+		return 1
+	}
+	say_as(style.Success, "Recovered %v webcash in %d secrets, for a balance of %v", total, found, w.Balance())
+	return 0
+}
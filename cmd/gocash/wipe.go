@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/maaku/gocash/internal/style"
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+// read_mining_log returns the claim codes recorded in the log of mined
+// webcash, and the public webcash of everything recorded, including what was
+// mined into a wallet, for which only the public webcash is logged.  Lines
+// which do not parse are skipped, with a warning.
+func read_mining_log(path string) ([]webcash.SecretWebcash, []webcash.PublicWebcash, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var secrets []webcash.SecretWebcash
+	var recorded []webcash.PublicWebcash
+	scanner := bufio.NewScanner(f)
+	for line_num := 1; scanner.Scan(); line_num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if pk, err := webcash.ParsePublicWebcash(line); err == nil {
+			recorded = append(recorded, pk)
+			continue
+		}
+		sk, err := webcash.ParseSecretWebcash(line)
+		if err != nil {
+			say("Warning: %s:%d: %v", path, line_num, err)
+			continue
+		}
+		secrets = append(secrets, sk)
+		recorded = append(recorded, webcash.FromSecret(sk))
+	}
+	return secrets, recorded, scanner.Err()
+}
+
+// sweep replaces all the unspent webcash among secrets with a single output
+// under the given secret, returning the amount swept.
+func sweep(secrets []webcash.SecretWebcash, to string) (webcash.Amount, error) {
+	if len(secrets) == 0 {
+		return 0, nil
+	}
+	pks := make([]webcash.PublicWebcash, len(secrets))
+	for i, sk := range secrets {
+		pks[i] = webcash.FromSecret(sk)
+	}
+	status, err := g_client.HealthCheck(pks)
+	if err != nil {
+		return 0, err
+	}
+	var inputs []webcash.SecretWebcash
+	var total webcash.Amount
+	for i, sk := range secrets {
+		if s, ok := status[pks[i].Hash]; ok && s.Spent != nil && !*s.Spent {
+			inputs = append(inputs, sk)
+			total += sk.Amount
+		}
+	}
+	if len(inputs) == 0 {
+		return 0, nil
+	}
+	output := webcash.SecretWebcash{Secret: to, Amount: total}
+	if err := g_client.Replace(inputs, []webcash.SecretWebcash{output}); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// shred overwrites a file with random data and syncs it to disk before
+// removing it, so that its contents are not left behind in the freed blocks.
+// On journaling or copy-on-write filesystems, and on SSDs, old copies may
+// survive regardless; this is a best effort.
+func shred(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err == nil {
+		_, err = io.CopyN(f, rand.Reader, info.Size())
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to overwrite %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
+
+// wipe_targets returns the files a wipe deletes, those of them which exist:
+// wallets and their backups, and the logs.  Only files gocash itself names
+// are included, since with GOCASH_HOME the directories may be shared with
+// anything.  Settings are kept, since they hold no secrets.
+func wipe_targets() ([]string, error) {
+	candidates := []string{g_paths.MiningLog(), g_paths.PendingLog(), g_paths.OrphanLog(), g_paths.StatsFile(), g_paths.EventsFile()}
+	for _, pattern := range []string{"*.webcash*", "*_wallet.db*"} {
+		wallets, err := filepath.Glob(filepath.Join(g_paths.Wallet, pattern))
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, wallets...)
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, path := range candidates {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// wipe_wallet reads the selected wallet, so that a wipe can warn of its
+// balance and sweep it, and closes its store again, so that its files can be
+// shredded.  It returns nil if there is no wallet, or if it cannot be read, in
+// which case a warning is given.
+func wipe_wallet() (*wallet.Wallet, wallet.Store) {
+	store, err := open_store()
+	if err != nil {
+		say("Warning: unable to read the wallet, so its balance is neither counted nor swept: %v", err)
+		return nil, nil
+	}
+	defer func() {
+		store.Close()
+		g_store = nil
+	}()
+	w, err := store.Load(wallet_passphrase)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, store
+	}
+	if err != nil {
+		say("Warning: unable to read %s, so its balance is neither counted nor swept: %v", store.Path(), err)
+		return nil, store
+	}
+	return w, store
+}
+
+// other_wallets returns those of files which may be wallets other than the
+// one kept in store, which a wipe neither counts nor sweeps: the files named
+// like wallets which are not the store's, its previous versions, or lock
+// files.
+func other_wallets(files []string, store wallet.Store) []string {
+	logs := map[string]bool{g_paths.MiningLog(): true, g_paths.PendingLog(): true, g_paths.OrphanLog(): true, g_paths.StatsFile(): true, g_paths.EventsFile(): true}
+	var others []string
+	for _, path := range files {
+		if logs[path] || strings.HasSuffix(path, ".lock") {
+			continue
+		}
+		if store != nil && (path == store.Path() || strings.HasPrefix(path, store.Path()+".") || strings.HasPrefix(path, store.Path()+"-")) {
+			continue
+		}
+		others = append(others, path)
+	}
+	return others
+}
+
+func run_wipe(args []string) int {
+	flags := flag.NewFlagSet("wipe", flag.ContinueOnError)
+	wallet_flag(flags)
+	sweep_to := flags.String("sweep-to", "", T("secret to move all unspent webcash, mined or in the wallet, to before wiping, e.g. a claim code of another wallet"))
+	yes := flags.Bool("yes", false, T("do not ask for confirmation"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// The wallet is read first, since opening it makes its lock file.
+	w, store := wipe_wallet()
+	files, err := wipe_targets()
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+	if len(files) == 0 {
+		say("Nothing to wipe.")
+		return 0
+	}
+
+	secrets, _, err := read_mining_log(g_paths.MiningLog())
+	if err != nil {
+		say("Error: %v", err)
+		return 1
+	}
+
+	say("The following files will be overwritten and deleted:")
+	for _, path := range files {
+		fmt.Println("  " + path)
+	}
+	if len(secrets) > 0 && *sweep_to == "" {
+		say("Warning: %s holds %d mined webcash, which will be lost unless swept with -sweep-to.", g_paths.MiningLog(), len(secrets))
+	}
+	if w != nil {
+		if balance := w.Balance(); balance > 0 && *sweep_to == "" {
+			say("Warning: %s holds a balance of %v webcash, which will be lost unless swept with -sweep-to.", store.Path(), balance)
+		}
+		if len(w.Unconfirmed) > 0 {
+			say("Warning: %s has %d unconfirmed outputs, which are not swept; run `gocash check` first to settle them.", store.Path(), len(w.Unconfirmed))
+		}
+		if w.Keychain != "" {
+			say("Its master secret will be removed from the keychain.")
+		}
+	}
+	for _, path := range other_wallets(files, store) {
+		say("Warning: %s may hold webcash of another wallet, which is neither counted nor swept.", path)
+	}
+	if !*yes {
+		if !confirm("Continue? [y/N] ") {
+			say("Nothing was wiped.")
+			return 1
+		}
+		if ask("Type %q to confirm: ", "WIPE") != "WIPE" {
+			say("Nothing was wiped.")
+			return 1
+		}
+	}
+
+	if *sweep_to != "" {
+		to := *sweep_to
+		if sk, err := webcash.ParseSecretWebcash(to); err == nil {
+			to = sk.Secret
+		}
+		if w != nil {
+			secrets = append(secrets, w.Webcash...)
+		}
+		amount, err := sweep(secrets, to)
+		if err != nil {
+			say("Error: sweep failed, nothing was wiped: %v", err)
+			return 1
+		}
+		say_as(style.Success, "Swept %v to e%v:secret:%s", amount, amount, to)
+	}
+
+	failed := false
+	for _, path := range files {
+		if err := shred(path); err != nil {
+			say("Error: %v", err)
+			failed = true
+		}
+	}
+	if w != nil && w.Keychain != "" {
+		if err := wallet.RemoveKeychain(w.Keychain); err != nil {
+			say("Error: unable to remove the master secret from the keychain: %v", err)
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	say_as(style.Success, "Wiped %d files.", len(files))
+	return 0
+}
@@ -2,4 +2,7 @@ module github.com/maaku/gocash
 
 go 1.19
 
-require golang.org/x/sync v0.1.0 // indirect
+require (
+	golang.org/x/crypto v0.17.0
+	golang.org/x/sync v0.1.0
+)
@@ -0,0 +1,47 @@
+// Package decimal formats unsigned integers as decimal text without
+// allocating, for use in constructing mining payloads.
+package decimal
+
+// The decimal digits of the numbers 00 through 99, concatenated, so that two
+// digits at a time can be converted with a table lookup.
+const digit_pairs = "" +
+	"00010203040506070809" +
+	"10111213141516171819" +
+	"20212223242526272829" +
+	"30313233343536373839" +
+	"40414243444546474849" +
+	"50515253545556575859" +
+	"60616263646566676869" +
+	"70717273747576777879" +
+	"80818283848586878889" +
+	"90919293949596979899"
+
+// AppendPadded appends v to dst in decimal, left-padded with zeros to at least
+// width digits.  It is used to build mining payloads, so it converts two
+// digits at a time from a precomputed table rather than calling into strconv.
+func AppendPadded(dst []byte, v uint64, width int) []byte {
+	var tmp [20]byte
+	i := len(tmp)
+	for v >= 100 {
+		pair := (v % 100) * 2
+		v /= 100
+		i -= 2
+		tmp[i], tmp[i+1] = digit_pairs[pair], digit_pairs[pair+1]
+	}
+	if v >= 10 {
+		i -= 2
+		tmp[i], tmp[i+1] = digit_pairs[v*2], digit_pairs[v*2+1]
+	} else {
+		i--
+		tmp[i] = byte('0' + v)
+	}
+	for n := len(tmp) - i; n < width; n++ {
+		dst = append(dst, '0')
+	}
+	return append(dst, tmp[i:]...)
+}
+
+// AppendUint appends v to dst in decimal.
+func AppendUint(dst []byte, v uint64) []byte {
+	return AppendPadded(dst, v, 0)
+}
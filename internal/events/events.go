@@ -0,0 +1,218 @@
+// Package events writes a machine-readable audit trail of significant
+// operations, one JSON object per line, separate from the messages meant for
+// people.
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Fields are the details of an event.  Values must be serializable as JSON.
+type Fields map[string]interface{}
+
+// A Log appends events to a file.  A nil *Log discards events, so that code
+// can emit them unconditionally.
+type Log struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// Open returns a log which appends to the file at path, creating it when the
+// first event is emitted.
+func Open(path string) *Log {
+	return &Log{path: path}
+}
+
+// Emit records an event of the given type.  Each line of the log is a JSON
+// object with "time" (RFC 3339, UTC) and "type" keys, and the fields.
+func (l *Log) Emit(event string, fields Fields) error {
+	if l == nil {
+		return nil
+	}
+	line, err := encode(event, fields)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if os.IsNotExist(err) {
+		// The log directory is only made on first use.
+		if err = os.MkdirAll(filepath.Dir(l.path), 0700); err == nil {
+			f, err = os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// encode serializes an event as a JSON object with "time" and "type" keys
+// alongside its fields.
+func encode(event string, fields Fields) ([]byte, error) {
+	record := make(map[string]interface{}, len(fields)+2)
+	for k, v := range fields {
+		record[k] = v
+	}
+	record["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	record["type"] = event
+	return json.Marshal(record)
+}
+
+// A Webhook posts events to a URL, each as a JSON object in the same form as
+// the lines of a Log.  A nil *Webhook discards events.
+type Webhook struct {
+	URL string
+	// The HTTP client used to make requests, or nil for a default one.
+	HTTPClient *http.Client
+}
+
+// Post sends an event to the webhook, returning an error if it was not
+// accepted with a 2xx status.
+func (w *Webhook) Post(event string, fields Fields) error {
+	if w == nil {
+		return nil
+	}
+	body, err := encode(event, fields)
+	if err != nil {
+		return err
+	}
+	client := w.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+// A Transport is an http.RoundTripper which emits an "api_call" event for
+// every request made through it, with its outcome and how long it took.
+type Transport struct {
+	Inner http.RoundTripper
+	Log   *Log
+	// Summarizes the body of a request for the event's "request" field,
+	// which must leave out any secrets; may be nil.
+	Summarize func(path string, body []byte) interface{}
+	// Called if an event cannot be written; may be nil.
+	OnError func(error)
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	inner := t.Inner
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	// The summary is taken first, from a copy of the body, as sending the
+	// request consumes it.
+	var summary interface{}
+	if t.Summarize != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			summary = t.Summarize(req.URL.Path, data)
+			wipe(data)
+		}
+	}
+	start := time.Now()
+	resp, err := inner.RoundTrip(req)
+	fields := Fields{
+		"method":  req.Method,
+		"path":    req.URL.Path,
+		"host":    req.URL.Host,
+		"seconds": time.Since(start).Seconds(),
+	}
+	if summary != nil {
+		fields["request"] = summary
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	} else {
+		fields["status"] = resp.StatusCode
+	}
+	if werr := t.Log.Emit("api_call", fields); werr != nil && t.OnError != nil {
+		t.OnError(werr)
+	}
+	return resp, err
+}
+
+// wipe overwrites a buffer which may have held secrets.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// A Message is an event as published to the subscribers of a Stream.
+type Message struct {
+	// The type of the event.
+	Type string
+	// The event as a JSON object, in the same form as a line of a Log.
+	Data []byte
+}
+
+// A Stream publishes events to any number of subscribers as they happen.
+// Subscribers which fall behind miss events rather than holding up the
+// publisher.  The zero value is a stream with no subscribers.
+type Stream struct {
+	mutex       sync.Mutex
+	subscribers map[chan Message]struct{}
+}
+
+// The number of events each subscriber may fall behind by before it misses
+// some.
+const stream_buffer = 64
+
+// Publish sends an event to every subscriber.
+func (s *Stream) Publish(event string, fields Fields) error {
+	data, err := encode(event, fields)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- Message{Type: event, Data: data}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel which receives events published from now on,
+// and a function which ends the subscription.
+func (s *Stream) Subscribe() (<-chan Message, func()) {
+	ch := make(chan Message, stream_buffer)
+	s.mutex.Lock()
+	if s.subscribers == nil {
+		s.subscribers = make(map[chan Message]struct{})
+	}
+	s.subscribers[ch] = struct{}{}
+	s.mutex.Unlock()
+	return ch, func() {
+		s.mutex.Lock()
+		delete(s.subscribers, ch)
+		s.mutex.Unlock()
+	}
+}
@@ -0,0 +1,159 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decode parses an event as written, checking its time.
+func decode(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var record map[string]interface{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("event %q: %v", data, err)
+	}
+	s, _ := record["time"].(string)
+	if when, err := time.Parse(time.RFC3339Nano, s); err != nil || time.Since(when) > time.Minute {
+		t.Errorf("event %q has the time %q", data, s)
+	}
+	return record
+}
+
+func TestLog(t *testing.T) {
+	// A nil log discards events.
+	var nil_log *Log
+	if err := nil_log.Emit("ignored", nil); err != nil {
+		t.Errorf("Emit to a nil log: %v", err)
+	}
+
+	// The directory is made by the first event emitted.
+	l := Open(filepath.Join(t.TempDir(), "logs", "events.jsonl"))
+	tests := []struct {
+		event  string
+		fields Fields
+	}{
+		{"mined", Fields{"amount": "190", "difficulty": 28.0}},
+		{"empty", nil},
+		// The event's own keys win over fields of the same name.
+		{"clash", Fields{"type": "other", "time": "never"}},
+	}
+	for _, test := range tests {
+		if err := l.Emit(test.event, test.fields); err != nil {
+			t.Fatalf("Emit: %v", err)
+		}
+	}
+	if err := l.Emit("bad", Fields{"value": func() {}}); err == nil {
+		t.Error("Emit of a field which is not JSON succeeded")
+	}
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for _, test := range tests {
+		if !scanner.Scan() {
+			t.Fatalf("the log ends before %q", test.event)
+		}
+		record := decode(t, scanner.Bytes())
+		if record["type"] != test.event {
+			t.Errorf("event %s has the type %v", scanner.Bytes(), record["type"])
+		}
+		for k, v := range test.fields {
+			if k != "type" && k != "time" && record[k] != v {
+				t.Errorf("event %s has %s = %v, expected %v", scanner.Bytes(), k, record[k], v)
+			}
+		}
+	}
+	if scanner.Scan() {
+		t.Errorf("unexpected event %s", scanner.Bytes())
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var nil_hook *Webhook
+	if err := nil_hook.Post("ignored", nil); err != nil {
+		t.Errorf("Post to a nil webhook: %v", err)
+	}
+
+	tests := []struct {
+		status int
+		ok     bool
+	}{
+		{http.StatusOK, true},
+		{http.StatusNoContent, true},
+		{http.StatusMovedPermanently, false},
+		{http.StatusNotFound, false},
+		{http.StatusInternalServerError, false},
+	}
+	for _, test := range tests {
+		var received []byte
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("webhook called with %s and %q", r.Method, r.Header.Get("Content-Type"))
+			}
+			received, _ = io.ReadAll(r.Body)
+			w.WriteHeader(test.status)
+		}))
+		hook := &Webhook{URL: ts.URL, HTTPClient: ts.Client()}
+		err := hook.Post("mined", Fields{"amount": "190"})
+		ts.Close()
+		if test.ok != (err == nil) {
+			t.Errorf("Post answered with %d = %v", test.status, err)
+		}
+		if record := decode(t, received); record["type"] != "mined" || record["amount"] != "190" {
+			t.Errorf("webhook received %s", received)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	var s Stream
+	// Publishing with no subscribers goes nowhere.
+	if err := s.Publish("unheard", nil); err != nil {
+		t.Fatal(err)
+	}
+	a, end_a := s.Subscribe()
+	b, end_b := s.Subscribe()
+	defer end_b()
+	if err := s.Publish("first", Fields{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	for name, ch := range map[string]<-chan Message{"a": a, "b": b} {
+		select {
+		case m := <-ch:
+			if record := decode(t, m.Data); m.Type != "first" || record["type"] != "first" || record["n"] != 1.0 {
+				t.Errorf("%s received %s event %s", name, m.Type, m.Data)
+			}
+		default:
+			t.Errorf("%s received nothing", name)
+		}
+	}
+
+	// An ended subscription receives nothing more, and a subscriber which
+	// falls behind misses events rather than holding up the publisher.
+	end_a()
+	for i := 0; i < stream_buffer+10; i++ {
+		if err := s.Publish("many", Fields{"n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(a) != 0 {
+		t.Errorf("an ended subscription received %d events", len(a))
+	}
+	if len(b) != stream_buffer {
+		t.Errorf("a subscriber behind by %d events holds %d", stream_buffer+10, len(b))
+	}
+	if m := <-b; !strings.Contains(string(m.Data), `"n":0`) {
+		t.Errorf("the first event kept is %s, expected the earliest", m.Data)
+	}
+}
@@ -0,0 +1,246 @@
+// Package fleet lets many machines mine into one wallet.  A coordinator,
+// which holds the wallet and submits mining reports, serves its protocol
+// settings to workers over HTTP and takes the solutions they find in return.
+//
+// Workers choose their own random secrets, as every mining thread does, so
+// their searches never overlap and need no coordination beyond the settings.
+// The coordinator trusts nothing in a solution but its preimage: the reward,
+// difficulty and timestamp are read back out of it, and it is checked to hash
+// to a valid proof of work before being queued for submission.  A worker
+// knows the secret of every reward it finds, so the solution is marked as
+// Remote, and once the server accepts it the coordinator replaces the reward
+// with a secret of its own before counting it.
+//
+// Every request carries a shared token.  The preimages contain the secrets of
+// the mined webcash, so the protocol should be served over TLS, or within a
+// network that only the fleet can reach.
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/miner"
+	"github.com/maaku/gocash/webcash"
+)
+
+// The API endpoints served by the coordinator.
+const (
+	WorkPath     = "/fleet/v1/work"
+	SolutionPath = "/fleet/v1/solution"
+)
+
+// The largest request body the coordinator reads.  Preimages are well under
+// 1 KiB.
+const max_request_size = 16 << 10
+
+// ParseSolution checks that preimage is a mining payload whose hash meets the
+// difficulty it commits to, and returns it as a solution with the reward,
+// difficulty and timestamp it contains.
+func ParseSolution(preimage string) (miner.Solution, error) {
+	hash := webcash.Uint256(sha256.Sum256([]byte(preimage)))
+	raw, err := base64.StdEncoding.DecodeString(preimage)
+	if err != nil {
+		return miner.Solution{}, fmt.Errorf("preimage is not base64: %v", err)
+	}
+	var payload struct {
+		Webcash    []string `json:"webcash"`
+		Difficulty uint8    `json:"difficulty"`
+		Timestamp  float64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return miner.Solution{}, fmt.Errorf("preimage is not a mining payload: %v", err)
+	}
+	if len(payload.Webcash) == 0 {
+		return miner.Solution{}, errors.New("preimage claims no webcash")
+	}
+	reward, err := webcash.ParseSecretWebcash(payload.Webcash[0])
+	if err != nil {
+		return miner.Solution{}, fmt.Errorf("preimage reward: %v", err)
+	}
+	if !webcash.CheckProofOfWork(hash, payload.Difficulty) {
+		return miner.Solution{}, fmt.Errorf("hash %v does not meet difficulty %d", hash, payload.Difficulty)
+	}
+	return miner.Solution{
+		Hash:       hash,
+		Preimage:   preimage,
+		Reward:     reward,
+		Difficulty: payload.Difficulty,
+		Timestamp:  time.UnixMicro(int64(math.Round(payload.Timestamp * 1e6))),
+	}, nil
+}
+
+// A Server is the coordinator's end of the protocol, an http.Handler.
+type Server struct {
+	// The token workers must present.  Requests are refused if it is empty.
+	Token string
+	// Returns the protocol settings that workers should mine against.
+	Settings func() webcash.ProtocolSettings
+	// Where solutions from workers are queued for submission.
+	Solutions *miner.SolutionQueue
+	// Receives a description of notable events, if set.
+	Log func(format string, args ...interface{})
+}
+
+func (s *Server) log(format string, args ...interface{}) {
+	if s.Log != nil {
+		s.Log(format, args...)
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		s.log("fleet: refused unauthorized request from %s", r.RemoteAddr)
+		write_error(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+	switch r.URL.Path {
+	case WorkPath:
+		if r.Method != http.MethodGet {
+			write_error(w, http.StatusMethodNotAllowed, "expected GET")
+			return
+		}
+		write_json(w, http.StatusOK, s.Settings())
+
+	case SolutionPath:
+		if r.Method != http.MethodPost {
+			write_error(w, http.StatusMethodNotAllowed, "expected POST")
+			return
+		}
+		var report struct {
+			Preimage string `json:"preimage"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, max_request_size)).Decode(&report); err != nil {
+			write_error(w, http.StatusBadRequest, "request is not valid JSON")
+			return
+		}
+		soln, err := ParseSolution(report.Preimage)
+		if err != nil {
+			s.log("fleet: rejected solution from %s: %v", r.RemoteAddr, err)
+			write_error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		soln.Remote = true
+		// Wait for room in the queue, which slows the workers down along
+		// with the coordinator's own mining threads.
+		if err := s.Solutions.Push(r.Context(), soln); err != nil {
+			write_error(w, http.StatusServiceUnavailable, "solution queue is closed")
+			return
+		}
+		s.log("fleet: received solution %v from %s", soln.Hash, r.RemoteAddr)
+		write_json(w, http.StatusOK, map[string]string{"status": "queued"})
+
+	default:
+		write_error(w, http.StatusNotFound, "unknown endpoint")
+	}
+}
+
+// authorized reports whether the request carries the server's token.
+func (s *Server) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if s.Token == "" || !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+func write_json(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func write_error(w http.ResponseWriter, status int, msg string) {
+	write_json(w, status, map[string]string{"error": msg})
+}
+
+// A Client is a worker's end of the protocol.  Its methods are safe for
+// concurrent use.
+type Client struct {
+	// The base URL of the coordinator, e.g. "https://miner1:8333".
+	URL string
+	// The token presented to the coordinator.
+	Token string
+	// The HTTP client used to make requests.
+	HTTPClient *http.Client
+}
+
+// Work fetches the protocol settings to mine against.
+func (c *Client) Work() (webcash.ProtocolSettings, error) {
+	var settings webcash.ProtocolSettings
+	err := c.do(http.MethodGet, WorkPath, nil, &settings)
+	return settings, err
+}
+
+// Submit sends a solution to the coordinator, which queues it for submission
+// to the server.  A refusal by the coordinator is returned as a
+// *client.ServerError; a status of 400 means the solution itself was refused,
+// and retrying it is pointless.
+func (c *Client) Submit(soln miner.Solution) error {
+	request := map[string]string{"preimage": soln.Preimage}
+	var response struct {
+		Status string `json:"status"`
+	}
+	return c.do(http.MethodPost, SolutionPath, request, &response)
+}
+
+func (c *Client) do(method, path string, request, response interface{}) error {
+	var body io.Reader
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("failed to serialize %s request: %w", path, err)
+		}
+		body = bytes.NewReader(data)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	http_client := c.HTTPClient
+	if http_client == nil {
+		http_client = http.DefaultClient
+	}
+	resp, err := http_client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, max_request_size))
+	if err != nil {
+		return fmt.Errorf("%w: invalid message body in response to %s: %v", client.ErrMalformedResponse, path, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(data, &failure); err != nil || failure.Error == "" {
+			failure.Error = http.StatusText(resp.StatusCode)
+		}
+		return &client.ServerError{Path: path, StatusCode: resp.StatusCode, Message: failure.Error}
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("%w: response to %s is not valid JSON: %v", client.ErrMalformedResponse, path, err)
+	}
+	return nil
+}
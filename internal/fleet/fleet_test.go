@@ -0,0 +1,199 @@
+package fleet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/miner"
+	"github.com/maaku/gocash/webcash"
+)
+
+const test_reward = "e200:secret:8e2c4e6f0b0b9a3d5b9cfe2a2c0e7f1a3b4c5d6e7f8091a2b3c4d5e6f708192a"
+
+// preimage returns a mining payload with the given JSON fields, base64
+// encoded as the miner sends it.
+func preimage(fields string) string {
+	return base64.StdEncoding.EncodeToString([]byte("{" + fields + "}"))
+}
+
+// solve returns a payload committing to difficulty whose hash meets it, found
+// by trying nonces.
+func solve(t *testing.T, difficulty uint8) string {
+	t.Helper()
+	for nonce := 0; nonce < 1<<20; nonce++ {
+		p := preimage(fmt.Sprintf(`"webcash": [%q], "subsidy": [], "difficulty": %d, "timestamp": 1700000000.25, "nonce": %d`, test_reward, difficulty, nonce))
+		if webcash.CheckProofOfWork(webcash.Uint256(sha256.Sum256([]byte(p))), difficulty) {
+			return p
+		}
+	}
+	t.Fatalf("no solution found at difficulty %d", difficulty)
+	return ""
+}
+
+func TestParseSolution(t *testing.T) {
+	good := solve(t, 8)
+	soln, err := ParseSolution(good)
+	if err != nil {
+		t.Fatalf("ParseSolution: %v", err)
+	}
+	reward, _ := webcash.ParseSecretWebcash(test_reward)
+	if soln.Reward != reward || soln.Difficulty != 8 || soln.Preimage != good || soln.Remote {
+		t.Errorf("ParseSolution = %+v, expected the reward %v at difficulty 8", soln, reward)
+	}
+	if want := time.Unix(1700000000, 250_000_000); !soln.Timestamp.Equal(want) {
+		t.Errorf("timestamp %v, expected %v", soln.Timestamp, want)
+	}
+	if soln.Hash != webcash.Uint256(sha256.Sum256([]byte(good))) {
+		t.Errorf("hash %v is not that of the preimage", soln.Hash)
+	}
+
+	tests := []struct {
+		name, preimage, err string
+	}{
+		{"not base64", "not base64!", "not base64"},
+		{"not JSON", base64.StdEncoding.EncodeToString([]byte("webcash")), "not a mining payload"},
+		{"wrong types", preimage(`"webcash": "e1:secret:abc", "difficulty": 0`), "not a mining payload"},
+		{"no webcash", preimage(`"webcash": [], "difficulty": 0`), "claims no webcash"},
+		{"bad reward", preimage(`"webcash": ["e1:public:abc"], "difficulty": 0`), "preimage reward"},
+		{"bad proof of work", preimage(`"webcash": ["` + test_reward + `"], "difficulty": 200`), "does not meet difficulty 200"},
+	}
+	for _, test := range tests {
+		if _, err := ParseSolution(test.preimage); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: ParseSolution = %v, expected an error containing %q", test.name, err, test.err)
+		}
+	}
+}
+
+// serve starts a coordinator with the given token, returning it and a client
+// for it presenting client_token.
+func serve(t *testing.T, token, client_token string) (*Server, *Client) {
+	t.Helper()
+	s := &Server{
+		Token:     token,
+		Settings:  func() webcash.ProtocolSettings { return webcash.ProtocolSettings{Difficulty: 8} },
+		Solutions: miner.NewSolutionQueue(1),
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, &Client{URL: ts.URL, Token: client_token, HTTPClient: ts.Client()}
+}
+
+// status returns the status code of a refusal, or 0.
+func status(err error) int {
+	var refused *client.ServerError
+	if errors.As(err, &refused) {
+		return refused.StatusCode
+	}
+	return 0
+}
+
+func TestServerToken(t *testing.T) {
+	good := solve(t, 8)
+	tests := []struct {
+		name, token, client_token string
+	}{
+		{"wrong token", "sesame", "open"},
+		{"token prefix", "sesame", "sesam"},
+		{"missing token", "sesame", ""},
+		// A coordinator without a token refuses everyone, even those who
+		// present none.
+		{"no server token", "", ""},
+	}
+	for _, test := range tests {
+		s, c := serve(t, test.token, test.client_token)
+		if _, err := c.Work(); status(err) != http.StatusUnauthorized {
+			t.Errorf("%s: Work = %v, expected 401", test.name, err)
+		}
+		if err := c.Submit(miner.Solution{Preimage: good}); status(err) != http.StatusUnauthorized {
+			t.Errorf("%s: Submit = %v, expected 401", test.name, err)
+		}
+		if n := len(s.Solutions.C()); n != 0 {
+			t.Errorf("%s: %d solutions queued", test.name, n)
+		}
+	}
+
+	// Nor is a token accepted other than as a bearer token.
+	s, _ := serve(t, "sesame", "sesame")
+	req := httptest.NewRequest(http.MethodGet, WorkPath, nil)
+	req.Header.Set("Authorization", "sesame")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("a token without \"Bearer\" got %d, expected 401", rec.Code)
+	}
+}
+
+func TestServerSolutions(t *testing.T) {
+	s, c := serve(t, "sesame", "sesame")
+	settings, err := c.Work()
+	if err != nil || settings.Difficulty != 8 {
+		t.Fatalf("Work = %+v, %v, expected difficulty 8", settings, err)
+	}
+
+	good := solve(t, 8)
+	if err := c.Submit(miner.Solution{Preimage: good}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case soln := <-s.Solutions.C():
+		// Only the preimage is trusted, and the solution is marked as
+		// found elsewhere.
+		if soln.Preimage != good || !soln.Remote || soln.Reward.Secret == "" {
+			t.Errorf("queued %+v, expected the remote solution submitted", soln)
+		}
+	default:
+		t.Fatal("the solution was not queued")
+	}
+	// A solution which is not valid is refused as such, and not queued.
+	if err := c.Submit(miner.Solution{Preimage: preimage(`"webcash": ["` + test_reward + `"], "difficulty": 200`)}); status(err) != http.StatusBadRequest {
+		t.Errorf("Submit of a bad solution = %v, expected 400", err)
+	}
+	if n := len(s.Solutions.C()); n != 0 {
+		t.Errorf("%d bad solutions queued", n)
+	}
+
+	for _, test := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, WorkPath, "", http.StatusMethodNotAllowed},
+		{http.MethodGet, SolutionPath, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, SolutionPath, "preimage", http.StatusBadRequest},
+		{http.MethodGet, "/fleet/v1/other", "", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		req.Header.Set("Authorization", "Bearer sesame")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != test.want {
+			t.Errorf("%s %s: got %d, expected %d", test.method, test.path, rec.Code, test.want)
+		}
+	}
+
+	// With the queue full, a solution waits for room, and is refused if the
+	// request ends first.
+	if err := c.Submit(miner.Solution{Preimage: good}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, SolutionPath, strings.NewReader(`{"preimage": "`+good+`"}`))
+	req.Header.Set("Authorization", "Bearer sesame")
+	ctx, cancel := context.WithTimeout(req.Context(), 50*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req.WithContext(ctx))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("a solution for a full queue got %d, expected 503", rec.Code)
+	}
+	if n := len(s.Solutions.C()); n != 1 {
+		t.Errorf("%d solutions queued, expected 1", n)
+	}
+}
@@ -0,0 +1,120 @@
+// Package i18n translates user-facing messages.  Messages are identified by
+// their English text, which is used as-is when no translation is available, so
+// untranslated messages degrade gracefully rather than going missing.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// A Translator translates messages, identified by their English text, into
+// one language.  It returns false if it has no translation of a message.
+type Translator interface {
+	Translate(msg string) (string, bool)
+}
+
+// A Catalog is a Translator backed by a fixed table from English messages to
+// their translations.  Format strings are translated before formatting, so a
+// translation must keep the verbs of the original, in the same order.
+type Catalog map[string]string
+
+func (c Catalog) Translate(msg string) (string, bool) {
+	translated, ok := c[msg]
+	return translated, ok
+}
+
+var (
+	mutex       sync.RWMutex
+	translators = map[string]Translator{}
+	language    = "en"
+	current     Translator
+)
+
+// Register makes a translator available for a language, named by its ISO 639
+// code, e.g. "es".
+func Register(lang string, t Translator) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	translators[lang] = t
+}
+
+// SetLanguage selects the language messages are translated into.  Locale
+// names such as "es_ES.UTF-8" are accepted.  It returns false, and leaves
+// messages in English, if no translator is registered for the language.
+func SetLanguage(lang string) bool {
+	lang = normalize(lang)
+	mutex.Lock()
+	defer mutex.Unlock()
+	t, ok := translators[lang]
+	if !ok {
+		language, current = "en", nil
+		return lang == "en"
+	}
+	language, current = lang, t
+	return true
+}
+
+// Language returns the language currently selected.
+func Language() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return language
+}
+
+// Languages returns the languages which have a registered translator, besides
+// English.
+func Languages() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	langs := make([]string, 0, len(translators))
+	for lang := range translators {
+		langs = append(langs, lang)
+	}
+	return langs
+}
+
+// FromEnvironment returns the user's language as given by the LC_ALL,
+// LC_MESSAGES and LANG environment variables, in the usual order of
+// precedence, or "en" if none is set.
+func FromEnvironment() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return normalize(value)
+		}
+	}
+	return "en"
+}
+
+// normalize reduces a locale name like "pt_BR.UTF-8@euro" to its language
+// code.  The "C" and "POSIX" locales are English.
+func normalize(locale string) string {
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if lang == "" || lang == "c" || lang == "posix" {
+		return "en"
+	}
+	return lang
+}
+
+// T translates a message into the selected language.
+func T(msg string) string {
+	mutex.RLock()
+	t := current
+	mutex.RUnlock()
+	if t != nil {
+		if translated, ok := t.Translate(msg); ok {
+			return translated
+		}
+	}
+	return msg
+}
+
+// Sprintf translates a format string, then formats it like fmt.Sprintf.
+func Sprintf(format string, args ...interface{}) string {
+	return fmt.Sprintf(T(format), args...)
+}
@@ -0,0 +1,457 @@
+package locales
+
+import "github.com/maaku/gocash/internal/i18n"
+
+func init() {
+	i18n.Register("es", spanish)
+}
+
+// Spanish.
+var spanish = i18n.Catalog{
+	// Terms of service
+	"The terms of service have changed since you last accepted them.": "Los términos del servicio han cambiado desde la última vez que los aceptó.",
+	"Do you accept the terms of service? [y/N] ":                      "¿Acepta los términos del servicio? [s/N] ",
+	"y":   "s",
+	"yes": "sí",
+
+	// Mining and submission
+	"GOT SOLUTION!!! %v %v":                                                                     "¡¡¡SOLUCIÓN ENCONTRADA!!! %v %v",
+	"Using SHA256 algorithm: %s":                                                                "Usando el algoritmo SHA256: %s",
+	"Running %d mining threads":                                                                 "Ejecutando %d hilos de minería",
+	"Difficulty adjustment occurred!  Server says difficulty=%d":                                "¡Ajuste de dificultad!  El servidor indica difficulty=%d",
+	"Server rejected MiningReport: %d %s":                                                       "El servidor rechazó el MiningReport: %d %s",
+	"Ignoring solution as difficulty commitment is too low: (%d < %d)":                          "Se ignora la solución porque la dificultad comprometida es demasiado baja: (%d < %d)",
+	"Ignoring solution as apparent difficulty is too low: (%d < %d)":                            "Se ignora la solución porque la dificultad aparente es demasiado baja: (%d < %d)",
+	"Ignoring solution as timestamp is too old: (%v < %v)":                                      "Se ignora la solución porque la marca de tiempo es demasiado antigua: (%v < %v)",
+	"Possible transient error, or server timeout?  Waiting to re-attempt.":                      "¿Error transitorio, o tiempo de espera del servidor agotado?  Esperando para reintentar.",
+	"server says difficulty=%v ratio=%v speed=%s expect=%v best=%d conn_reuse=%s queue=%v":      "el servidor indica difficulty=%v ratio=%v speed=%s expect=%v best=%d conn_reuse=%s queue=%v",
+	"Error: unable to pin mining thread %d to CPU %d: %v":                                       "Error: no se pudo fijar el hilo de minería %d a la CPU %d: %v",
+	"mining thread %d: failed to generate secrets: %v":                                          "hilo de minería %d: no se pudieron generar los secretos: %v",
+	"closing mining thread %d":                                                                  "cerrando el hilo de minería %d",
+	"Warning: %s:%d: %v (`gocash recover` can find the webcash of a report which was accepted)": "Advertencia: %s:%d: %v (`gocash recover` puede encontrar el webcash de un informe que fue aceptado)",
+	"Error: failed to claim the keep secret: %v":                                                "Error: no se pudo reclamar el secreto propio: %v",
+	"dropping a solution whose keep secret another has claimed":                                 "se descarta una solución cuyo secreto propio ya reclamó otra",
+
+	// Errors
+	"Error: %v":                                                  "Error: %v",
+	"Error: -memory-limit: %v":                                   "Error: -memory-limit: %v",
+	"Error: failed to open %s: %v":                               "Error: no se pudo abrir %s: %v",
+	"Error: unable to fetch mining target: %v":                   "Error: no se pudo obtener el objetivo de minería: %v",
+	"Error: config reload failed, keeping previous settings: %v": "Error: falló la recarga de la configuración, se mantiene la anterior: %v",
+	"Warning: no translation for language %q, using English":     "Advertencia: no hay traducción para el idioma %q, se usa el inglés",
+
+	"Error: failed to record event: %v":        "Error: no se pudo registrar el evento: %v",
+	"Error: failed to record stats: %v":        "Error: no se pudieron registrar las estadísticas: %v",
+	"Error: unknown command %q (commands: %s)": "Error: orden desconocida %q (órdenes: %s)",
+
+	// Statistics export
+	"Usage: gocash stats [export|summary] [flags]": "Uso: gocash stats [export|summary] [opciones]",
+	"Error: -since: %v":                            "Error: -since: %v",
+	"Error: -until: %v":                            "Error: -until: %v",
+	"Error: -format: unknown format %q":            "Error: -format: formato desconocido %q",
+	"output format, \"csv\" or \"json\"":           "formato de salida, \"csv\" o \"json\"",
+	"only export samples after this time, RFC 3339 or a duration before now such as \"24h\"": "exportar solo muestras posteriores a este momento, en RFC 3339 o como duración hasta ahora, p. ej. \"24h\"",
+	"only export samples before this time, RFC 3339 or a duration before now":                "exportar solo muestras anteriores a este momento, en RFC 3339 o como duración hasta ahora",
+	"stats file to read":                          "archivo de estadísticas a leer",
+	"file to write to (default: standard output)": "archivo de salida (por defecto: la salida estándar)",
+
+	// Status
+	"Server: %s":                        "Servidor: %s",
+	"Epoch %d, difficulty %d, ratio %v": "Época %d, dificultad %d, proporción %v",
+	"Mining reward %v, of which %v is the server subsidy":                                "Recompensa de minería %v, de la que %v es la subvención del servidor",
+	"%d mining reports so far, %d of %d into this epoch":                                 "%d informes de minería hasta ahora, %d de %d en esta época",
+	"Mining report count unavailable (%v); dates are the latest the epochs could begin.": "Número de informes de minería no disponible (%v); las fechas son las más tardías en que podrían comenzar las épocas.",
+	"epoch":        "época",
+	"starts":       "comienza",
+	"miner reward": "recompensa",
+	"subsidy":      "subvención",
+	"now":          "ahora",
+	"project the upcoming epochs and their rewards":          "proyectar las próximas épocas y sus recompensas",
+	"number of epochs to project, including the current one": "número de épocas a proyectar, incluida la actual",
+
+	// Paths
+	"Mined webcash is logged to %s":                                          "El webcash minado se registra en %s",
+	"create the directories, with private permissions, if they do not exist": "crear los directorios, con permisos privados, si no existen",
+	"config":      "configuración",
+	"config file": "archivo de configuración",
+	"terms":       "términos",
+	"wallet":      "cartera",
+	"wallet file": "archivo de cartera",
+	"mining log":  "registro de minería",
+	"log":         "registros",
+	"orphan log":  "registro de huérfanas",
+	"events":      "eventos",
+	"stats":       "estadísticas",
+	"cache":       "caché",
+
+	// Setup
+	"number of CPUs to mine with, instead of asking":                                        "número de CPUs con las que minar, en lugar de preguntar",
+	"Welcome to gocash.  This will set up mining on this machine.":                          "Bienvenido a gocash.  Esto configurará la minería en esta máquina.",
+	"Files are kept in %s, %s and %s (see `gocash paths`).":                                 "Los archivos se guardan en %s, %s y %s (vea `gocash paths`).",
+	"The terms of service are accepted.":                                                    "Los términos del servicio están aceptados.",
+	"This machine has %d CPUs.  How many should mine? [%d] ":                                "Esta máquina tiene %d CPUs.  ¿Cuántas deben minar? [%d] ",
+	"Please enter a number from 1 to %d.":                                                   "Introduzca un número del 1 al %d.",
+	"Saved to %s.":                                                                          "Guardado en %s.",
+	"Setup is complete.  Run `gocash` to start mining; mined webcash will be logged to %s.": "La configuración está completa.  Ejecute `gocash` para empezar a minar; el webcash minado se registrará en %s.",
+
+	// Wipe
+	"Warning: %s:%d: %v":                                   "Advertencia: %s:%d: %v",
+	"do not ask for confirmation":                          "no pedir confirmación",
+	"Nothing to wipe.":                                     "No hay nada que borrar.",
+	"The following files will be overwritten and deleted:": "Los siguientes archivos se sobrescribirán y eliminarán:",
+	"Warning: %s holds %d mined webcash, which will be lost unless swept with -sweep-to.": "Advertencia: %s contiene %d webcash minados, que se perderán si no se transfieren con -sweep-to.",
+	"Continue? [y/N] ":                           "¿Continuar? [s/N] ",
+	"Type %q to confirm: ":                       "Escriba %q para confirmar: ",
+	"Nothing was wiped.":                         "No se borró nada.",
+	"Error: sweep failed, nothing was wiped: %v": "Error: falló la transferencia, no se borró nada: %v",
+	"Swept %v to e%v:secret:%s":                  "Transferido %v a e%v:secret:%s",
+	"Wiped %d files.":                            "Borrados %d archivos.",
+	"secret to move all unspent webcash, mined or in the wallet, to before wiping, e.g. a claim code of another wallet": "secreto al que mover todo el webcash sin gastar, minado o de la cartera, antes de borrar, p. ej. un código de otra cartera",
+	"Warning: unable to read the wallet, so its balance is neither counted nor swept: %v":                               "Advertencia: no se pudo leer la cartera, así que su saldo ni se cuenta ni se barre: %v",
+	"Warning: unable to read %s, so its balance is neither counted nor swept: %v":                                       "Advertencia: no se pudo leer %s, así que su saldo ni se cuenta ni se barre: %v",
+	"Warning: %s holds a balance of %v webcash, which will be lost unless swept with -sweep-to.":                        "Advertencia: %s tiene un saldo de %v webcash, que se perderá a menos que se barra con -sweep-to.",
+	"Warning: %s has %d unconfirmed outputs, which are not swept; run `gocash check` first to settle them.":             "Advertencia: %s tiene %d salidas sin confirmar, que no se barren; ejecute antes `gocash check` para resolverlas.",
+	"Its master secret will be removed from the keychain.":                                                              "Su secreto maestro se quitará del llavero.",
+	"Warning: %s may hold webcash of another wallet, which is neither counted nor swept.":                               "Advertencia: %s puede contener webcash de otra cartera, que ni se cuenta ni se barre.",
+
+	// Prices
+	"where to get the price of webcash, for showing what it is worth: \"fixed:<currency>:<price>\" or \"json:<currency>:<field>:<url>\"": "de dónde obtener el precio del webcash, para mostrar cuánto vale: \"fixed:<moneda>:<precio>\" o \"json:<moneda>:<campo>:<url>\"",
+	"Warning: unable to get price from %s: %v": "Advertencia: no se pudo obtener el precio de %s: %v",
+	" (about %s)":          " (unos %s)",
+	"Mined %v%s":           "Minado %v%s",
+	"Error: -price: %v":    "Error: -price: %v",
+	"The miner keeps %v%s": "El minero se queda %v%s",
+
+	// Output style
+	"when to color output: \"auto\", \"always\" or \"never\"; NO_COLOR in the environment also turns it off": "cuándo colorear la salida: \"auto\", \"always\" o \"never\"; NO_COLOR en el entorno también lo desactiva",
+	"color theme, \"dark\" or \"light\", to suit the terminal background":                                    "tema de color, \"dark\" o \"light\", según el fondo de la terminal",
+	"plain, line-oriented output with no color or control sequences, for screen readers and log collectors":  "salida simple, línea a línea, sin color ni secuencias de control, para lectores de pantalla y recolectores de registros",
+
+	// Self-test
+	"include a self-replace of the smallest unspent mined webcash": "incluir un autorreemplazo del webcash minado sin gastar más pequeño",
+	"fetch settings":                                    "obtener ajustes",
+	"terms of service":                                  "términos del servicio",
+	"accepted terms are current":                        "los términos aceptados están al día",
+	"find unspent webcash":                              "buscar webcash sin gastar",
+	"disabled by -replace=false":                        "desactivado con -replace=false",
+	"no mined webcash in %s":                            "no hay webcash minado en %s",
+	"all mined webcash is spent":                        "todo el webcash minado está gastado",
+	"self-replace":                                      "autorreemplazo",
+	"health check":                                      "comprobación de estado",
+	"the replaced webcash is not marked spent":          "el webcash reemplazado no figura como gastado",
+	"the new webcash is not marked unspent":             "el webcash nuevo no figura como sin gastar",
+	"replaced webcash is spent, new webcash is unspent": "el webcash reemplazado está gastado y el nuevo sin gastar",
+	"Error: self-test failed":                           "Error: falló la autocomprobación",
+	"Self-test passed.":                                 "Autocomprobación superada.",
+
+	"interval between hashrate status lines (0 to disable)":                                           "intervalo entre líneas de estado del hashrate (0 para desactivarlas)",
+	"hashrate now=%s 1m=%s 15m=%s attempts=%d accepted=%d rejected=%d luck=%s expect=%s yield=%v/day": "hashrate actual=%s 1m=%s 15m=%s intentos=%d aceptados=%d rechazados=%d suerte=%s espera=%s rendimiento=%v/día",
+
+	"percentage of the time each mining thread spends hashing, resting the remainder": "porcentaje del tiempo que cada hilo de minería dedica a calcular hashes, descansando el resto",
+	"mine at the lowest scheduling priority, so that other programs come first":       "minar con la prioridad de planificación más baja, para que los demás programas vayan primero",
+	"Error: unable to lower the priority of mining thread %d: %v":                     "Error: no se puede bajar la prioridad del hilo de minería %d: %v",
+
+	"OpenCL devices to also mine on, \"all\" or a comma-separated list of numbers as shown by `gocash gpus`": "dispositivos OpenCL en los que minar también, \"all\" o una lista de números separados por comas como los que muestra `gocash gpus`",
+	"Mining on GPU %d: %s":                                 "Minando en la GPU %d: %s",
+	"No OpenCL devices found.":                             "No se encontraron dispositivos OpenCL.",
+	"%s (%s, %d compute units)":                            "%s (%s, %d unidades de cómputo)",
+	"closing GPU mining thread %d":                         "cerrando el hilo de minería de la GPU %d",
+	"GPU mining thread %d: failed to generate secrets: %v": "hilo de minería de la GPU %d: no se pudieron generar los secretos: %v",
+	"Error: GPU %d: %v":                                    "Error: GPU %d: %v",
+	"Error: GPU %d returned an invalid solution":           "Error: la GPU %d devolvió una solución no válida",
+
+	"hashes computed per call into the SHA-256 library: 8, 40, 200 or 1000":                                                        "hashes calculados por llamada a la biblioteca SHA-256: 8, 40, 200 o 1000",
+	"SHA-256 implementation to mine with: \"auto\" for the fastest libsha2 has for this CPU, or \"go\" for Go's own crypto/sha256": "implementación de SHA-256 con la que minar: \"auto\" para la más rápida que libsha2 tiene para esta CPU, o \"go\" para la propia crypto/sha256 de Go",
+
+	"Error: failed to record pending solution in %s: %v":                     "Error: no se pudo registrar la solución pendiente en %s: %v",
+	"Reconciling %d pending mining reports from %s":                          "Conciliando %d informes de minería pendientes de %s",
+	"Error: unable to check pending mining reports, will retry next run: %v": "Error: no se pueden comprobar los informes de minería pendientes, se reintentará en la próxima ejecución: %v",
+	"Recovered %v": "Recuperado %v",
+	"pending log":  "registro pendiente",
+
+	"hashes per second to estimate mining rewards for (default: the average over the last day of recorded mining)": "hashes por segundo para los que estimar las recompensas de minería (por defecto: el promedio de la minería registrada durante el último día)",
+	"At %s, expect a solution every %s and %v per day%s":                                                           "A %s, se espera una solución cada %s y %v por día%s",
+
+	"Warning: %v": "Advertencia: %v",
+
+	"extra leading zero bits beyond the difficulty a solution needs to be submitted, so that it is not rejected after a difficulty increase": "bits a cero iniciales adicionales a la dificultad que necesita una solución para enviarse, para que no sea rechazada tras un aumento de dificultad",
+	"Skipping marginal solution: (%d < %d + %d margin), %d skipped so far":                                                                   "Omitiendo solución marginal: (%d < %d + %d de margen), %d omitidas hasta ahora",
+
+	"caught signal %v, shutting down": "recibida la señal %v, cerrando",
+	"Mined for %v: %d hashes at %s, %d mining reports accepted, %d marginal solutions skipped": "Minado durante %v: %d hashes a %s, %d informes de minería aceptados, %d soluciones marginales omitidas",
+	"Unsubmitted solution: %v %v":         "Solución sin enviar: %v %v",
+	"Saved unsubmitted solution %v to %s": "Guardada la solución sin enviar %v en %s",
+
+	"find the number of mining threads with the best hashrate, measuring each candidate for this long (0 to disable)": "buscar el número de hilos de minería con el mejor hashrate, midiendo cada candidato durante este tiempo (0 para desactivarlo)",
+	"autotune: %d mining threads do better than %d (%s vs %s)":                                                        "autoajuste: %d hilos de minería rinden más que %d (%s frente a %s)",
+	"autotune: keeping %d mining threads (%s)":                                                                        "autoajuste: se mantienen %d hilos de minería (%s)",
+
+	"measure the hashrate for this long against a synthetic target, without contacting the server, and exit": "medir el hashrate durante este tiempo con un objetivo sintético, sin contactar con el servidor, y salir",
+	"Benchmarking for %v at difficulty %d, without contacting the server":                                    "Midiendo el rendimiento durante %v con dificultad %d, sin contactar con el servidor",
+	"Error: invalid solution %v": "Error: solución no válida %v",
+	"thread %d: %s":              "hilo %d: %s",
+	"total: %s from %d threads over %v, %d solutions found": "total: %s con %d hilos durante %v, %d soluciones encontradas",
+
+	"only mine within these daily windows of local time, e.g. \"22:00-07:00\" or \"00:00-08:00,12:00-13:00\"": "minar solo dentro de estas franjas diarias de hora local, p. ej. \"22:00-07:00\" o \"00:00-08:00,12:00-13:00\"",
+	"pause mining while other programs use more than this percentage of the CPU (0 to disable; Linux only)":   "pausar la minería mientras otros programas usen más de este porcentaje de la CPU (0 para desactivarlo; solo Linux)",
+	"Error: -schedule: %v": "Error: -schedule: %v",
+	"Error: -pause-load: must be a percentage from 0 to 100": "Error: -pause-load: debe ser un porcentaje de 0 a 100",
+	"Error: -pause-load: %v":                                 "Error: -pause-load: %v",
+	"Error: -sha256: %v":                                     "Error: -sha256: %v",
+	"outside the mining schedule":                            "fuera del horario de minería",
+	"other programs are using %.0f%% of the CPU":             "otros programas están usando el %.0f%% de la CPU",
+	"Pausing mining: %s":                                     "Pausando la minería: %s",
+	"Resuming mining":                                        "Reanudando la minería",
+
+	"Epoch %d began: mining reward %v, of which %v is the server subsidy":       "Comenzó la época %d: recompensa de minería %v, de la que %v es la subvención del servidor",
+	"Ignoring solution as it was mined for a previous epoch: (reward %v != %v)": "Se ignora la solución porque se minó para una época anterior: (recompensa %v != %v)",
+
+	"only summarize samples after this time, RFC 3339 or a duration before now such as \"24h\"": "resumir solo las muestras posteriores a este momento, en RFC 3339 o como una duración antes de ahora como \"24h\"",
+	"only summarize samples before this time, RFC 3339 or a duration before now":                "resumir solo las muestras anteriores a este momento, en RFC 3339 o como una duración antes de ahora",
+	"Mined for %v at an average of %s":                                                          "Minado durante %v a una media de %s",
+	"%d mining reports answered: %d accepted, %d rejected (%.1f%% accepted)":                    "%d informes de minería respondidos: %d aceptados, %d rechazados (%.1f%% aceptados)",
+	"difficulty":              "dificultad",
+	"stale":                   "caducado",
+	"duplicate":               "duplicado",
+	"other":                   "otro",
+	"Nothing recorded in %s.": "No hay nada registrado en %s.",
+	"Since %s: %d hashes attempted, %v earned":                "Desde %s: %d hashes intentados, %v ganados",
+	"Luck %s: %d solutions accepted where %.2f were expected": "Suerte %s: %d soluciones aceptadas donde se esperaban %.2f",
+
+	// Fleet
+	"address to accept fleet workers on, e.g. \":8333\", making this instance the coordinator which submits their solutions": "dirección en la que aceptar trabajadores de la flota, p. ej. \":8333\", lo que hace de esta instancia el coordinador que envía sus soluciones",
+	"base URL of a fleet coordinator to mine for, sending it solutions instead of submitting them to the server":             "URL base de un coordinador de flota para el que minar, enviándole las soluciones en lugar de al servidor",
+	"token shared by the coordinator and workers of a fleet; best set in the config file, where other users cannot read it":  "token compartido por el coordinador y los trabajadores de una flota; mejor en el archivo de configuración, donde otros usuarios no pueden leerlo",
+	"TLS certificate file for -fleet-listen":                             "archivo de certificado TLS para -fleet-listen",
+	"TLS private key file for -fleet-listen":                             "archivo de clave privada TLS para -fleet-listen",
+	"Error: -fleet-listen and -coordinator cannot be used together":      "Error: -fleet-listen y -coordinator no pueden usarse juntos",
+	"Error: -fleet-token is required with -fleet-listen or -coordinator": "Error: -fleet-token es obligatorio con -fleet-listen o -coordinator",
+	"Error: -fleet-cert and -fleet-key must be given together":           "Error: -fleet-cert y -fleet-key deben darse juntos",
+	"Accepting workers on %s":                                            "Aceptando trabajadores en %s",
+	"Mining for the coordinator at %s":                                   "Minando para el coordinador en %s",
+	"Sent solution %v to the coordinator":                                "Solución %v enviada al coordinador",
+	"Coordinator refused solution %v: %s":                                "El coordinador rechazó la solución %v: %s",
+	"Sending %d pending solutions from %s":                               "Enviando %d soluciones pendientes de %s",
+	"fleet: refused unauthorized request from %s":                        "flota: se rechazó una petición no autorizada de %s",
+	"fleet: rejected solution from %s: %v":                               "flota: se rechazó la solución de %s: %v",
+	"fleet: received solution %v from %s":                                "flota: solución %v recibida de %s",
+	"Warning: the reward of solution %v, from a worker, may not have been replaced, so it is kept as it is: %v": "Advertencia: puede que la recompensa de la solución %v, de un trabajador, no se haya reemplazado, así que se guarda tal cual: %v",
+	"Warning: the reward of solution %v, from a worker, is already spent":                                       "Advertencia: la recompensa de la solución %v, de un trabajador, ya está gastada",
+
+	// Notifications
+	"URL to post a JSON notification to whenever a mining report is accepted": "URL a la que enviar una notificación JSON cada vez que se acepta un informe de minería",
+	"Error: -webhook: expected an http or https URL":                          "Error: -webhook: se esperaba una URL http o https",
+	"Warning: webhook: %v": "Advertencia: webhook: %v",
+	"show a desktop notification when a solution is found, and when the server starts rejecting mining reports": "mostrar una notificación de escritorio al encontrar una solución, y cuando el servidor empiece a rechazar informes de minería",
+	"Warning: desktop notification: %v":          "Advertencia: notificación de escritorio: %v",
+	"Found a solution worth %v webcash":          "Encontrada una solución que vale %v webcash",
+	"The server is rejecting mining reports: %s": "El servidor está rechazando informes de minería: %s",
+	"Mined %v webcash":                           "Minados %v webcash",
+
+	// Pausing
+	"Mining paused by request":                                      "Minería en pausa por petición",
+	"Mining resumed by request":                                     "Minería reanudada por petición",
+	"Mining resumed by request, but remains paused by the schedule": "Minería reanudada por petición, pero sigue en pausa por el horario",
+	"Enter \"p\" to pause or resume mining":                         "Introduzca \"p\" para pausar o reanudar la minería",
+
+	// Dashboard
+	"address to serve a status web page on, and a live stream of events at /events, e.g. \"localhost:8080\"": "dirección en la que servir una página web de estado, y un flujo de eventos en vivo en /events, p. ej. \"localhost:8080\"",
+	"Serving the dashboard on http://%s/": "Sirviendo el panel en http://%s/",
+	"Server":                              "Servidor",
+	"Difficulty":                          "Dificultad",
+	"Epoch":                               "Época",
+	"Mining threads":                      "Hilos de minería",
+	"Hashrate":                            "Tasa de hash",
+	"Expected time to a solution":         "Tiempo esperado hasta una solución",
+	"Mining reports accepted / rejected":  "Informes de minería aceptados / rechazados",
+	"Luck":                                "Suerte",
+	"Mining log total":                    "Total del registro de minería",
+	"Hashrate over the last hour":         "Tasa de hash en la última hora",
+	"Recent messages":                     "Mensajes recientes",
+	"Pause":                               "Pausar",
+	"Resume":                              "Reanudar",
+	"Unable to reach the miner":           "No se puede contactar con el minero",
+
+	// Metrics and profiling
+	"address to serve Prometheus metrics on at /metrics, e.g. \"localhost:9477\"":                   "dirección en la que servir métricas de Prometheus en /metrics, p. ej. \"localhost:9477\"",
+	"port, or address, to serve runtime profiles on at /debug/pprof/ (a bare port is on localhost)": "puerto, o dirección, en el que servir perfiles de ejecución en /debug/pprof/ (un puerto solo es en localhost)",
+	"Serving profiles on http://%s/debug/pprof/":                                                    "Sirviendo perfiles en http://%s/debug/pprof/",
+	"Serving metrics on http://%s/metrics":                                                          "Sirviendo métricas en http://%s/metrics",
+
+	// Wallet
+	"Back up this file: whoever has it can spend its webcash.":                                  "Haga una copia de seguridad de este archivo: quien lo tenga puede gastar su webcash.",
+	"Balance: %v webcash in %d secrets":                                                         "Saldo: %v webcash en %d secretos",
+	"Created a wallet at %s":                                                                    "Se creó una cartera en %s",
+	"Error: %s already exists":                                                                  "Error: %s ya existe",
+	"Log: %d entries":                                                                           "Registro: %d entradas",
+	"Unconfirmed: %d secrets":                                                                   "Sin confirmar: %d secretos",
+	"Error: failed to add mined webcash to %s: %v":                                              "Error: no se pudo añadir el webcash minado a %s: %v",
+	"Mining into the wallet %s":                                                                 "Minando en la cartera %s",
+	"Error: recovery stopped early: %v":                                                         "Error: la recuperación se detuvo antes de terminar: %v",
+	"Master secret: ":                                                                           "Secreto maestro: ",
+	"Recovered %v webcash in %d secrets, for a balance of %v":                                   "Se recuperaron %v webcash en %d secretos, para un saldo de %v",
+	"Saved what was found so far to %s; run recover again to continue.":                         "Lo encontrado hasta ahora se guardó en %s; ejecute recover de nuevo para continuar.",
+	"Scanning for webcash, stopping each chain after %d unused secrets...":                      "Buscando webcash, deteniendo cada cadena tras %d secretos sin usar...",
+	"Usage: gocash recover [flags] [master secret]":                                             "Uso: gocash recover [opciones] [secreto maestro]",
+	"number of secrets checked per request to the server":                                       "número de secretos comprobados por petición al servidor",
+	"number of unused secrets in a row after which a chain is taken to end":                     "número de secretos seguidos sin usar tras el que se da por terminada una cadena",
+	"%d secrets used, %d unspent found, depth %d":                                               "%d secretos usados, %d sin gastar encontrados, profundidad %d",
+	"Error: that webcash is already in the wallet":                                              "Error: ese webcash ya está en la cartera",
+	"Inserted %v webcash, for a balance of %v":                                                  "Se insertaron %v webcash, para un saldo de %v",
+	"Paid %v webcash, leaving a balance of %v.  Give the recipient this claim code:":            "Se pagaron %v webcash, con un saldo restante de %v.  Entregue al destinatario este código:",
+	"Error: -group must be at least 2":                                                          "Error: -group debe ser al menos 2",
+	"Merged %d secrets worth %v":                                                                "Se fusionaron %d secretos por valor de %v",
+	"The wallet holds %v webcash in %d secrets, from %d before":                                 "La cartera tiene %v webcash en %d secretos, frente a %d antes",
+	"most secrets to merge in one request to the server":                                        "máximo de secretos a fusionar en una petición al servidor",
+	"Encrypted with a passphrase":                                                               "Cifrada con una contraseña",
+	"New passphrase: ":                                                                          "Nueva contraseña: ",
+	"Repeat the passphrase: ":                                                                   "Repita la contraseña: ",
+	"Wallet passphrase: ":                                                                       "Contraseña de la cartera: ",
+	"encrypt the wallet with a passphrase":                                                      "cifrar la cartera con una contraseña",
+	"Error: unable to back up the wallet, so it is unchanged: %v":                               "Error: no se pudo hacer una copia de la cartera, así que no se ha cambiado: %v",
+	"Removed the passphrase of %s":                                                              "Se quitó la contraseña de %s",
+	"Set the passphrase of %s":                                                                  "Se estableció la contraseña de %s",
+	"The previous wallet is kept as %s":                                                         "La cartera anterior se conserva como %s",
+	"The wallet has no passphrase.":                                                             "La cartera no tiene contraseña.",
+	"Warning: its secrets are now stored unencrypted.":                                          "Advertencia: sus secretos se guardan ahora sin cifrar.",
+	"remove the passphrase, leaving the wallet unencrypted":                                     "quitar la contraseña, dejando la cartera sin cifrar",
+	"Error: -encrypt and -sqlite cannot be used together":                                       "Error: -encrypt y -sqlite no pueden usarse juntos",
+	"Error: only wallet files can be encrypted, not %s":                                         "Error: solo los archivos de cartera pueden cifrarse, no %s",
+	"Moved the wallet to %s":                                                                    "La cartera se ha trasladado a %s",
+	"Remove %s, or gocash will keep using it.":                                                  "Elimine %s, o gocash seguirá usándolo.",
+	"keep the wallet in a SQLite database, for wallets of many thousands of secrets":            "guardar la cartera en una base de datos SQLite, para carteras de muchos miles de secretos",
+	"No transactions.":                                                                          "No hay transacciones.",
+	"only show the last n transactions":                                                         "mostrar solo las últimas n transacciones",
+	"only show transactions after this time, RFC 3339 or a duration before now such as \"24h\"": "mostrar solo las transacciones posteriores a este momento, RFC 3339 o una duración antes de ahora como \"24h\"",
+	"  %v: already spent":                                                                       "  %v: ya gastado",
+	"  %v: of no amount on the server":                                                          "  %v: sin importe en el servidor",
+	"  %v: unknown to the server":                                                               "  %v: desconocido para el servidor",
+	"  %v: worth %v on the server":                                                              "  %v: vale %v en el servidor",
+	"%d secrets, holding %v webcash, are not as the wallet says":                                "%d secretos, con %v webcash, no son como dice la cartera",
+	"Verified: every secret is unspent and worth what the wallet says":                          "Verificado: cada secreto está sin gastar y vale lo que dice la cartera",
+	"Verifying %d secrets with the server...":                                                   "Verificando %d secretos con el servidor...",
+	"check every secret with the server":                                                        "comprobar cada secreto con el servidor",
+	"  %v: spent":                                                                               "  %v: gastado",
+	"  %v: unspent":                                                                             "  %v: sin gastar",
+	"%d of %d watched are unspent, holding %v webcash":                                          "%d de %d vigilados están sin gastar, con %v webcash",
+	"Add public webcash to it with `gocash watch add`.":                                         "Añada webcash público con `gocash watch add`.",
+	"Created a watch-only wallet at %s":                                                         "Se creó una cartera de solo vigilancia en %s",
+	"It holds no secrets; use it as the wallet file of the box which watches.":                  "No contiene secretos; úselo como archivo de cartera del equipo que vigila.",
+	"The wallet %s is watch-only, so mining keeps secrets only in the mining log":               "La cartera %s es de solo vigilancia, así que la minería guarda los secretos solo en el registro de minería",
+	"The wallet watches nothing.":                                                               "La cartera no vigila nada.",
+	"Usage: gocash watch [add|check|export]":                                                    "Uso: gocash watch [add|check|export]",
+	"Usage: gocash watch add <public webcash>...":                                               "Uso: gocash watch add <webcash público>...",
+	"Usage: gocash watch export <file>":                                                         "Uso: gocash watch export <archivo>",
+	"Watching %d more, %d in all":                                                               "Vigilando %d más, %d en total",
+	"Wrote a watch-only wallet of %d public webcash to %s":                                      "Se escribió una cartera de solo vigilancia de %d webcash público en %s",
+	"create a watch-only wallet, which holds no secrets":                                        "crear una cartera de solo vigilancia, que no contiene secretos",
+	"Watching %d public webcash":                                                                "Vigilando %d webcash público",
+	"Error: %v: %v":                                                                             "Error: %v: %v",
+	"Error: -wallet: %v":                                                                        "Error: -wallet: %v",
+	"No wallets in %s (create one with `gocash wallet create`)":                                 "No hay carteras en %s (cree una con `gocash wallet create`)",
+	"`name` of the wallet to use, e.g. \"savings\", kept in <name>_wallet.webcash (default: the wallet setting of the config file, or \"default\")": "`nombre` de la cartera que usar, p. ej. \"savings\", guardada en <nombre>_wallet.webcash (por defecto: el ajuste wallet del archivo de configuración, o \"default\")",
+	"Backed up %v webcash in %d secrets to %s": "Se hizo una copia de seguridad de %v webcash en %d secretos en %s",
+	"Backup passphrase: ":                      "Contraseña de la copia de seguridad: ",
+	"Choose a passphrase for the backup.  Without it, the backup cannot be restored.":          "Elija una contraseña para la copia de seguridad.  Sin ella, la copia no puede restaurarse.",
+	"Error: %s already exists; restore as another wallet with -wallet":                         "Error: %s ya existe; restaure como otra cartera con -wallet",
+	"It is encrypted with the backup's passphrase; change it with `gocash wallet passphrase`.": "Está cifrada con la contraseña de la copia de seguridad; cámbiela con `gocash wallet passphrase`.",
+	"Restore it with `gocash wallet restore %s`.":                                              "Restáurela con `gocash wallet restore %s`.",
+	"Restored %v webcash in %d secrets to %s":                                                  "Se restauraron %v webcash en %d secretos en %s",
+	"Usage: gocash wallet backup <file>":                                                       "Uso: gocash wallet backup <archivo>",
+	"Usage: gocash wallet restore <file>":                                                      "Uso: gocash wallet restore <archivo>",
+	"number of previous versions of the wallet file to keep beside it, as <file>.1 and so on":  "número de versiones anteriores del archivo de cartera que conservar junto a él, como <archivo>.1 y así sucesivamente",
+	"if another gocash is using the wallet, wait for it to finish rather than failing":         "si otro gocash está usando la cartera, esperar a que termine en lugar de fallar",
+	"%d secrets, holding %v webcash":                                                           "%d secretos, con %v webcash",
+	"Error: %d secrets in the wallet match %q":                                                 "Error: %d secretos de la cartera coinciden con %q",
+	"Labelled %v %q":                            "Se etiquetó %v como %q",
+	"Removed the label of %v":                   "Se quitó la etiqueta de %v",
+	"Usage: gocash label <public hash> [label]": "Uso: gocash label <hash público> [etiqueta]",
+	"label for the webcash in the wallet, e.g. \"from Alice\" (default: the memo)":                                                                   "etiqueta para el webcash en la cartera, p. ej. \"de Alicia\" (por defecto: la nota)",
+	"only list secrets whose label contains this text, ignoring case":                                                                                "listar solo los secretos cuya etiqueta contiene este texto, sin distinguir mayúsculas",
+	"show the claim codes of the secrets, rather than their public hashes":                                                                           "mostrar los códigos de los secretos, en lugar de sus hashes públicos",
+	"how to choose the webcash to pay with: \"fewest-inputs\", \"minimize-change\", \"oldest-first\", or \"privacy\" to avoid linking mined webcash": "cómo elegir el webcash con el que pagar: \"fewest-inputs\", \"minimize-change\", \"oldest-first\", o \"privacy\" para evitar vincular el webcash minado",
+	"  %s: depth advanced to %d, %d unspent found":                                                                                                   "  %s: profundidad avanzada a %d, %d sin gastar encontrados",
+	"  %v: spent, archived": "  %v: gastado, archivado",
+	"  %v: payment found on the server, left to its recipient":                                                     "  %v: pago encontrado en el servidor, dejado a su destinatario",
+	"If a payment was never handed out, `gocash recover -sweep-payments` takes it back.":                           "Si un pago nunca se entregó, `gocash recover -sweep-payments` lo recupera.",
+	"  %v: unconfirmed output found on the server, now held":                                                       "  %v: salida sin confirmar encontrada en el servidor, ahora en la cartera",
+	"Checked %d secrets, for a balance of %v":                                                                      "Se comprobaron %d secretos, para un saldo de %v",
+	"Checked %d secrets: %d do not match the server, for a balance of %v":                                          "Se comprobaron %d secretos: %d no coinciden con el servidor, para un saldo de %v",
+	"Checking %d secrets with the server...":                                                                       "Comprobando %d secretos con el servidor...",
+	"Error: the check stopped early: %v":                                                                           "Error: la comprobación se detuvo antes de tiempo: %v",
+	"Archived: %d spent secrets":                                                                                   "Archivados: %d secretos gastados",
+	"  %s: depth advanced to %d":                                                                                   "  %s: profundidad avanzada a %d",
+	"Wallet %s, version %s, schema %d":                                                                             "Cartera %s, versión %s, esquema %d",
+	"Usage: gocash wallet [backup|convert|create|info|keychain|list|passphrase|restore]":                           "Uso: gocash wallet [backup|convert|create|info|keychain|list|passphrase|restore]",
+	"keep the master secret in the wallet again, and remove it from the keychain":                                  "volver a guardar el secreto maestro en la cartera, y quitarlo del llavero",
+	"The master secret of %s is not in the keychain.":                                                              "El secreto maestro de %s no está en el llavero.",
+	"Error: unable to remove the master secret from the keychain: %v":                                              "Error: no se pudo quitar el secreto maestro del llavero: %v",
+	"Moved the master secret of %s back from the keychain":                                                         "Se devolvió el secreto maestro de %s desde el llavero",
+	"The master secret of %s is already in the keychain.":                                                          "El secreto maestro de %s ya está en el llavero.",
+	"Moved the master secret of %s into the keychain":                                                              "Se trasladó el secreto maestro de %s al llavero",
+	"The wallet now needs the keychain to derive its secrets; `gocash wallet backup` makes a copy which does not.": "Ahora la cartera necesita el llavero para derivar sus secretos; `gocash wallet backup` hace una copia que no lo necesita.",
+	"Master secret kept in the keychain":                                                                           "Secreto maestro guardado en el llavero",
+	"insert every claim code in `file`, one per line, or \"-\" for stdin":                                          "insertar cada código de `file`, uno por línea, o \"-\" para la entrada estándar",
+	"with -file, the number of claim codes to claim at a time":                                                     "con -file, el número de códigos a reclamar a la vez",
+	"Error: -parallel must be at least 1":                                                                          "Error: -parallel debe ser al menos 1",
+	"  line %d: %v (it may have been claimed; `gocash check` will tell)":                                           "  línea %d: %v (puede que se haya reclamado; `gocash check` lo dirá)",
+	"  line %d: %v":          "  línea %d: %v",
+	"  line %d: inserted %v": "  línea %d: insertado %v",
+	"Inserted %v webcash from %d of %d claim codes, for a balance of %v":                           "Insertados %v webcash de %d de %d códigos, para un saldo de %v",
+	"also show the claim code as a QR code, for a phone to scan":                                   "mostrar también el código como código QR, para escanearlo con un teléfono",
+	"also write the claim code as a QR code to a PNG image at `path`":                              "escribir también el código como código QR en una imagen PNG en `path`",
+	"Wrote the claim code as a QR code to %s":                                                      "Se escribió el código como código QR en %s",
+	"read the claim code from the QR code in the `image`, a PNG, JPEG or GIF":                      "leer el código del código QR de la imagen `image`, PNG, JPEG o GIF",
+	"Usage: gocash insert <claim code|webcash: link> [memo], or gocash insert -file <file> [memo]": "Uso: gocash insert <código|enlace webcash:> [nota], o gocash insert -file <archivo> [nota]",
+	"Usage: gocash pay <amount|webcash: request> [memo]":                                           "Uso: gocash pay <cantidad|solicitud webcash:> [nota]",
+	"give the payment as a webcash: link, with the memo, rather than a bare claim code":            "dar el pago como un enlace webcash:, con la nota, en lugar de un código sin más",
+	"with -uri, how long the link is good for, e.g. \"24h\" (default: forever)":                    "con -uri, cuánto tiempo vale el enlace, p. ej. \"24h\" (por defecto: siempre)",
+	"Paid %v webcash, leaving a balance of %v.  Give the recipient this link:":                     "Se pagaron %v webcash, con un saldo restante de %v.  Entregue al destinatario este enlace:",
+	"how long the request is good for, e.g. \"24h\" (default: forever)":                            "cuánto tiempo vale la solicitud, p. ej. \"24h\" (por defecto: siempre)",
+	"also show the request as a QR code, for a phone to scan":                                      "mostrar también la solicitud como código QR, para escanearla con un teléfono",
+	"Usage: gocash request <amount> [memo]":                                                        "Uso: gocash request <cantidad> [nota]",
+	"write the page to `file` (default: paper-<hash>.html in the current directory)":               "escribir la página en `file` (por defecto: paper-<hash>.html en el directorio actual)",
+	"Usage: gocash paper [-o file] <amount> [memo]":                                                "Uso: gocash paper [-o archivo] <cantidad> [nota]",
+	"paper wallet": "cartera de papel",
+	"Error: unable to write the paper wallet: %v":                           "Error: no se pudo escribir la cartera de papel: %v",
+	"Its claim code, which is kept nowhere else:":                           "Su código, que no se guarda en ningún otro sitio:",
+	"Paid %v webcash into a paper wallet, leaving a balance of %v":          "Se pagaron %v webcash a una cartera de papel, con un saldo restante de %v",
+	"Print %s, then delete it: whoever has the file can spend the webcash.": "Imprima %s y luego bórrelo: quien tenga el archivo puede gastar el webcash.",
+	"Check whether it has been claimed with `gocash watch check`.":          "Compruebe si se ha reclamado con `gocash watch check`.",
+	"Webcash paper wallet": "Cartera de papel de webcash",
+	"Claim code":           "Código",
+	"Public hash":          "Hash público",
+	"Created":              "Creada",
+	"Whoever has the claim code can spend this webcash: keep the paper safe, and claim it with `gocash insert` or another webcash client once it is given to you. Whether it has been claimed can be checked from the public hash, which gives nothing away.": "Quien tenga el código puede gastar este webcash: guarde el papel en un lugar seguro y reclámelo con `gocash insert` u otro cliente de webcash cuando se lo den. Si se ha reclamado puede comprobarse con el hash público, que no revela nada.",
+	"%d payments have not been claimed by their recipients, and were left to them; use -sweep-payments to take them back.":                                                                                                                                    "%d pagos aún no han sido reclamados por sus destinatarios y se les han dejado; use -sweep-payments para recuperarlos.",
+	"also take back payments which their recipients have not claimed yet":      "recuperar también los pagos que sus destinatarios aún no han reclamado",
+	"Run `gocash check` to bring the wallet up to date.":                       "Ejecute `gocash check` para poner la billetera al día.",
+	"Warning: the replacement was made, but the wallet could not be saved: %v": "Advertencia: el reemplazo se realizó, pero no se pudo guardar la billetera: %v",
+
+	// Configuration and signals
+	"Setting %q changed in %s, but only takes effect on restart": "El ajuste %q cambió en %s, pero solo tendrá efecto al reiniciar",
+	"caught SIGHUP, but no config file to reload":                "SIGHUP recibido, pero no hay archivo de configuración que recargar",
+	"caught SIGHUP, reloading %s":                                "SIGHUP recibido, recargando %s",
+	"closing signal handler":                                     "cerrando el manejador de señales",
+	"closing update thread":                                      "cerrando el hilo de actualización",
+	"all goroutines exited":                                      "todas las gorrutinas terminaron",
+
+	// Flag usage
+	"base URL of the webcash server":                                                                                              "URL base del servidor de webcash",
+	"number of mining threads (default: one per CPU, or per CPU in -cpus)":                                                        "número de hilos de minería (por defecto: uno por CPU, o por CPU de -cpus)",
+	"maximum number of CPUs executing simultaneously (default: all)":                                                              "número máximo de CPUs ejecutando simultáneamente (por defecto: todas)",
+	"comma-separated list of CPUs to pin mining threads to, e.g. \"0,2,4-7\"":                                                     "lista de CPUs, separadas por comas, a las que fijar los hilos de minería, p. ej. \"0,2,4-7\"",
+	"maximum number of idle HTTP connections kept open (0 for no limit)":                                                          "número máximo de conexiones HTTP inactivas abiertas (0 para no limitar)",
+	"maximum number of idle HTTP connections kept open per host":                                                                  "número máximo de conexiones HTTP inactivas abiertas por servidor",
+	"maximum number of HTTP connections per host (0 for no limit)":                                                                "número máximo de conexiones HTTP por servidor (0 para no limitar)",
+	"how long an idle HTTP connection is kept open":                                                                               "cuánto tiempo se mantiene abierta una conexión HTTP inactiva",
+	"shortest interval between difficulty checks, used after a change or rejected report":                                         "intervalo mínimo entre consultas de dificultad, usado tras un cambio o un informe rechazado",
+	"longest interval between difficulty checks while nothing is changing":                                                        "intervalo máximo entre consultas de dificultad mientras nada cambia",
+	"number of found solutions which may await submission before mining pauses":                                                   "número de soluciones encontradas que pueden esperar su envío antes de pausar la minería",
+	"soft limit on total memory use, e.g. \"256MiB\" (overrides GOMEMLIMIT)":                                                      "límite flexible del uso total de memoria, p. ej. \"256MiB\" (prevalece sobre GOMEMLIMIT)",
+	"accept the terms of service without prompting":                                                                               "aceptar los términos del servicio sin preguntar",
+	"how often to check the terms of service for changes":                                                                         "cada cuánto comprobar si los términos del servicio han cambiado",
+	"file of \"flag = value\" settings; send SIGHUP to reload (default: gocash.conf in the config directory, if it exists)":       "archivo de ajustes \"opción = valor\"; envíe SIGHUP para recargarlo (por defecto: gocash.conf en el directorio de configuración, si existe)",
+	"language of messages, e.g. \"es\" (default: from LANG)":                                                                      "idioma de los mensajes, p. ej. \"es\" (por defecto: según LANG)",
+	"when mining into a wallet, also write mined claim codes and pending mining reports to the logs in plaintext, as without one": "al minar en una billetera, escribir también en los registros, en texto plano, los códigos de reclamo minados y los informes de minería pendientes, como sin ella",
+}
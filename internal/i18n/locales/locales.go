@@ -0,0 +1,3 @@
+// Package locales registers the translations shipped with gocash.  Import it
+// for its side effects.
+package locales
@@ -0,0 +1,51 @@
+// Package keychain keeps secrets in the platform's store of them: the login
+// keychain on macOS, the Credential Manager on Windows, and the Secret Service
+// (through secret-tool) on Linux and other Unix desktops.  Secrets are kept
+// under the service "gocash", by account.
+package keychain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// The service the secrets are kept under.
+const service = "gocash"
+
+// ErrNotFound is returned by Get when the keychain has no secret for the
+// account.
+var ErrNotFound = errors.New("no such secret in the keychain")
+
+// Get returns the secret kept for account.
+func Get(account string) ([]byte, error) {
+	return get(account)
+}
+
+// Set keeps secret for account, replacing any it had.
+func Set(account string, secret []byte) error {
+	return set(account, secret)
+}
+
+// Delete removes the secret kept for account.  It is not an error if there is
+// none.
+func Delete(account string) error {
+	return remove(account)
+}
+
+// run runs a keychain tool with stdin as its input, returning its output with
+// any error, which includes what the tool said.
+func run(cmd *exec.Cmd, stdin []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if msg := bytes.TrimSpace(stderr.Bytes()); err != nil && len(msg) > 0 {
+		err = fmt.Errorf("%s: %w: %s", cmd.Args[0], err, msg)
+	} else if err != nil {
+		err = fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return stdout.Bytes(), err
+}
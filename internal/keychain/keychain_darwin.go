@@ -0,0 +1,38 @@
+package keychain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// The exit status of security(1) for an item which is not there.
+const errsec_item_not_found = 44
+
+// The secret would be visible to other processes as an argument, so it is
+// added through security's interactive mode instead.  Accounts and secrets are
+// hex, and so need no quoting.
+func set(account string, secret []byte) error {
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", service, account, secret)
+	_, err := run(exec.Command("security", "-i"), []byte(command))
+	return err
+}
+
+func get(account string) ([]byte, error) {
+	out, err := run(exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w"), nil)
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == errsec_item_not_found {
+		return nil, ErrNotFound
+	}
+	return bytes.TrimRight(out, "\n"), err
+}
+
+func remove(account string) error {
+	_, err := run(exec.Command("security", "delete-generic-password", "-s", service, "-a", account), nil)
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == errsec_item_not_found {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,30 @@
+//go:build !darwin && !windows
+
+package keychain
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// secret-tool reads the secret to store from stdin, so that it is not visible
+// to other processes as an argument.
+func set(account string, secret []byte) error {
+	_, err := run(exec.Command("secret-tool", "store", "--label=gocash wallet "+account, "service", service, "account", account), secret)
+	return err
+}
+
+// secret-tool exits with status 1, saying nothing, when there is no secret.
+func get(account string) ([]byte, error) {
+	out, err := run(exec.Command("secret-tool", "lookup", "service", service, "account", account), nil)
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 && len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return out, err
+}
+
+func remove(account string) error {
+	_, err := run(exec.Command("secret-tool", "clear", "service", service, "account", account), nil)
+	return err
+}
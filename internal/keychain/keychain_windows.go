@@ -0,0 +1,96 @@
+package keychain
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32    = syscall.NewLazyDLL("advapi32.dll")
+	cred_write  = advapi32.NewProc("CredWriteW")
+	cred_read   = advapi32.NewProc("CredReadW")
+	cred_delete = advapi32.NewProc("CredDeleteW")
+	cred_free   = advapi32.NewProc("CredFree")
+)
+
+const (
+	cred_type_generic          = 1
+	cred_persist_local_machine = 2
+	error_not_found            = syscall.Errno(1168)
+)
+
+// The CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// target returns the name of the credential kept for account.
+func target(account string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(service + ":" + account)
+}
+
+func set(account string, secret []byte) error {
+	name, err := target(account)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	cred := credential{
+		Type:               cred_type_generic,
+		TargetName:         name,
+		CredentialBlobSize: uint32(len(secret)),
+		Persist:            cred_persist_local_machine,
+		UserName:           user,
+	}
+	if len(secret) > 0 {
+		cred.CredentialBlob = &secret[0]
+	}
+	if r, _, err := cred_write.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return err
+	}
+	return nil
+}
+
+func get(account string) ([]byte, error) {
+	name, err := target(account)
+	if err != nil {
+		return nil, err
+	}
+	var cred *credential
+	r, _, err := cred_read.Call(uintptr(unsafe.Pointer(name)), cred_type_generic, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if err == error_not_found {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer cred_free.Call(uintptr(unsafe.Pointer(cred)))
+	secret := make([]byte, cred.CredentialBlobSize)
+	copy(secret, unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize))
+	return secret, nil
+}
+
+func remove(account string) error {
+	name, err := target(account)
+	if err != nil {
+		return err
+	}
+	if r, _, err := cred_delete.Call(uintptr(unsafe.Pointer(name)), cred_type_generic, 0); r == 0 && err != error_not_found {
+		return err
+	}
+	return nil
+}
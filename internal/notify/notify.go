@@ -0,0 +1,23 @@
+// Package notify shows desktop notifications, using whichever mechanism the
+// platform provides: notify-send on Linux and other Unix desktops, the
+// notification center on macOS, and toasts on Windows.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Send shows a notification with the given title and message.  It waits for
+// the platform's notifier, for at most a few seconds.
+func Send(title, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := platform_command(ctx, title, message).CombinedOutput()
+	if err != nil && len(bytes.TrimSpace(out)) > 0 {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+	return err
+}
@@ -0,0 +1,16 @@
+package notify
+
+import (
+	"context"
+	"os/exec"
+)
+
+// The title and message are passed as arguments to the script, rather than
+// spliced into it, so that they need no quoting.
+func platform_command(ctx context.Context, title, message string) *exec.Cmd {
+	return exec.CommandContext(ctx, "osascript",
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		title, message)
+}
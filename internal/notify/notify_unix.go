@@ -0,0 +1,12 @@
+//go:build !darwin && !windows
+
+package notify
+
+import (
+	"context"
+	"os/exec"
+)
+
+func platform_command(ctx context.Context, title, message string) *exec.Cmd {
+	return exec.CommandContext(ctx, "notify-send", "--app-name=gocash", title, message)
+}
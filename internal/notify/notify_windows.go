@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// The toast is shown under PowerShell's application ID, since an unregistered
+// one is silently ignored.  The title and message reach the script through the
+// environment, and become text nodes, so that they need no quoting.
+const toast_script = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:GOCASH_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:GOCASH_NOTIFY_MESSAGE)) > $null
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show([Windows.UI.Notifications.ToastNotification]::new($xml))
+`
+
+func platform_command(ctx context.Context, title, message string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", toast_script)
+	cmd.Env = append(os.Environ(), "GOCASH_NOTIFY_TITLE="+title, "GOCASH_NOTIFY_MESSAGE="+message)
+	return cmd
+}
@@ -0,0 +1,111 @@
+// Package paths resolves where gocash keeps its files, following the
+// conventions of each platform: the XDG base directories on Linux and other
+// Unix systems, ~/Library on macOS, and AppData on Windows.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The name of the directory created in each of the platform's locations.
+const app_name = "gocash"
+
+// Paths are the directories and files used by gocash.
+type Paths struct {
+	// Settings: the config file and the record of accepted terms.
+	Config string
+	// Wallets, and the log of mined webcash.  Kept private to the user, as
+	// anything here may be worth money.
+	Wallet string
+	// Logs and the stats store.  Also kept private, since logs of rejected
+	// solutions contain secrets.
+	Log string
+	// Data which can be regenerated, and is safe to delete.
+	Cache string
+
+	// The name of the wallet in use, whose file in the wallet directory is
+	// <name>_wallet.webcash, or <name>_wallet.db for SQLite.  Empty means
+	// "default".
+	WalletName string
+}
+
+// The files within the directories.
+func (p Paths) ConfigFile() string { return filepath.Join(p.Config, "gocash.conf") }
+func (p Paths) TermsFile() string  { return filepath.Join(p.Config, "terms.accepted") }
+func (p Paths) WalletFile() string { return filepath.Join(p.Wallet, p.wallet_name()+"_wallet.webcash") }
+func (p Paths) WalletDB() string   { return filepath.Join(p.Wallet, p.wallet_name()+"_wallet.db") }
+func (p Paths) MiningLog() string  { return filepath.Join(p.Wallet, "webcash.log") }
+func (p Paths) PendingLog() string { return filepath.Join(p.Wallet, "pending.log") }
+func (p Paths) OrphanLog() string  { return filepath.Join(p.Log, "orphan.log") }
+func (p Paths) StatsFile() string  { return filepath.Join(p.Log, "stats.log") }
+func (p Paths) EventsFile() string { return filepath.Join(p.Log, "events.jsonl") }
+
+func (p Paths) wallet_name() string {
+	if p.WalletName == "" {
+		return "default"
+	}
+	return p.WalletName
+}
+
+// CheckWalletName checks that a wallet name is usable in a file name: letters,
+// digits, '-' and '_' only.
+func CheckWalletName(name string) error {
+	if name == "" {
+		return errors.New("wallet name is empty")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("invalid wallet name %q: use only letters, digits, '-' and '_'", name)
+		}
+	}
+	return nil
+}
+
+// Default returns the platform's standard locations.  If the GOCASH_HOME
+// environment variable is set, everything is kept in that one directory
+// instead, e.g. GOCASH_HOME=. keeps the files in the working directory.
+func Default() (Paths, error) {
+	if home := os.Getenv("GOCASH_HOME"); home != "" {
+		home, err := filepath.Abs(home)
+		if err != nil {
+			return Paths{}, err
+		}
+		return Paths{Config: home, Wallet: home, Log: home, Cache: filepath.Join(home, "cache")}, nil
+	}
+	return platform_default()
+}
+
+// Create makes any of the directories which do not exist yet.  All but the
+// cache are made accessible only to the user, and existing directories are
+// tightened to match, since they may hold secrets.
+func (p Paths) Create() error {
+	for _, dir := range []struct {
+		path string
+		mode os.FileMode
+	}{
+		{p.Config, 0700},
+		{p.Wallet, 0700},
+		{p.Log, 0700},
+		{p.Cache, 0755},
+	} {
+		if err := os.MkdirAll(dir.path, dir.mode); err != nil {
+			return fmt.Errorf("unable to create %s: %w", dir.path, err)
+		}
+		if err := os.Chmod(dir.path, dir.mode); err != nil {
+			return fmt.Errorf("unable to set permissions of %s: %w", dir.path, err)
+		}
+	}
+	return nil
+}
+
+// home_dir returns the user's home directory, for building defaults.
+func home_dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find home directory (set GOCASH_HOME instead): %w", err)
+	}
+	return home, nil
+}
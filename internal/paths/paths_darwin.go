@@ -0,0 +1,18 @@
+package paths
+
+import "path/filepath"
+
+func platform_default() (Paths, error) {
+	home, err := home_dir()
+	if err != nil {
+		return Paths{}, err
+	}
+	library := filepath.Join(home, "Library")
+	support := filepath.Join(library, "Application Support", app_name)
+	return Paths{
+		Config: support,
+		Wallet: support,
+		Log:    filepath.Join(library, "Logs", app_name),
+		Cache:  filepath.Join(library, "Caches", app_name),
+	}, nil
+}
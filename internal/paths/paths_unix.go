@@ -0,0 +1,30 @@
+//go:build !darwin && !windows
+
+package paths
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// xdg_dir returns the XDG base directory named by the environment variable,
+// or its default within the home directory.
+func xdg_dir(env, home string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, app_name)
+	}
+	return filepath.Join(append(append([]string{home}, fallback...), app_name)...)
+}
+
+func platform_default() (Paths, error) {
+	home, err := home_dir()
+	if err != nil {
+		return Paths{}, err
+	}
+	return Paths{
+		Config: xdg_dir("XDG_CONFIG_HOME", home, ".config"),
+		Wallet: xdg_dir("XDG_DATA_HOME", home, ".local", "share"),
+		Log:    xdg_dir("XDG_STATE_HOME", home, ".local", "state"),
+		Cache:  xdg_dir("XDG_CACHE_HOME", home, ".cache"),
+	}, nil
+}
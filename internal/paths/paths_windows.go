@@ -0,0 +1,31 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Settings and wallets roam with the user's profile; logs and the cache are
+// specific to the machine.
+func platform_default() (Paths, error) {
+	roaming := os.Getenv("APPDATA")
+	local := os.Getenv("LOCALAPPDATA")
+	if roaming == "" || local == "" {
+		home, err := home_dir()
+		if err != nil {
+			return Paths{}, err
+		}
+		if roaming == "" {
+			roaming = filepath.Join(home, "AppData", "Roaming")
+		}
+		if local == "" {
+			local = filepath.Join(home, "AppData", "Local")
+		}
+	}
+	return Paths{
+		Config: filepath.Join(roaming, app_name),
+		Wallet: filepath.Join(roaming, app_name),
+		Log:    filepath.Join(local, app_name, "Logs"),
+		Cache:  filepath.Join(local, app_name, "Cache"),
+	}, nil
+}
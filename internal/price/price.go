@@ -0,0 +1,155 @@
+// Package price values webcash in other currencies, using whichever source of
+// prices the user chooses.
+package price
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// A Quote is the price of one webcash in another currency.
+type Quote struct {
+	// The currency the price is in, e.g. "USD".
+	Currency string
+	// The price of 1 webcash.
+	Price float64
+	// When the price was obtained, and from where.
+	Time   time.Time
+	Source string
+}
+
+// Value returns the worth of amt at the quoted price.
+func (q Quote) Value(amt webcash.Amount) float64 {
+	return float64(amt) / 1_000_000_00 * q.Price
+}
+
+// Format returns the worth of amt as text, e.g. "1.23 USD".
+func (q Quote) Format(amt webcash.Amount) string {
+	return strconv.FormatFloat(q.Value(amt), 'f', 2, 64) + " " + q.Currency
+}
+
+// A Provider is a source of prices.
+type Provider interface {
+	// Name describes the provider, for display alongside its prices.
+	Name() string
+	// Quote fetches the current price.
+	Quote(ctx context.Context) (Quote, error)
+}
+
+// Fixed is a Provider of a price set by the user, for those who would rather
+// value their webcash at a figure of their own than trust any source.
+type Fixed struct {
+	Currency string
+	Price    float64
+}
+
+func (f Fixed) Name() string { return "fixed" }
+
+func (f Fixed) Quote(ctx context.Context) (Quote, error) {
+	return Quote{Currency: f.Currency, Price: f.Price, Time: time.Now(), Source: f.Name()}, nil
+}
+
+// JSON is a Provider which fetches a document from a URL and reads the
+// price from it, for use with any HTTP price API.
+type JSON struct {
+	Currency string
+	URL      string
+	// The path to the price within the document, as object keys and array
+	// indices separated by dots, e.g. "data.0.price".  The price may be a
+	// number or a string.
+	Field string
+	// The HTTP client to use, or nil for http.DefaultClient.
+	Client *http.Client
+}
+
+func (j JSON) Name() string { return j.URL }
+
+func (j JSON) Quote(ctx context.Context) (Quote, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.URL, nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	client := j.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("%s: %s", j.URL, resp.Status)
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return Quote{}, fmt.Errorf("%s: %w", j.URL, err)
+	}
+	for _, key := range strings.Split(j.Field, ".") {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			doc = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return Quote{}, fmt.Errorf("%s: no element %q in array", j.URL, key)
+			}
+			doc = v[i]
+		default:
+			return Quote{}, fmt.Errorf("%s: no field %q", j.URL, j.Field)
+		}
+	}
+	var price float64
+	switch v := doc.(type) {
+	case json.Number:
+		price, err = v.Float64()
+	case string:
+		price, err = strconv.ParseFloat(v, 64)
+	default:
+		err = fmt.Errorf("field %q is not a number", j.Field)
+	}
+	if err != nil {
+		return Quote{}, fmt.Errorf("%s: %w", j.URL, err)
+	}
+	return Quote{Currency: j.Currency, Price: price, Time: time.Now(), Source: j.Name()}, nil
+}
+
+// Parse selects a provider from a specification, as given in the config:
+//
+//	fixed:<currency>:<price>          e.g. fixed:USD:0.0000015
+//	json:<currency>:<field>:<url>     e.g. json:USD:data.price:https://example.com/webcash
+//
+// The empty string selects no provider, and nil is returned.
+func Parse(spec string) (Provider, error) {
+	if spec == "" || spec == "none" {
+		return nil, nil
+	}
+	kind, rest, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "fixed":
+		currency, value, ok := strings.Cut(rest, ":")
+		price, err := strconv.ParseFloat(value, 64)
+		if !ok || currency == "" || err != nil || !(price >= 0) || math.IsInf(price, 1) {
+			return nil, fmt.Errorf("invalid price source %q: expected \"fixed:<currency>:<price>\"", spec)
+		}
+		return Fixed{Currency: currency, Price: price}, nil
+	case "json":
+		parts := strings.SplitN(rest, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || !strings.HasPrefix(parts[2], "http") {
+			return nil, fmt.Errorf("invalid price source %q: expected \"json:<currency>:<field>:<url>\"", spec)
+		}
+		return JSON{Currency: parts[0], Field: parts[1], URL: parts[2]}, nil
+	}
+	return nil, fmt.Errorf("unknown price source %q", spec)
+}
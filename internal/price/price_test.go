@@ -0,0 +1,119 @@
+package price
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Provider
+		err  bool
+	}{
+		{"", nil, false},
+		{"none", nil, false},
+		{"fixed:USD:0.0000015", Fixed{Currency: "USD", Price: 0.0000015}, false},
+		{"fixed:EUR:0", Fixed{Currency: "EUR", Price: 0}, false},
+		{"json:USD:data.price:https://example.com/webcash", JSON{Currency: "USD", Field: "data.price", URL: "https://example.com/webcash"}, false},
+		// The URL keeps its own colons.
+		{"json:USD:0:http://localhost:8080/price?pair=WEB:USD", JSON{Currency: "USD", Field: "0", URL: "http://localhost:8080/price?pair=WEB:USD"}, false},
+		{"fixed", nil, true},
+		{"fixed:USD", nil, true},
+		{"fixed::1", nil, true},
+		{"fixed:USD:cheap", nil, true},
+		{"fixed:USD:-1", nil, true},
+		{"fixed:USD:NaN", nil, true},
+		{"fixed:USD:Inf", nil, true},
+		{"json:USD:price", nil, true},
+		{"json::price:https://example.com", nil, true},
+		{"json:USD::https://example.com", nil, true},
+		{"json:USD:price:example.com", nil, true},
+		{"coingecko:USD", nil, true},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("Parse(%q) = %#v; expected an error", test.spec, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("Parse(%q) = %#v, %v; expected %#v", test.spec, got, err, test.want)
+		}
+	}
+}
+
+func TestJSONQuote(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(r.URL.Query().Get("doc")))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		doc, field string
+		want       float64
+		err        string
+	}{
+		{`{"price": 0.0000015}`, "price", 0.0000015, ""},
+		{`{"data": {"price": "2.5e-6"}}`, "data.price", 0.0000025, ""},
+		{`{"data": [{"price": 1}, {"price": 3}]}`, "data.1.price", 3, ""},
+		{`[12345678901234567890]`, "0", 12345678901234567890, ""},
+		{`{"price": 1}`, "cost", 0, "not a number"},
+		{`{"price": "cheap"}`, "price", 0, "invalid syntax"},
+		{`{"price": [1]}`, "price", 0, "not a number"},
+		{`{"data": [1]}`, "data.1", 0, "no element \"1\""},
+		{`{"data": [1]}`, "data.x", 0, "no element \"x\""},
+		{`{"data": 1}`, "data.price", 0, "no field"},
+		{`not JSON`, "price", 0, "invalid character"},
+	}
+	for _, test := range tests {
+		j := JSON{Currency: "USD", Field: test.field, URL: ts.URL + "/?doc=" + url.QueryEscape(test.doc), Client: ts.Client()}
+		q, err := j.Quote(context.Background())
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s from %s: Quote = %v, %v; expected an error containing %q", test.field, test.doc, q.Price, err, test.err)
+			}
+			continue
+		}
+		if err != nil || q.Price != test.want || q.Currency != "USD" || q.Source != j.URL || q.Time.IsZero() {
+			t.Errorf("%s from %s: Quote = %+v, %v; expected the price %v", test.field, test.doc, q, err, test.want)
+		}
+	}
+
+	j := JSON{Currency: "USD", Field: "price", URL: ts.URL + "/missing", Client: ts.Client()}
+	if _, err := j.Quote(context.Background()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Quote of a missing document = %v, expected a 404", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	q := Quote{Currency: "USD", Price: 0.5}
+	tests := []struct {
+		amt  webcash.Amount
+		want string
+	}{
+		{0, "0.00 USD"},
+		{1_000_000_00, "0.50 USD"},
+		{3_000_000_00, "1.50 USD"},
+		{1_234_567_89, "0.62 USD"},
+		{1, "0.00 USD"},
+		{2_000_000_000_000_00, "1000000.00 USD"},
+	}
+	for _, test := range tests {
+		if got := q.Format(test.amt); got != test.want {
+			t.Errorf("Format(%v) = %q, expected %q", test.amt, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math/bits"
+)
+
+// ErrNotFound is returned by Decode for an image in which no QR code could be
+// read.
+var ErrNotFound = errors.New("no readable QR code in the image")
+
+// Decode reads the QR code in an image.  The image must be a clean, upright
+// rendering of the code, such as a screenshot or one from Image, on a light
+// background: photos, which are skewed or rotated, are not supported.  Errors
+// are detected but not corrected, so a damaged code fails to decode.
+func Decode(img image.Image) (string, error) {
+	c, err := sample(img)
+	if err != nil {
+		return "", err
+	}
+	version := (c.Size - 17) / 4
+	mask, err := c.read_format()
+	if err != nil {
+		return "", err
+	}
+	expected := new_code(version)
+	c.function = expected.function
+	c.apply_mask(mask)
+	var codewords bit_buffer
+	c.data_modules(func(x, y int) {
+		codewords = append(codewords, c.modules[y][x])
+	})
+	data, err := deinterleave(version, codewords.bytes())
+	if err != nil {
+		return "", err
+	}
+	return parse_segment(version, data)
+}
+
+// sample finds the code in an image by the extent of its dark pixels, and
+// reads each of its modules from the pixel at its centre.
+func sample(img image.Image) (*Code, error) {
+	bounds := img.Bounds()
+	dark := func(x, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return (r+g+b)/3 < 0x8000
+	}
+	left, top, right, bottom := bounds.Max.X, bounds.Max.Y, bounds.Min.X-1, bounds.Min.Y-1
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if dark(x, y) {
+				left, top = min(left, x), min(top, y)
+				right, bottom = max(right, x), max(bottom, y)
+			}
+		}
+	}
+	if right < left {
+		return nil, ErrNotFound
+	}
+	// The top row of the top left finder pattern is 7 modules of dark.
+	run := 0
+	for x := left; x <= right && dark(x, top); x++ {
+		run++
+	}
+	module := float64(run) / 7
+	width, height := float64(right-left+1), float64(bottom-top+1)
+	size := int(width/module + 0.5)
+	if module < 1 || size < 21 || (size-17)%4 != 0 || (size-17)/4 > max_version || abs(int(height/module+0.5)-size) > 0 {
+		return nil, ErrNotFound
+	}
+	module = width / float64(size)
+	c := &Code{Size: size, modules: make([][]bool, size)}
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+		for x := range c.modules[y] {
+			c.modules[y][x] = dark(left+int((float64(x)+0.5)*module), top+int((float64(y)+0.5)*module))
+		}
+	}
+	return c, nil
+}
+
+// read_format returns the mask of the code from its format information, which
+// is read from whichever copy is closest to valid.
+func (c *Code) read_format() (int, error) {
+	var first, second uint
+	bit := func(x, y int) uint {
+		if c.modules[y][x] {
+			return 1
+		}
+		return 0
+	}
+	for i := 0; i <= 5; i++ {
+		first |= bit(8, i) << i
+	}
+	first |= bit(8, 7)<<6 | bit(8, 8)<<7 | bit(7, 8)<<8
+	for i := 9; i < 15; i++ {
+		first |= bit(14-i, 8) << i
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(c.Size-1-i, 8) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(8, c.Size-15+i) << i
+	}
+	// Format information can be told apart with up to 3 errors.
+	for level := 0; level < 4; level++ {
+		for mask := 0; mask < 8; mask++ {
+			data := uint(level<<3 | mask)
+			rem := data
+			for i := 0; i < 10; i++ {
+				rem = rem<<1 ^ (rem>>9)*0x537
+			}
+			want := (data<<10 | rem) ^ 0x5412
+			if bits.OnesCount(want^first) <= 3 || bits.OnesCount(want^second) <= 3 {
+				if level != level_m {
+					return 0, errors.New("QR code uses an error correction level other than M, which is not supported")
+				}
+				return mask, nil
+			}
+		}
+	}
+	return 0, ErrNotFound
+}
+
+// deinterleave undoes interleave, checking each block against its error
+// correction.
+func deinterleave(version int, codewords []byte) ([]byte, error) {
+	layout := layouts[version]
+	blocks := make([][]byte, len(layout.blocks))
+	i := 0
+	longest := layout.blocks[len(layout.blocks)-1]
+	for j := 0; j < longest+layout.ec; j++ {
+		for b, n := range layout.blocks {
+			if j < n || j >= longest {
+				blocks[b] = append(blocks[b], codewords[i])
+				i++
+			}
+		}
+	}
+	divisor := rs_divisor(layout.ec)
+	var data []byte
+	for b, n := range layout.blocks {
+		block := blocks[b]
+		ec := rs_remainder(block[:n], divisor)
+		if string(ec) != string(block[n:]) {
+			return nil, errors.New("QR code is damaged, and cannot be read")
+		}
+		data = append(data, block[:n]...)
+	}
+	return data, nil
+}
+
+// parse_segment reads the byte mode segment which holds the text.
+func parse_segment(version int, data []byte) (string, error) {
+	pos := 0
+	read := func(n int) uint {
+		var v uint
+		for i := 0; i < n; i++ {
+			if pos < 8*len(data) {
+				v = v<<1 | uint(data[pos/8]>>(7-pos%8)&1)
+			}
+			pos++
+		}
+		return v
+	}
+	if mode := read(4); mode != 0x4 {
+		return "", fmt.Errorf("QR code uses mode %d, where only byte mode (4) is supported", mode)
+	}
+	n := int(read(count_bits(version)))
+	if 4+count_bits(version)+8*n > 8*len(data) {
+		return "", errors.New("QR code is damaged, and cannot be read")
+	}
+	text := make([]byte, n)
+	for i := range text {
+		text[i] = byte(read(8))
+	}
+	return string(text), nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
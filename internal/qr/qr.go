@@ -0,0 +1,485 @@
+// Package qr encodes text as QR codes, for claim codes to be scanned by a phone
+// rather than copied by hand, and reads back QR codes from clean images, such
+// as screenshots or those the package renders.
+//
+// Only what claim codes need is implemented: byte mode, error correction
+// level M, and versions 1 through 10, which hold up to 213 bytes.
+package qr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooLong is returned for text which does not fit in a version 10 code.
+var ErrTooLong = errors.New("too long for a QR code")
+
+// A Code is a QR code: a square of dark and light modules.
+type Code struct {
+	// The number of modules along each side.
+	Size int
+	// The modules, by row and then column.
+	modules [][]bool
+	// Which modules are function patterns rather than data.
+	function [][]bool
+}
+
+// Dark reports whether the module in row y, column x is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// The error correction blocks of a version: the number of error correction
+// codewords per block, and the number of data codewords in each block, the
+// short blocks first.
+type block_layout struct {
+	ec     int
+	blocks []int
+}
+
+// The layouts at level M, by version.
+var layouts = [...]block_layout{
+	1:  {10, []int{16}},
+	2:  {16, []int{28}},
+	3:  {26, []int{44}},
+	4:  {18, []int{32, 32}},
+	5:  {24, []int{43, 43}},
+	6:  {16, []int{27, 27, 27, 27}},
+	7:  {18, []int{31, 31, 31, 31}},
+	8:  {22, []int{38, 38, 39, 39}},
+	9:  {22, []int{36, 36, 36, 37, 37}},
+	10: {26, []int{43, 43, 43, 43, 44}},
+}
+
+const max_version = len(layouts) - 1
+
+// The centres of the alignment patterns along each axis, by version.
+var alignments = [...][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// The error correction level bits of level M, as in the format information.
+const level_m = 0
+
+// data_capacity returns the number of data codewords of a version.
+func data_capacity(version int) int {
+	n := 0
+	for _, b := range layouts[version].blocks {
+		n += b
+	}
+	return n
+}
+
+// count_bits returns the width of the byte mode character count.
+func count_bits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// Encode returns the smallest QR code holding text.
+func Encode(text string) (*Code, error) {
+	version := 1
+	for ; version <= max_version; version++ {
+		if 4+count_bits(version)+8*len(text) <= 8*data_capacity(version) {
+			break
+		}
+	}
+	if version > max_version {
+		return nil, fmt.Errorf("%d bytes is %w", len(text), ErrTooLong)
+	}
+
+	// The single byte mode segment, with its terminator and padding.
+	var bits bit_buffer
+	bits.append(0x4, 4)
+	bits.append(uint(len(text)), count_bits(version))
+	for i := 0; i < len(text); i++ {
+		bits.append(uint(text[i]), 8)
+	}
+	capacity := 8 * data_capacity(version)
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	for pad := uint(0xEC); len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	c := new_code(version)
+	c.draw_codewords(interleave(version, bits.bytes()))
+	best, lowest := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.apply_mask(mask)
+		c.draw_format(mask)
+		if p := c.penalty(); lowest < 0 || p < lowest {
+			best, lowest = mask, p
+		}
+		c.apply_mask(mask)
+	}
+	c.apply_mask(best)
+	c.draw_format(best)
+	return c, nil
+}
+
+// A bit_buffer is a sequence of bits, most significant first.
+type bit_buffer []bool
+
+func (b *bit_buffer) append(v uint, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>i&1 != 0)
+	}
+}
+
+func (b bit_buffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// interleave splits the data codewords into the version's blocks, adds their
+// error correction, and interleaves them all as they are laid out.
+func interleave(version int, data []byte) []byte {
+	layout := layouts[version]
+	divisor := rs_divisor(layout.ec)
+	var blocks, ecs [][]byte
+	for _, n := range layout.blocks {
+		blocks = append(blocks, data[:n])
+		ecs = append(ecs, rs_remainder(data[:n], divisor))
+		data = data[n:]
+	}
+	var out []byte
+	longest := layout.blocks[len(layout.blocks)-1]
+	for i := 0; i < longest; i++ {
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ec; i++ {
+		for _, ec := range ecs {
+			out = append(out, ec[i])
+		}
+	}
+	return out
+}
+
+// new_code returns a code of the given version with its function patterns
+// drawn, and the format information reserved.
+func new_code(version int) *Code {
+	size := 17 + 4*version
+	c := &Code{Size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+		c.function[y] = make([]bool, size)
+	}
+	for i := 0; i < size; i++ {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	c.draw_finder(3, 3)
+	c.draw_finder(size-4, 3)
+	c.draw_finder(3, size-4)
+	centres := alignments[version]
+	for i, x := range centres {
+		for j, y := range centres {
+			last := len(centres) - 1
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			c.draw_alignment(x, y)
+		}
+	}
+	c.draw_format(0)
+	c.draw_version(version)
+	return c
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+// draw_finder draws a finder pattern and its separator centred on x, y.
+func (c *Code) draw_finder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			if x+dx < 0 || x+dx >= c.Size || y+dy < 0 || y+dy >= c.Size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			c.set(x+dx, y+dy, d != 2 && d != 4)
+		}
+	}
+}
+
+// draw_alignment draws an alignment pattern centred on x, y.
+func (c *Code) draw_alignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// format_bits returns the format information for a mask, with its error
+// correction.
+func format_bits(mask int) uint {
+	data := uint(level_m<<3 | mask)
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// draw_format draws both copies of the format information, and the dark
+// module beside them.
+func (c *Code) draw_format(mask int) {
+	bits := format_bits(mask)
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		c.set(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i))
+	}
+	c.set(8, c.Size-8, true)
+}
+
+// draw_version draws both copies of the version information, which versions
+// 7 and up have.
+func (c *Code) draw_version(version int) {
+	if version < 7 {
+		return
+	}
+	rem := uint(version)
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := uint(version)<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := bits>>i&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.set(a, b, dark)
+		c.set(b, a, dark)
+	}
+}
+
+// data_modules calls f with each module of data, in the order of the bits
+// laid out in them: upwards and downwards in pairs of columns from the right,
+// skipping the vertical timing pattern.
+func (c *Code) data_modules(f func(x, y int)) {
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				if x := right - j; !c.function[y][x] {
+					f(x, y)
+				}
+			}
+		}
+	}
+}
+
+func (c *Code) draw_codewords(data []byte) {
+	i := 0
+	c.data_modules(func(x, y int) {
+		if i < 8*len(data) {
+			c.modules[y][x] = data[i/8]>>(7-i%8)&1 != 0
+		}
+		i++
+	})
+}
+
+// masked reports whether a mask inverts the module at x, y.
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// apply_mask inverts the data modules a mask selects; applying it again
+// undoes it.
+func (c *Code) apply_mask(mask int) {
+	c.data_modules(func(x, y int) {
+		if masked(mask, x, y) {
+			c.modules[y][x] = !c.modules[y][x]
+		}
+	})
+}
+
+// penalty scores how hard the code would be to scan, by the rules of the
+// standard, for choosing a mask.
+func (c *Code) penalty() int {
+	score := 0
+	dark := 0
+	// Runs of five or more modules of a colour, and patterns which look like
+	// part of a finder, along rows and columns.
+	finder := []bool{true, false, true, true, true, false, true}
+	for _, across := range []bool{true, false} {
+		at := func(i, j int) bool {
+			if across {
+				return c.modules[i][j]
+			}
+			return c.modules[j][i]
+		}
+		for i := 0; i < c.Size; i++ {
+			run := 0
+			for j := 0; j < c.Size; j++ {
+				if j > 0 && at(i, j) == at(i, j-1) {
+					run++
+				} else {
+					run = 1
+				}
+				if run == 5 {
+					score += 3
+				} else if run > 5 {
+					score++
+				}
+				if j+len(finder) > c.Size {
+					continue
+				}
+				matches := true
+				for k, want := range finder {
+					matches = matches && at(i, j+k) == want
+				}
+				if matches && (light_run(at, c.Size, i, j-4, j) || light_run(at, c.Size, i, j+7, j+11)) {
+					score += 40
+				}
+			}
+		}
+	}
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				m := c.modules[y][x]
+				if m == c.modules[y-1][x] && m == c.modules[y][x-1] && m == c.modules[y-1][x-1] {
+					score += 3
+				}
+			}
+		}
+	}
+	// The balance of dark and light, in steps of 5% from even.
+	total := c.Size * c.Size
+	if k := (abs(dark*20-total*10)+total-1)/total - 1; k > 0 {
+		score += k * 10
+	}
+	return score
+}
+
+// light_run reports whether the modules from j to k of line i are all light,
+// counting those beyond the edge, which is the quiet zone.
+func light_run(at func(i, j int) bool, size, i, j, k int) bool {
+	for ; j < k; j++ {
+		if j >= 0 && j < size && at(i, j) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// gf_multiply multiplies in GF(2^8), modulo the polynomial the standard uses.
+func gf_multiply(x, y byte) byte {
+	var z uint
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= uint(y>>i&1) * uint(x)
+	}
+	return byte(z)
+}
+
+// rs_divisor returns the Reed-Solomon generator polynomial of a degree, its
+// leading coefficient left out.
+func rs_divisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gf_multiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gf_multiply(root, 2)
+	}
+	return result
+}
+
+// rs_remainder returns the error correction codewords of data.
+func rs_remainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gf_multiply(coef, factor)
+		}
+	}
+	return result
+}
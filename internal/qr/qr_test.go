@@ -0,0 +1,236 @@
+package qr
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	chars := strings.Repeat("webcash:e0.5:secret:0123456789abcdef?memo=lunch&", 5)
+	for version := 1; version <= max_version; version++ {
+		// The shortest and longest texts of each version, and one between.
+		longest := (8*data_capacity(version) - 4 - count_bits(version)) / 8
+		shortest := 1
+		if version > 1 {
+			shortest = (8*data_capacity(version-1)-4-count_bits(version-1))/8 + 1
+		}
+		for _, n := range []int{shortest, (shortest + longest) / 2, longest} {
+			text := chars[:n]
+			code, err := Encode(text)
+			if err != nil {
+				t.Fatalf("Encode(%d bytes): %v", n, err)
+			}
+			if want := 17 + 4*version; code.Size != want {
+				t.Errorf("Encode(%d bytes) has size %d, want %d for version %d", n, code.Size, want, version)
+			}
+			for _, scale := range []int{1, 3, 8} {
+				got, err := Decode(code.Image(scale))
+				if err != nil {
+					t.Errorf("Decode(%d bytes, scale %d): %v", n, scale, err)
+				} else if got != text {
+					t.Errorf("Decode(%d bytes, scale %d) = %q, want %q", n, scale, got, text)
+				}
+			}
+		}
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	var b []byte
+	for i := 0; i < 200; i++ {
+		b = append(b, byte(255-i))
+	}
+	code, err := Encode(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(code.Image(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(b) {
+		t.Errorf("Decode = %x, want %x", got, b)
+	}
+}
+
+func TestTooLong(t *testing.T) {
+	longest := (8*data_capacity(max_version) - 4 - count_bits(max_version)) / 8
+	if _, err := Encode(strings.Repeat("x", longest)); err != nil {
+		t.Errorf("Encode(%d bytes): %v", longest, err)
+	}
+	if _, err := Encode(strings.Repeat("x", longest+1)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode(%d bytes) = %v, want ErrTooLong", longest+1, err)
+	}
+}
+
+// The reference codes were made by github.com/skip2/go-qrcode at level M,
+// without a border.  Both its choice of mask and ours, by penalty, agree for
+// these texts.
+var references = []struct {
+	text string
+	rows []string
+}{
+	{"webcash:e1:secret:", []string{
+		"#######..##.....#.#######",
+		"#.....#.#...##....#.....#",
+		"#.###.#.#.###..##.#.###.#",
+		"#.###.#.#.#...#...#.###.#",
+		"#.###.#..#####..#.#.###.#",
+		"#.....#...##.##.#.#.....#",
+		"#######.#.#.#.#.#.#######",
+		"........#.#..#..#........",
+		"#.....#.####..##.##..###.",
+		".#####.#..##..##.#..#.#..",
+		"...#..#..#.#...#..##.####",
+		"##.#...###.#..##.###.#.##",
+		"#...###.##...###.###.#..#",
+		"#.####...##.######.#.....",
+		"#.#...#.###..####...#.###",
+		"#.####......#####....###.",
+		"#.#.#.##..#.#.###########",
+		"........#.......#...#..#.",
+		"#######...#.###.#.#.#.#.#",
+		"#.....#....#..###...##...",
+		"#.###.#..#.#...######.#.#",
+		"#.###.#..#..##.#####..###",
+		"#.###.#..##..##..#....#.#",
+		"#.....#..#..##..#.#..#..#",
+		"#######.#..###..#..#.#..#",
+	}},
+	{"webcash:e0.5:secret:8e2c4e6f0b0b9a3d5b9cfe2a2c0e7f1a3b4c5d6e7f8091a2b3c4d5e6f708192a?memo=thanks+for+the+lunch+yesterday", []string{
+		"#######..#..#...#..#...#.....####...#.#######",
+		"#.....#.....####.#..##..####.......#..#.....#",
+		"#.###.#.#.#####..###...#..#.#.####.#..#.###.#",
+		"#.###.#.#..##....######.##.#...#...##.#.###.#",
+		"#.###.#.#....#.###..######.####...###.#.###.#",
+		"#.....#.##..#.####..#...#.#....##.....#.....#",
+		"#######.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#######",
+		"........##......#...#...#.#.#####............",
+		"#.#####..#..#.#.##.######.##..........#####..",
+		"######.###....#...##..##...#.###.#.###....#.#",
+		"#######..##....#..#....####....#..##.###...#.",
+		".#...#..###....#..#....##...######..##.##.###",
+		"#.#..###.##..###.###.#####...##...#......#.#.",
+		"#..###.###...####...##...#..####...##...#...#",
+		"...####.#.###.###########.###....########.##.",
+		".#.##...##.##.#####....#..#.######.....##.##.",
+		"....###....###....######.#.#.###..#..#...#...",
+		"#.#.....#.#..#..##.##..#...#.##.##.##....#.##",
+		"#....##..###.#.#.#####..#####...#.#..###.....",
+		"...#.#..##.#..###.#......#.##.#.#....#.####.#",
+		"..########...#.####.######...#.#.#..#####..#.",
+		"....#...####.##.....#...##..#.###..##...###.#",
+		".##.#.#.###.#.#.#.###.#.###..#..###.#.#.####.",
+		".#.##...#..##.#####.#...#.#.###.##..#...###..",
+		".#..############..#.#####......#.#..#####....",
+		".##..#....#####..######.##....##.#.##.#....##",
+		"##.#..#.##....##..#..#....#..#.#.##..#..####.",
+		".#..#.....#.##.#.#.##.###..###..#..##.##.##..",
+		"#.##..###...##.###..##...#.#...#.##.#..##...#",
+		".#...#..##..#.#.#..##.#.#..#.##.#..####...#.#",
+		"###.###.##....#.###.#....####....##..#..#.##.",
+		"####...#.#....#####....##...#.###..##.##..#..",
+		"..###.#.....#....#......#.##.#...#..##.###..#",
+		"..#....###...####..#######.#.###...###.#....#",
+		"....#.###.####.##...#..#..##.#.#.##..#..##.#.",
+		".####....###.....##.##..#...#.#.#####.##.####",
+		"#..##.#########...##########..#..#..######.#.",
+		"........#..#..#####.#...##...###....#...#.###",
+		"#######..#..#.#.#.###.#.###....#.##.#.#.#....",
+		"#.....#.#.#...##..###...##.######.#.#...###.#",
+		"#.###.#.###.##.#....######...#...########....",
+		"#.###.#.#....#.#...#...##..##.#..#.#....#.###",
+		"#.###.#.#.####.#.###.##.####.#.#..###.##.###.",
+		"#.....#..####..######......##.###..#.#....#..",
+		"#######.###...##..#....####..#.....#..####.#.",
+	}},
+}
+
+// reference_image renders rows of a reference code as Image does.
+func reference_image(rows []string, scale int) image.Image {
+	c := &Code{Size: len(rows), modules: make([][]bool, len(rows))}
+	for y, row := range rows {
+		c.modules[y] = make([]bool, len(row))
+		for x := range row {
+			c.modules[y][x] = row[x] == '#'
+		}
+	}
+	return c.Image(scale)
+}
+
+func TestReference(t *testing.T) {
+	for _, r := range references {
+		code, err := Encode(r.text)
+		if err != nil {
+			t.Fatalf("Encode(%q): %v", r.text, err)
+		}
+		if code.Size != len(r.rows) {
+			t.Fatalf("Encode(%q) has size %d, want %d", r.text, code.Size, len(r.rows))
+		}
+		for y, row := range r.rows {
+			for x := range row {
+				if code.Dark(x, y) != (row[x] == '#') {
+					t.Errorf("Encode(%q): module %d, %d differs from the reference", r.text, x, y)
+				}
+			}
+		}
+		got, err := Decode(reference_image(r.rows, 4))
+		if err != nil {
+			t.Errorf("Decode(reference for %q): %v", r.text, err)
+		} else if got != r.text {
+			t.Errorf("Decode(reference) = %q, want %q", got, r.text)
+		}
+	}
+}
+
+// The examples of ISO/IEC 18004, at version 1, level M: the data codewords of
+// "01234567" in numeric mode and of "HELLO WORLD" in alphanumeric mode, and
+// their error correction.
+func TestErrorCorrection(t *testing.T) {
+	tests := []struct {
+		data, ec []byte
+	}{
+		{
+			[]byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11},
+			[]byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55},
+		},
+		{
+			[]byte{0x20, 0x5B, 0x0B, 0x78, 0xD1, 0x72, 0xDC, 0x4D, 0x43, 0x40, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11},
+			[]byte{0xC4, 0x23, 0x27, 0x77, 0xEB, 0xD7, 0xE7, 0xE2, 0x5D, 0x17},
+		},
+	}
+	for _, test := range tests {
+		if got := rs_remainder(test.data, rs_divisor(10)); string(got) != string(test.ec) {
+			t.Errorf("rs_remainder(% x) = % x, want % x", test.data, got, test.ec)
+		}
+	}
+}
+
+// The format information of level M, by mask, from the table of the
+// standard.
+func TestFormatBits(t *testing.T) {
+	want := []uint{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+	for mask, w := range want {
+		if got := format_bits(mask); got != w {
+			t.Errorf("format_bits(%d) = %#x, want %#x", mask, got, w)
+		}
+	}
+}
+
+func TestDecodeDamaged(t *testing.T) {
+	code, err := Encode("webcash:e1:secret:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a data module, far from the format information.
+	x, y := code.Size-1, code.Size-1
+	code.modules[y][x] = !code.modules[y][x]
+	if _, err := Decode(code.Image(4)); err == nil {
+		t.Error("Decode of a damaged code succeeded")
+	}
+	if _, err := Decode(image.NewGray(image.Rect(0, 0, 100, 100))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Decode(blank) = %v, want ErrNotFound", err)
+	}
+}
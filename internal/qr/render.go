@@ -0,0 +1,57 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// The width of the light border around a code, in modules, which scanners
+// need to find it.
+const quiet_zone = 4
+
+// dark_at is Dark, but light beyond the edges, in the quiet zone.
+func (c *Code) dark_at(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y][x]
+}
+
+// Terminal renders the code for a terminal, with its quiet zone, two rows of
+// modules to a line of half blocks.  The colours are set explicitly, black on
+// white, so that the code scans whatever the terminal's colours are.
+func (c *Code) Terminal() string {
+	var b strings.Builder
+	for y := -quiet_zone; y < c.Size+quiet_zone; y += 2 {
+		b.WriteString("\x1b[30;47m")
+		for x := -quiet_zone; x < c.Size+quiet_zone; x++ {
+			switch top, bottom := c.dark_at(x, y), c.dark_at(x, y+1); {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String()
+}
+
+// Image renders the code as an image, scale pixels to a module, with its
+// quiet zone.
+func (c *Code) Image(scale int) image.Image {
+	side := (c.Size + 2*quiet_zone) * scale
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	for py := 0; py < side; py++ {
+		for px := 0; px < side; px++ {
+			v := color.Gray{Y: 0xFF}
+			if c.dark_at(px/scale-quiet_zone, py/scale-quiet_zone) {
+				v.Y = 0
+			}
+			img.SetGray(px, py, v)
+		}
+	}
+	return img
+}
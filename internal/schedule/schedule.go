@@ -0,0 +1,86 @@
+// Package schedule decides when mining should run: within configured windows
+// of the day, and optionally only while the rest of the machine is idle.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Window is a daily period of local time, from Start up to End, given as
+// offsets since midnight.  A window whose end is before its start runs past
+// midnight, e.g. 22:00-07:00.
+type Window struct {
+	Start, End time.Duration
+}
+
+// Contains reports whether t falls within the window.
+func (w Window) Contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// A Schedule is a set of windows.  The empty schedule is always active.
+type Schedule []Window
+
+// Active reports whether t falls within any of the schedule's windows.
+func (s Schedule) Active(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, w := range s {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses a comma-separated list of windows in 24-hour local time, e.g.
+// "22:00-07:00" or "00:00-08:00,12:00-13:00".  The empty string is the empty
+// schedule.
+func Parse(spec string) (Schedule, error) {
+	var s Schedule
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		start, end, ok := strings.Cut(field, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid window %q: expected a range such as \"22:00-07:00\"", field)
+		}
+		var w Window
+		var err error
+		if w.Start, err = parse_clock(start); err != nil {
+			return nil, err
+		}
+		if w.End, err = parse_clock(end); err != nil {
+			return nil, err
+		}
+		if w.Start == w.End {
+			return nil, fmt.Errorf("invalid window %q: it is empty", field)
+		}
+		s = append(s, w)
+	}
+	return s, nil
+}
+
+// parse_clock parses a time of day as HH:MM, returning it as an offset since
+// midnight.  24:00 is allowed, as the end of a window.
+func parse_clock(clock string) (time.Duration, error) {
+	clock = strings.TrimSpace(clock)
+	h, m, ok := strings.Cut(clock, ":")
+	hours, herr := strconv.ParseUint(h, 10, 8)
+	minutes, merr := strconv.ParseUint(m, 10, 8)
+	if !ok || herr != nil || merr != nil || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, fmt.Errorf("invalid time of day %q: expected HH:MM", clock)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
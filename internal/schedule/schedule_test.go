@@ -0,0 +1,78 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Schedule
+		err  bool
+	}{
+		{"", nil, false},
+		{" , ", nil, false},
+		{"22:00-07:00", Schedule{{22 * time.Hour, 7 * time.Hour}}, false},
+		{"00:00-08:00,12:00-13:30", Schedule{{0, 8 * time.Hour}, {12 * time.Hour, 13*time.Hour + 30*time.Minute}}, false},
+		{" 9:05 - 17:00 ,", Schedule{{9*time.Hour + 5*time.Minute, 17 * time.Hour}}, false},
+		{"00:00-24:00", Schedule{{0, 24 * time.Hour}}, false},
+		{"22:00", nil, true},
+		{"22:00-22:00", nil, true},
+		{"22:00-07:00,bad", nil, true},
+		{"24:01-07:00", nil, true},
+		{"25:00-07:00", nil, true},
+		{"12:60-13:00", nil, true},
+		{"-1:00-07:00", nil, true},
+		{"12-13", nil, true},
+		{"12:00x-13:00", nil, true},
+		{"12:00-13:00:00", nil, true},
+		{"noon-13:00", nil, true},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v; expected an error", test.spec, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%q) = %v, %v; expected %v", test.spec, got, err, test.want)
+		}
+	}
+}
+
+func TestActive(t *testing.T) {
+	s, err := Parse("22:00-07:00,12:00-13:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 1, hour, minute, 0, 0, time.Local)
+	}
+	tests := []struct {
+		t    time.Time
+		want bool
+	}{
+		// A window includes its start but not its end.
+		{at(22, 0), true},
+		{at(23, 59), true},
+		{at(0, 0), true},
+		{at(6, 59), true},
+		{at(7, 0), false},
+		{at(11, 59), false},
+		{at(12, 0), true},
+		{at(13, 0), false},
+		{at(21, 59), false},
+	}
+	for _, test := range tests {
+		if got := s.Active(test.t); got != test.want {
+			t.Errorf("Active(%s) = %v, expected %v", test.t.Format("15:04"), got, test.want)
+		}
+	}
+	if !(Schedule{}).Active(at(3, 0)) {
+		t.Error("the empty schedule is not active")
+	}
+}
@@ -0,0 +1,98 @@
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A UsageMonitor measures how much of the CPU other programs are using, so
+// that mining can give way to them.  The miner's own use is excluded, since
+// otherwise mining would always look like a busy machine.
+type UsageMonitor struct {
+	busy, total, self uint64
+}
+
+// NewUsageMonitor returns a monitor whose first Sample measures from now.
+func NewUsageMonitor() (*UsageMonitor, error) {
+	m := new(UsageMonitor)
+	if _, err := m.Sample(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Sample returns the fraction of the machine's CPU time, from 0 to 1, used by
+// other processes since the previous sample.
+func (m *UsageMonitor) Sample() (float64, error) {
+	busy, total, err := read_system_times()
+	if err != nil {
+		return 0, err
+	}
+	self, err := read_self_time()
+	if err != nil {
+		return 0, err
+	}
+	d_busy, d_total, d_self := busy-m.busy, total-m.total, self-m.self
+	m.busy, m.total, m.self = busy, total, self
+	if d_total == 0 || d_busy < d_self {
+		return 0, nil
+	}
+	return float64(d_busy-d_self) / float64(d_total), nil
+}
+
+// read_system_times returns the busy and total CPU time of all CPUs, in clock
+// ticks, from the first line of /proc/stat.
+func read_system_times() (busy, total uint64, err error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 8 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("unexpected format of /proc/stat")
+	}
+	// user nice system idle iowait irq softirq steal; guest time is already
+	// included in user time.
+	for i, field := range fields[1:] {
+		if i >= 8 {
+			break
+		}
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unexpected format of /proc/stat")
+		}
+		total += n
+		if i != 3 && i != 4 {
+			busy += n
+		}
+	}
+	return busy, total, nil
+}
+
+// read_self_time returns the CPU time used by this process, in clock ticks.
+func read_self_time() (uint64, error) {
+	data, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0, err
+	}
+	// The command name may contain spaces, but is followed by the last ')'.
+	i := strings.LastIndexByte(string(data), ')')
+	if i < 0 {
+		return 0, fmt.Errorf("unexpected format of /proc/self/stat")
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	// utime and stime are fields 14 and 15, counting the pid and command
+	// name as the first two.
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("unexpected format of /proc/self/stat")
+	}
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("unexpected format of /proc/self/stat")
+	}
+	return utime + stime, nil
+}
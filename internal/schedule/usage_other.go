@@ -0,0 +1,22 @@
+//go:build !linux
+
+package schedule
+
+import (
+	"errors"
+	"runtime"
+)
+
+// A UsageMonitor measures how much of the CPU other programs are using.
+type UsageMonitor struct{}
+
+// NewUsageMonitor returns a monitor whose first Sample measures from now.
+func NewUsageMonitor() (*UsageMonitor, error) {
+	return nil, errors.New("measuring CPU usage is not supported on " + runtime.GOOS)
+}
+
+// Sample returns the fraction of the machine's CPU time, from 0 to 1, used by
+// other processes since the previous sample.
+func (m *UsageMonitor) Sample() (float64, error) {
+	return 0, errors.New("measuring CPU usage is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,274 @@
+// Package stats keeps a persistent record of mining performance: periodic
+// hashrate samples, and the solutions found and rejected.
+package stats
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// The kinds of sample recorded.
+const (
+	// The hashrate over the interval since the last sample.
+	KindHashrate = "hashrate"
+	// A solution accepted by the server.
+	KindSolution = "solution"
+	// A solution rejected by the server.
+	KindReject = "reject"
+)
+
+// A Sample is a single entry of the stats store.
+type Sample struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	// The difficulty the server required at the time.
+	Difficulty uint8 `json:"difficulty"`
+	// For hashrate samples, the hashes attempted and the interval over which
+	// they were.
+	Attempts uint64        `json:"attempts,omitempty"`
+	Elapsed  time.Duration `json:"elapsed,omitempty"`
+	// For solutions and rejects, the apparent difficulty of the solution.
+	Best uint8 `json:"best,omitempty"`
+	// For rejects, the server's reason.
+	Reason string `json:"reason,omitempty"`
+	// For solutions, the webcash kept by the miner.
+	Amount webcash.Amount `json:"amount,omitempty"`
+}
+
+// Hashrate returns the hashes per second of a hashrate sample.
+func (s Sample) Hashrate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Attempts) / s.Elapsed.Seconds()
+}
+
+// A Store appends samples to a file, one JSON object per line, so that a
+// crash loses at most the sample being written.
+type Store struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// Open returns a store which records samples to the file at path, which is
+// created when the first sample is recorded.
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Record appends a sample to the store.
+func (s *Store) Record(sample Sample) error {
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if os.IsNotExist(err) {
+		// The directory is only made on first use.
+		if err = os.MkdirAll(filepath.Dir(s.path), 0700); err == nil {
+			f, err = os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Load reads the samples recorded between since and until.  A zero time
+// leaves that end of the range open.  A missing file is an empty store.
+func (s *Store) Load(since, until time.Time) ([]Sample, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var samples []Sample
+	scanner := bufio.NewScanner(f)
+	for line_num := 1; scanner.Scan(); line_num++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var sample Sample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.path, line_num, err)
+		}
+		if !since.IsZero() && sample.Time.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !sample.Time.Before(until) {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+// The causes into which Classify sorts rejections.
+const (
+	// The solution did not meet the server's difficulty.
+	CauseDifficulty = "difficulty"
+	// The solution's timestamp was too far from the server's clock.
+	CauseStale = "stale"
+	// The solution reused a secret, or was already reported.
+	CauseDuplicate = "duplicate"
+	// The report did not accept the terms of service.
+	CauseTerms = "terms"
+	// Anything else.
+	CauseOther = "other"
+)
+
+// Classify sorts a server's reason for rejecting a mining report into one of
+// the broad causes above, by the words the reference server uses.
+func Classify(reason string) string {
+	reason = strings.ToLower(reason)
+	switch {
+	case strings.Contains(reason, "difficulty") || strings.Contains(reason, "proof"):
+		return CauseDifficulty
+	case strings.Contains(reason, "timestamp") || strings.Contains(reason, "time"):
+		return CauseStale
+	case strings.Contains(reason, "secret") || strings.Contains(reason, "already") || strings.Contains(reason, "spent"):
+		return CauseDuplicate
+	case strings.Contains(reason, "terms") || strings.Contains(reason, "legalese"):
+		return CauseTerms
+	}
+	return CauseOther
+}
+
+// A Summary totals a range of samples.
+type Summary struct {
+	// The time of the first sample.
+	Since time.Time
+	// The hashes attempted, and the time spent mining.
+	Attempts uint64
+	Elapsed  time.Duration
+	// The number of solutions the attempts should have found on average, at
+	// the difficulty of the time.
+	Expected float64
+	// The mining reports answered by the server, and the webcash kept from
+	// those it accepted.
+	Accepted int
+	Rejected int
+	Earned   webcash.Amount
+	// The rejections by cause, as classified by Classify.
+	Causes map[string]int
+}
+
+// Summarize totals samples.
+func Summarize(samples []Sample) Summary {
+	sum := Summary{Causes: make(map[string]int)}
+	for _, s := range samples {
+		if sum.Since.IsZero() || s.Time.Before(sum.Since) {
+			sum.Since = s.Time
+		}
+		switch s.Kind {
+		case KindHashrate:
+			sum.Attempts += s.Attempts
+			sum.Elapsed += s.Elapsed
+			sum.Expected += ExpectedSolutions(s.Attempts, s.Difficulty)
+		case KindSolution:
+			sum.Accepted++
+			sum.Earned += s.Amount
+		case KindReject:
+			sum.Rejected++
+			sum.Causes[Classify(s.Reason)]++
+		}
+	}
+	return sum
+}
+
+// Hashrate returns the average hashes per second while mining.
+func (s Summary) Hashrate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Attempts) / s.Elapsed.Seconds()
+}
+
+// Luck returns the accepted solutions as a fraction of the number expected,
+// or zero if none were expected.  Well below one over a long period, luck
+// suggests a problem rather than chance, such as mining against a stale
+// difficulty.
+func (s Summary) Luck() float64 {
+	if s.Expected <= 0 {
+		return 0
+	}
+	return float64(s.Accepted) / s.Expected
+}
+
+// ExpectedSolutions returns the number of solutions attempts hashes find on
+// average at the given difficulty.
+func ExpectedSolutions(attempts uint64, difficulty uint8) float64 {
+	return float64(attempts) / math.Exp2(float64(difficulty))
+}
+
+// AcceptanceRate returns the fraction of answered mining reports which the
+// server accepted, or zero if there were none.
+func (s Summary) AcceptanceRate() float64 {
+	if s.Accepted+s.Rejected == 0 {
+		return 0
+	}
+	return float64(s.Accepted) / float64(s.Accepted+s.Rejected)
+}
+
+// WriteCSV writes samples as CSV with a header row.  Times are RFC 3339, and
+// hashrates are in hashes per second.
+func WriteCSV(w io.Writer, samples []Sample) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"time", "kind", "difficulty", "attempts", "elapsed_seconds", "hashrate", "best", "reason", "amount"})
+	for _, s := range samples {
+		out.Write([]string{
+			s.Time.UTC().Format(time.RFC3339),
+			s.Kind,
+			strconv.Itoa(int(s.Difficulty)),
+			strconv.FormatUint(s.Attempts, 10),
+			strconv.FormatFloat(s.Elapsed.Seconds(), 'f', 3, 64),
+			strconv.FormatFloat(s.Hashrate(), 'f', 2, 64),
+			strconv.Itoa(int(s.Best)),
+			s.Reason,
+			s.Amount.String(),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes samples as a JSON array, with the hashrate of each sample
+// included for convenience.
+func WriteJSON(w io.Writer, samples []Sample) error {
+	type exported struct {
+		Sample
+		Hashrate float64 `json:"hashrate,omitempty"`
+	}
+	records := make([]exported, len(samples))
+	for i, s := range samples {
+		records[i] = exported{Sample: s, Hashrate: s.Hashrate()}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
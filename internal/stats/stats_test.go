@@ -0,0 +1,160 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var test_start = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// test_samples returns one sample of each kind, a minute apart.
+func test_samples() []Sample {
+	return []Sample{
+		{Time: test_start, Kind: KindHashrate, Difficulty: 20, Attempts: 3 << 20, Elapsed: 10 * time.Second},
+		{Time: test_start.Add(time.Minute), Kind: KindSolution, Difficulty: 20, Best: 22, Amount: 2_000_000_00},
+		{Time: test_start.Add(2 * time.Minute), Kind: KindReject, Difficulty: 21, Best: 20, Reason: "Didn't use enough proof of work"},
+		{Time: test_start.Add(3 * time.Minute), Kind: KindHashrate, Difficulty: 21, Attempts: 1 << 21, Elapsed: 5 * time.Second},
+	}
+}
+
+func TestStore(t *testing.T) {
+	// The directory is made by the first sample recorded.
+	s := Open(filepath.Join(t.TempDir(), "stats", "stats.jsonl"))
+	if samples, err := s.Load(time.Time{}, time.Time{}); err != nil || samples != nil {
+		t.Fatalf("Load of a missing file = %v, %v, expected nothing", samples, err)
+	}
+	samples := test_samples()
+	for _, sample := range samples {
+		if err := s.Record(sample); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		since, until time.Time
+		want         []Sample
+	}{
+		{"all", time.Time{}, time.Time{}, samples},
+		{"since", test_start.Add(time.Minute), time.Time{}, samples[1:]},
+		{"until", time.Time{}, test_start.Add(2 * time.Minute), samples[:2]},
+		{"between", test_start.Add(30 * time.Second), test_start.Add(3 * time.Minute), samples[1:3]},
+		{"none", test_start.Add(time.Hour), time.Time{}, nil},
+	}
+	for _, test := range tests {
+		got, err := s.Load(test.since, test.until)
+		if err != nil {
+			t.Errorf("%s: Load: %v", test.name, err)
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: Load = %d samples, expected %d", test.name, len(got), len(test.want))
+			continue
+		}
+		for i := range got {
+			if !got[i].Time.Equal(test.want[i].Time) || got[i].Kind != test.want[i].Kind || got[i].Amount != test.want[i].Amount {
+				t.Errorf("%s: sample %d is %+v, expected %+v", test.name, i, got[i], test.want[i])
+			}
+		}
+	}
+
+	// Blank lines are skipped, but a corrupt line is an error naming it.
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("\n{\"time\": \n")
+	f.Close()
+	if _, err := s.Load(time.Time{}, time.Time{}); err == nil {
+		t.Error("Load of a corrupt file succeeded")
+	} else if want := s.path + ":6:"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Load = %v, expected an error at %s", err, want)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		reason, want string
+	}{
+		{"Didn't use enough proof of work", CauseDifficulty},
+		{"difficulty too low", CauseDifficulty},
+		{"Timestamp is too far in the future", CauseStale},
+		{"stale TIME", CauseStale},
+		{"Secret has already been used", CauseDuplicate},
+		{"webcash already spent", CauseDuplicate},
+		{"You must accept the terms of service", CauseTerms},
+		{"legalese not agreed", CauseTerms},
+		{"Internal Server Error", CauseOther},
+		{"", CauseOther},
+	}
+	for _, test := range tests {
+		if got := Classify(test.reason); got != test.want {
+			t.Errorf("Classify(%q) = %q, expected %q", test.reason, got, test.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	sum := Summarize(test_samples())
+	if !sum.Since.Equal(test_start) {
+		t.Errorf("since %v, expected %v", sum.Since, test_start)
+	}
+	if sum.Attempts != 5<<20 || sum.Elapsed != 15*time.Second {
+		t.Errorf("%d attempts over %v, expected %d over 15s", sum.Attempts, sum.Elapsed, 5<<20)
+	}
+	// Each hashrate sample is weighed at the difficulty of its time.
+	if sum.Expected != 4 {
+		t.Errorf("%v solutions expected, expected 4", sum.Expected)
+	}
+	if sum.Accepted != 1 || sum.Rejected != 1 || sum.Earned != 2_000_000_00 {
+		t.Errorf("%d accepted, %d rejected and %v earned, expected 1, 1 and 2", sum.Accepted, sum.Rejected, sum.Earned)
+	}
+	if want := map[string]int{CauseDifficulty: 1}; !reflect.DeepEqual(sum.Causes, want) {
+		t.Errorf("causes %v, expected %v", sum.Causes, want)
+	}
+	for _, test := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"hashrate", sum.Hashrate(), float64(5<<20) / 15},
+		{"luck", sum.Luck(), 0.25},
+		{"acceptance rate", sum.AcceptanceRate(), 0.5},
+	} {
+		if math.Abs(test.got-test.want) > 1e-9 {
+			t.Errorf("%s %v, expected %v", test.name, test.got, test.want)
+		}
+	}
+
+	// Nothing to go on is zero rather than a division by zero.
+	empty := Summarize(nil)
+	if empty.Hashrate() != 0 || empty.Luck() != 0 || empty.AcceptanceRate() != 0 {
+		t.Errorf("empty summary has hashrate %v, luck %v and acceptance rate %v", empty.Hashrate(), empty.Luck(), empty.AcceptanceRate())
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, test_samples()[:3]); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"time", "kind", "difficulty", "attempts", "elapsed_seconds", "hashrate", "best", "reason", "amount"},
+		{"2026-03-01T12:00:00Z", "hashrate", "20", "3145728", "10.000", "314572.80", "0", "", "0"},
+		{"2026-03-01T12:01:00Z", "solution", "20", "0", "0.000", "0.00", "22", "", "2"},
+		{"2026-03-01T12:02:00Z", "reject", "21", "0", "0.000", "0.00", "20", "Didn't use enough proof of work", "0"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("WriteCSV wrote %q, expected %q", records, want)
+	}
+}
@@ -0,0 +1,157 @@
+// Package style colors terminal output by what it means (errors, warnings,
+// successes, amounts) rather than by hard-coded colors, so that it can follow
+// the user's theme, and be turned off entirely.
+//
+// Color is off unless output is a terminal, and always off if the NO_COLOR
+// environment variable is set to anything (see https://no-color.org) or TERM
+// is "dumb".
+package style
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// A Role is what a piece of output means.
+type Role int
+
+const (
+	Normal Role = iota
+	Error
+	Warning
+	Success
+	// Amounts of webcash, and other figures the user reads for their value.
+	Value
+	// Detail of little interest, such as debug messages.
+	Muted
+)
+
+// A Theme gives the ANSI SGR parameters used for each role, e.g. "1;31" for
+// bold red.  Roles not in the theme are left unstyled.
+type Theme map[Role]string
+
+// The built-in themes, for terminals with dark and light backgrounds.
+var (
+	Dark = Theme{
+		Error:   "1;31",
+		Warning: "1;33",
+		Success: "1;32",
+		Value:   "1;36",
+		Muted:   "2",
+	}
+	Light = Theme{
+		Error:   "1;31",
+		Warning: "1;35",
+		Success: "1;32",
+		Value:   "1;34",
+		Muted:   "90",
+	}
+)
+
+// Themes are the built-in themes by name.
+var Themes = map[string]Theme{
+	"dark":  Dark,
+	"light": Light,
+}
+
+var (
+	mutex   sync.RWMutex
+	theme   = Dark
+	enabled = false
+)
+
+// Configure sets when to use color: "auto" for only when f is a terminal and
+// the environment allows it, "always", or "never"; and the theme, by name.
+func Configure(when, theme_name string, f *os.File) error {
+	t, ok := Themes[theme_name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q (expected \"dark\" or \"light\")", theme_name)
+	}
+	var on bool
+	switch when {
+	case "auto":
+		on = Supported(f)
+	case "always":
+		on = true
+	case "never":
+		on = false
+	default:
+		return fmt.Errorf("invalid color mode %q (expected \"auto\", \"always\" or \"never\")", when)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	theme, enabled = t, on
+	return nil
+}
+
+// Disable turns color off.
+func Disable() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	enabled = false
+}
+
+// Enabled reports whether output is being colored.
+func Enabled() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return enabled
+}
+
+// Supported reports whether color should be used for output to f, going by
+// the environment and whether f is a terminal.
+func Supported(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Paint styles text for its role, if color is enabled.
+func Paint(role Role, text string) string {
+	mutex.RLock()
+	on, sgr := enabled, theme[role]
+	mutex.RUnlock()
+	if !on || sgr == "" || text == "" {
+		return text
+	}
+	return "\x1b[" + sgr + "m" + text + "\x1b[0m"
+}
+
+// Strip removes ANSI escape sequences and other control characters, other
+// than tabs and newlines, from text.  It is used in plain mode to keep text
+// from elsewhere, such as server messages, from sneaking control sequences
+// into the output.
+func Strip(text string) string {
+	out := make([]rune, 0, len(text))
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == 0x1b:
+			// Skip a CSI sequence, ESC [ ... final byte, or a
+			// two-character escape.
+			if i+1 < len(runes) && runes[i+1] == '[' {
+				i += 2
+				for i < len(runes) && (runes[i] < 0x40 || runes[i] > 0x7e) {
+					i++
+				}
+			} else {
+				i++
+			}
+		case r == '\t' || r == '\n':
+			out = append(out, r)
+		case r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0):
+			// other control characters, including carriage returns
+			// which would allow overwriting a line in place
+		default:
+			out = append(out, r)
+		}
+	}
+	return string(out)
+}
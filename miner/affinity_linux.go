@@ -0,0 +1,24 @@
+package miner
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// set_thread_affinity restricts the calling OS thread to run only on the given
+// CPU.  The caller must have locked its goroutine to the thread with
+// runtime.LockOSThread, or the pinning will apply to whichever goroutine the
+// scheduler happens to run there next.
+func set_thread_affinity(cpu int) error {
+	var mask [16]uint64 // room for 1024 CPUs, same as glibc's cpu_set_t
+	if cpu < 0 || cpu >= len(mask)*64 {
+		return syscall.EINVAL
+	}
+	mask[cpu/64] |= 1 << (uint(cpu) % 64)
+	// A pid of zero refers to the calling thread.
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package miner
+
+import (
+	"errors"
+	"runtime"
+)
+
+func set_thread_affinity(cpu int) error {
+	return errors.New("CPU affinity is not supported on " + runtime.GOOS)
+}
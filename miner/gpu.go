@@ -0,0 +1,30 @@
+package miner
+
+import "unsafe"
+
+// A GPUDevice is an OpenCL device which can mine, as listed by GPUDevices.
+type GPUDevice struct {
+	// The device's position in the list, for selecting it by number.
+	Index int
+	// The names of the device and of its OpenCL platform (driver).
+	Name     string
+	Platform string
+	// The number of parallel compute units on the device, which sizes
+	// the work given to it.
+	ComputeUnits int
+
+	// The OpenCL device id, a cl_device_id.
+	id unsafe.Pointer
+}
+
+// gpu_tail fills in the final 16 bytes of a GPU mining payload: the three
+// nonce digit groups selected by the nonce a*1000000 + b*1000 + c, and the
+// encoding of the closing brace.  This matches the message tail hashed by the
+// search kernel.
+func gpu_tail(tail *[16]byte, nonce uint32) {
+	a, b, c := nonce/1000000, nonce/1000%1000, nonce%1000
+	copy(tail[0:4], nonce_table[4*a:4*a+4])
+	copy(tail[4:8], nonce_table[4*b:4*b+4])
+	copy(tail[8:12], nonce_table[4*c:4*c+4])
+	copy(tail[12:16], "fQ==")
+}
@@ -0,0 +1,74 @@
+package miner
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Hashrates are a snapshot of a HashrateMeter, in hashes per second.
+type Hashrates struct {
+	// The rate over the interval since the previous update.
+	Current float64
+	// Exponentially-weighted moving averages over one and fifteen minutes,
+	// in the manner of the Unix load average.
+	OneMinute     float64
+	FifteenMinute float64
+	// The hashes computed since the meter was created.
+	Total uint64
+}
+
+// A HashrateMeter turns periodic readings of a running total of hashes into
+// moving averages.  Readings may be taken at any interval, though the one
+// minute average is only meaningful if they are taken more often than that.
+// It is safe for concurrent use.
+type HashrateMeter struct {
+	mutex sync.Mutex
+	first uint64
+	last  uint64
+	when  time.Time
+	// Whether the averages have been seeded by a first measured rate.
+	seeded bool
+	rates  Hashrates
+}
+
+// Update records that total hashes had been computed as of now.  The first
+// update only sets the starting point; the averages are seeded from the rate
+// measured by the second.
+func (h *HashrateMeter) Update(total uint64, now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.when.IsZero() {
+		h.first, h.last, h.when = total, total, now
+		return
+	}
+	elapsed := now.Sub(h.when).Seconds()
+	if elapsed <= 0 || total < h.last {
+		return
+	}
+	rate := float64(total-h.last) / elapsed
+	if !h.seeded {
+		h.rates.OneMinute, h.rates.FifteenMinute = rate, rate
+		h.seeded = true
+	} else {
+		h.rates.OneMinute = ewma(h.rates.OneMinute, rate, elapsed, time.Minute)
+		h.rates.FifteenMinute = ewma(h.rates.FifteenMinute, rate, elapsed, 15*time.Minute)
+	}
+	h.rates.Current = rate
+	h.rates.Total = total - h.first
+	h.last, h.when = total, now
+}
+
+// Rates returns the rates as of the last update.
+func (h *HashrateMeter) Rates() Hashrates {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.rates
+}
+
+// ewma decays avg toward sample by the weight due to elapsed seconds of a
+// moving average over window.
+func ewma(avg, sample, elapsed float64, window time.Duration) float64 {
+	alpha := 1 - math.Exp(-elapsed/window.Seconds())
+	return avg + alpha*(sample-avg)
+}
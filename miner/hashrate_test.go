@@ -0,0 +1,46 @@
+package miner
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHashrateMeter(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// The averages after a steady 1000 H/s, then 10 seconds at 4000 H/s.
+	one := 1000 + (1-math.Exp(-10.0/60))*3000
+	fifteen := 1000 + (1-math.Exp(-10.0/900))*3000
+	type reading struct {
+		total   uint64
+		seconds float64
+	}
+	tests := []struct {
+		name     string
+		readings []reading
+		want     Hashrates
+	}{
+		{"none", nil, Hashrates{}},
+		// The first reading is only a starting point.
+		{"first", []reading{{5000, 0}}, Hashrates{}},
+		{"seeded", []reading{{5000, 0}, {15000, 10}}, Hashrates{1000, 1000, 1000, 10000}},
+		{"steady", []reading{{0, 0}, {10000, 10}, {20000, 20}, {30000, 30}}, Hashrates{1000, 1000, 1000, 30000}},
+		{"faster", []reading{{0, 0}, {10000, 10}, {50000, 20}}, Hashrates{4000, one, fifteen, 50000}},
+		// A reading which goes backwards in time or in total is ignored.
+		{"same time", []reading{{0, 0}, {10000, 10}, {20000, 10}}, Hashrates{1000, 1000, 1000, 10000}},
+		{"earlier", []reading{{0, 0}, {10000, 10}, {20000, 5}}, Hashrates{1000, 1000, 1000, 10000}},
+		{"lower total", []reading{{0, 0}, {10000, 10}, {5000, 20}}, Hashrates{1000, 1000, 1000, 10000}},
+		{"idle", []reading{{0, 0}, {10000, 10}, {10000, 20}}, Hashrates{0, 1000 * math.Exp(-10.0/60), 1000 * math.Exp(-10.0/900), 10000}},
+	}
+	for _, test := range tests {
+		var h HashrateMeter
+		for _, r := range test.readings {
+			h.Update(r.total, start.Add(time.Duration(r.seconds*float64(time.Second))))
+		}
+		got := h.Rates()
+		near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+		if !near(got.Current, test.want.Current) || !near(got.OneMinute, test.want.OneMinute) || !near(got.FifteenMinute, test.want.FifteenMinute) || got.Total != test.want.Total {
+			t.Errorf("%s: Rates = %+v, expected %+v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,759 @@
+// Package miner searches for webcash proof-of-work solutions on the CPU.
+package miner
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maaku/gocash/internal/decimal"
+	"github.com/maaku/gocash/webcash"
+)
+
+// The largest difficulty we will attempt work on.
+const MaxDifficulty = 50
+
+// A Solution is a mining payload whose hash meets the difficulty it commits to.
+type Solution struct {
+	// The hash of the solution.
+	Hash webcash.Uint256 `json:"hash"`
+	// The base64-encoded mining payload.
+	Preimage string `json:"preimage"`
+	// The reward to the miner
+	Reward webcash.SecretWebcash `json:"reward"`
+	// The committed difficulty
+	Difficulty uint8 `json:"difficulty"`
+	// The committed timestamp
+	Timestamp time.Time `json:"timestamp"`
+	// Whether the solution was found by another machine, which knows the
+	// secret of its reward.
+	Remote bool `json:"remote,omitempty"`
+}
+
+// A SolutionQueue carries solutions from the mining threads to whatever
+// submits them to the server.  It is bounded: when the consumer falls behind,
+// for example because the server is slow to accept reports, mining threads
+// block when pushing new solutions rather than the backlog growing without
+// limit.  The time spent blocked is recorded so that the slowdown is visible.
+type SolutionQueue struct {
+	ch chan Solution
+	// Total nanoseconds mining threads have spent waiting for space.
+	blocked_ns int64
+	// The largest number of solutions queued at once.
+	high_water int64
+}
+
+// NewSolutionQueue returns a queue with room for capacity solutions.
+func NewSolutionQueue(capacity int) *SolutionQueue {
+	return &SolutionQueue{ch: make(chan Solution, capacity)}
+}
+
+// Push adds a solution to the queue, waiting for space if it is full.  An
+// error is returned only if ctx is cancelled first.
+func (q *SolutionQueue) Push(ctx context.Context, soln Solution) error {
+	select {
+	case q.ch <- soln:
+	default:
+		start := time.Now()
+		select {
+		case q.ch <- soln:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		atomic.AddInt64(&q.blocked_ns, int64(time.Since(start)))
+	}
+	for {
+		depth := int64(len(q.ch))
+		old := atomic.LoadInt64(&q.high_water)
+		if depth <= old || atomic.CompareAndSwapInt64(&q.high_water, old, depth) {
+			return nil
+		}
+	}
+}
+
+// C returns the channel from which queued solutions are received.
+func (q *SolutionQueue) C() <-chan Solution {
+	return q.ch
+}
+
+// String reports the current and maximum queue depth, and the total time
+// mining threads have spent blocked on a full queue.
+func (q *SolutionQueue) String() string {
+	blocked := time.Duration(atomic.LoadInt64(&q.blocked_ns))
+	return fmt.Sprintf("%d/%d (max %d, blocked %v)", len(q.ch), cap(q.ch), atomic.LoadInt64(&q.high_water), blocked.Round(time.Millisecond))
+}
+
+// A Miner runs a pool of mining threads, each searching for solutions to the
+// current protocol settings and pushing any it finds to a SolutionQueue.  The
+// number of threads and their CPU assignments can be changed while running;
+// threads which keep their assignment across a change are left running, with
+// their arenas and midstates intact.
+type Miner struct {
+	// Receives a description of notable events, if set before the first
+	// call to Resize.
+	Log func(format string, args ...interface{})
+	// If set before the first call to Resize, mining threads run at the
+	// lowest scheduling priority, where the platform supports it.
+	Idle bool
+	// If set before the first call to Resize, supplies the secrets for the
+	// miner's share of rewards in place of random ones, e.g. from a wallet.
+	// Every thread mines for the same secret, and the next is asked for only
+	// once a solution has claimed it, so that secrets are used in turn, with
+	// none skipped, however many threads there are and however often they
+	// restart.  Secrets must be at most MaxKeepSecretLen characters, and
+	// contain no quotes or backslashes.
+	KeepSecret func() (string, error)
+	// If set along with KeepSecret, called with a secret from it as a
+	// solution claims it, once the solution has been pushed and before the
+	// next secret is asked for.
+	ClaimSecret func(secret string) error
+
+	ctx       context.Context
+	solutions *SolutionQueue
+
+	// The secret from KeepSecret which every thread is mining for, or "" if
+	// the next has yet to be asked for, and the number claimed so far, by
+	// which threads notice that theirs has been.  keep_mutex protects both,
+	// and keep_claims is also read atomically.
+	keep_mutex  sync.Mutex
+	keep_secret string
+	keep_claims uint64
+
+	mutex    sync.Mutex // protects settings
+	settings webcash.ProtocolSettings
+
+	// The number of hashes computed since the miner was created, and the
+	// number as of the last call to TakeStats.
+	attempts uint64
+	taken    uint64
+	// The highest apparent difficulty of any hash computed since the last
+	// call to TakeStats.  Only ever increased by the mining threads, via
+	// record_best_difficulty.
+	best_difficulty uint32
+
+	// The number of hashes computed per call into libsha2.
+	batch_size uint32
+
+	// Nonzero while mining is paused.
+	paused uint32
+
+	// The percentage of the time each mining thread spends hashing, the rest
+	// being spent asleep.  Zero means no limit.
+	max_cpu uint32
+
+	wg         sync.WaitGroup
+	pool_mutex sync.Mutex // protects workers
+	workers    []pool_worker
+}
+
+type pool_worker struct {
+	cpu    int
+	cancel context.CancelFunc
+	// The number of hashes computed by this thread.
+	attempts *uint64
+}
+
+// New returns a miner which will mine against settings and deliver solutions
+// to the given queue.  No mining threads are started until Resize is called,
+// and all of them stop when ctx is cancelled.
+func New(ctx context.Context, settings webcash.ProtocolSettings, solutions *SolutionQueue) *Miner {
+	return &Miner{
+		ctx:        ctx,
+		solutions:  solutions,
+		settings:   settings,
+		batch_size: DefaultBatchSize,
+	}
+}
+
+func (m *Miner) log(format string, args ...interface{}) {
+	if m.Log != nil {
+		m.Log(format, args...)
+	}
+}
+
+// Settings returns the protocol settings currently being mined against.
+func (m *Miner) Settings() webcash.ProtocolSettings {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.settings
+}
+
+// SetSettings changes the protocol settings to mine against.  Threads pick up
+// the new settings when they next construct a mining payload.
+func (m *Miner) SetSettings(settings webcash.ProtocolSettings) {
+	m.mutex.Lock()
+	m.settings = settings
+	m.mutex.Unlock()
+}
+
+// SetDifficulty changes only the difficulty of the current settings, returning
+// the previous difficulty.
+func (m *Miner) SetDifficulty(difficulty uint8) uint8 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	old := m.settings.Difficulty
+	m.settings.Difficulty = difficulty
+	return old
+}
+
+// TakeStats returns the number of hashes computed, and the highest apparent
+// difficulty among them, since the previous call.
+func (m *Miner) TakeStats() (attempts uint64, best uint8) {
+	total := atomic.LoadUint64(&m.attempts)
+	attempts = total - atomic.SwapUint64(&m.taken, total)
+	best = uint8(atomic.SwapUint32(&m.best_difficulty, 0))
+	return attempts, best
+}
+
+// Attempts returns the number of hashes computed since the miner was created.
+// Unlike TakeStats, it can be read by any number of observers.
+func (m *Miner) Attempts() uint64 {
+	return atomic.LoadUint64(&m.attempts)
+}
+
+// SetBatchSize changes the number of hashes each mining thread computes per
+// call into libsha2, which trades call overhead against cache use.  It must be
+// 8, 40, 200 or 1000.  Threads pick up the change with their next payload.
+func (m *Miner) SetBatchSize(n int) error {
+	if err := CheckBatchSize(n); err != nil {
+		return err
+	}
+	atomic.StoreUint32(&m.batch_size, uint32(n))
+	return nil
+}
+
+// CheckBatchSize returns an error if n is not a usable batch size.
+func CheckBatchSize(n int) error {
+	if n <= 0 || n%8 != 0 || max_batch_size%n != 0 {
+		return fmt.Errorf("invalid batch size %d: must be 8, 40, 200 or 1000", n)
+	}
+	return nil
+}
+
+// SetPaused pauses or resumes mining.  Paused threads finish the payload they
+// are working on, and then wait without using the CPU until resumed.
+func (m *Miner) SetPaused(paused bool) {
+	var flag uint32
+	if paused {
+		flag = 1
+	}
+	atomic.StoreUint32(&m.paused, flag)
+}
+
+// SetMaxCPU limits each mining thread to hashing for the given percentage of
+// the time, sleeping for the remainder, so that mining leaves room for other
+// work on every core it runs on.  A percentage of 100 or more removes the
+// limit.
+func (m *Miner) SetMaxCPU(percent int) {
+	if percent <= 0 || percent >= 100 {
+		percent = 0
+	}
+	atomic.StoreUint32(&m.max_cpu, uint32(percent))
+}
+
+// throttle sleeps for long enough that the time spent hashing since busy_since
+// is the configured share of the total, and then resets busy_since.
+func (m *Miner) throttle(busy_since *time.Time) {
+	percent := time.Duration(atomic.LoadUint32(&m.max_cpu))
+	if percent != 0 {
+		busy := time.Since(*busy_since)
+		time.Sleep(busy * (100 - percent) / percent)
+	}
+	*busy_since = time.Now()
+}
+
+// record_best_difficulty raises best_difficulty to diff, if it is not already
+// at least that large.
+func (m *Miner) record_best_difficulty(diff uint8) {
+	for {
+		old := atomic.LoadUint32(&m.best_difficulty)
+		if uint32(diff) <= old || atomic.CompareAndSwapUint32(&m.best_difficulty, old, uint32(diff)) {
+			return
+		}
+	}
+}
+
+// Resize runs the given number of mining threads, starting and stopping
+// threads as necessary.  If workers is zero, one thread is run per usable CPU,
+// or per pinned CPU if a list is given.  Threads are pinned to the listed CPUs
+// in turn.  It returns the number of threads now running.
+//
+// Each thread mines with its own random subsidy secrets, so no two threads ever
+// search the same preimages, even when mining for the same keep secret.
+func (m *Miner) Resize(workers int, cpus []int) int {
+	m.pool_mutex.Lock()
+	defer m.pool_mutex.Unlock()
+
+	num_threads := workers
+	if num_threads <= 0 {
+		num_threads = runtime.GOMAXPROCS(0)
+		if len(cpus) > 0 && len(cpus) < num_threads {
+			num_threads = len(cpus)
+		}
+	}
+
+	// Stop surplus threads, and any whose CPU assignment has changed.
+	for id := len(m.workers) - 1; id >= 0; id-- {
+		if id >= num_threads {
+			m.workers[id].cancel()
+			m.workers = m.workers[:id]
+			continue
+		}
+		if cpu := assigned_cpu(cpus, id); cpu != m.workers[id].cpu {
+			m.workers[id].cancel()
+			m.workers[id] = m.start(id, cpu)
+		}
+	}
+	for id := len(m.workers); id < num_threads; id++ {
+		m.workers = append(m.workers, m.start(id, assigned_cpu(cpus, id)))
+	}
+	return num_threads
+}
+
+func (m *Miner) start(id, cpu int) pool_worker {
+	ctx, cancel := context.WithCancel(m.ctx)
+	attempts := new(uint64)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.mining_thread(ctx, id, cpu, attempts)
+	}()
+	return pool_worker{cpu: cpu, cancel: cancel, attempts: attempts}
+}
+
+// ThreadAttempts returns the number of hashes computed by each running mining
+// thread since it started.  A thread restarted by Resize starts again from
+// zero.
+func (m *Miner) ThreadAttempts() []uint64 {
+	m.pool_mutex.Lock()
+	defer m.pool_mutex.Unlock()
+	counts := make([]uint64, len(m.workers))
+	for id, w := range m.workers {
+		counts[id] = atomic.LoadUint64(w.attempts)
+	}
+	return counts
+}
+
+// Wait blocks until every mining thread has exited.
+func (m *Miner) Wait() {
+	m.wg.Wait()
+}
+
+// assigned_cpu returns the CPU that mining thread id should be pinned to, or -1
+// if it is not to be pinned.
+func assigned_cpu(cpus []int, id int) int {
+	if len(cpus) == 0 {
+		return -1
+	}
+	return cpus[id%len(cpus)]
+}
+
+// ParseCPUList parses a comma-separated list of CPU numbers and inclusive
+// ranges, e.g. "0,2,4-7", as used by taskset(1).
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		lo, hi, is_range := strings.Cut(field, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU number %q", lo)
+		}
+		last := first
+		if is_range {
+			last, err = strconv.Atoi(hi)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CPU number %q", hi)
+			}
+		}
+		if first < 0 || last < first {
+			return nil, fmt.Errorf("invalid CPU range %q", field)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	if len(cpus) == 0 {
+		return nil, errors.New("empty CPU list")
+	}
+	return cpus, nil
+}
+
+// The default and largest number of hashes computed per call into libsha2.
+// Batch sizes must be a multiple of 8, the number of lanes hashed in parallel,
+// and divide the 1000 values of the second nonce digit group evenly.
+const (
+	DefaultBatchSize = 25 * 8
+	max_batch_size   = 1000
+)
+
+// An 18-byte secret is exactly 24 characters when base64-encoded.
+const secret_len = 24
+
+// The longest keep secret which Miner.KeepSecret may supply.
+const MaxKeepSecretLen = 64
+
+// An upper bound on the size of the JSON mining payload prefix.  The fixed
+// portion is well under 300 bytes, and under 340 even with the longest keep
+// secret and amounts, including the padding to a multiple of 48 bytes.
+const max_prefix_len = 384
+
+// A mining_arena holds every buffer a mining thread needs to construct and hash
+// work candidates.  It is allocated once per thread and reused for every
+// attempt, so that the hot path generates no garbage and hashrate isn't
+// periodically interrupted by the garbage collector.
+type mining_arena struct {
+	// Raw entropy for secret generation.
+	entropy [18]byte
+	// The base64-encoded keep and subsidy secrets.  The keep secret is a view
+	// into keep_buf, which also has room for one from Miner.KeepSecret.
+	keep     []byte
+	keep_buf [MaxKeepSecretLen]byte
+	subsidy  [secret_len]byte
+	// The number of keep secrets claimed as of when the keep secret was
+	// taken from Miner.KeepSecret, if it was.
+	claims uint64
+	// The JSON mining payload prefix, and its base64 encoding.
+	raw    [max_prefix_len]byte
+	prefix [max_prefix_len / 3 * 4]byte
+	// The SHA256 state after absorbing the encoded prefix.
+	midstate Hasher
+	// Output buffer for a batch of hashes.
+	hashes [max_batch_size]webcash.Uint256
+}
+
+// generate_secrets fills the keep and subsidy secret buffers with fresh
+// randomness from the runtime's CSPRNG.
+func (arena *mining_arena) generate_secrets() error {
+	if _, err := rand.Read(arena.entropy[:]); err != nil {
+		return err
+	}
+	arena.keep = arena.keep_buf[:secret_len]
+	base64.StdEncoding.Encode(arena.keep, arena.entropy[:])
+	if _, err := rand.Read(arena.entropy[:]); err != nil {
+		return err
+	}
+	base64.StdEncoding.Encode(arena.subsidy[:], arena.entropy[:])
+
+	// Clear the secret from memory
+	for i := range arena.entropy {
+		arena.entropy[i] = 0
+	}
+	return nil
+}
+
+// generate_secrets fills the arena's secret buffers for a new mining payload.
+// A keep secret from KeepSecret is shared by every thread until a solution
+// claims it, so that secrets are drawn from it only as fast as solutions are
+// found.
+func (m *Miner) generate_secrets(arena *mining_arena) error {
+	if err := arena.generate_secrets(); err != nil {
+		return err
+	}
+	if m.KeepSecret == nil {
+		return nil
+	}
+	m.keep_mutex.Lock()
+	defer m.keep_mutex.Unlock()
+	if m.keep_secret == "" {
+		secret, err := m.KeepSecret()
+		if err != nil {
+			return err
+		}
+		if secret == "" || len(secret) > MaxKeepSecretLen || strings.ContainsAny(secret, "\"\\") {
+			return fmt.Errorf("unusable keep secret of length %d", len(secret))
+		}
+		m.keep_secret = secret
+	}
+	arena.claims = m.keep_claims
+	arena.keep = arena.keep_buf[:copy(arena.keep_buf[:], m.keep_secret)]
+	return nil
+}
+
+// keep_claimed reports whether the keep secret from KeepSecret which the
+// arena's payload is mined for has since been claimed by another solution.
+func (m *Miner) keep_claimed(arena *mining_arena) bool {
+	return m.KeepSecret != nil && atomic.LoadUint64(&m.keep_claims) != arena.claims
+}
+
+// push_solution pushes a solution to the arena's payload.  If its keep secret
+// is from KeepSecret, the solution claims it: unless another solution claimed
+// it first, when the solution is dropped, every other thread abandons its
+// payload at once, the secret is passed to ClaimSecret, and the next one is
+// mined for.  The secret's lock is held while the solution is pushed, so that
+// if it cannot be, the secret is left unclaimed; meanwhile the other threads
+// wait for the next.  An error is returned only if ctx is cancelled first.
+func (m *Miner) push_solution(ctx context.Context, arena *mining_arena, soln Solution) error {
+	if m.KeepSecret == nil {
+		return m.solutions.Push(ctx, soln)
+	}
+	m.keep_mutex.Lock()
+	defer m.keep_mutex.Unlock()
+	if m.keep_claims != arena.claims {
+		m.log("dropping a solution whose keep secret another has claimed")
+		return nil
+	}
+	atomic.AddUint64(&m.keep_claims, 1)
+	if err := m.solutions.Push(ctx, soln); err != nil {
+		return err
+	}
+	if m.ClaimSecret != nil {
+		if err := m.ClaimSecret(m.keep_secret); err != nil {
+			m.log("Error: failed to claim the keep secret: %v", err)
+		}
+	}
+	m.keep_secret = ""
+	return nil
+}
+
+// build_prefix serializes the fixed portion of the mining payload, up to and
+// including the leading digit of the nonce, and returns its base64 encoding.
+// The result is a multiple of 64 bytes in length and is a view into the arena,
+// valid until the next call.
+func (arena *mining_arena) build_prefix(keep, subsidy webcash.Amount, difficulty uint8, now time.Time) []byte {
+	buf := arena.raw[:0]
+	buf = append(buf, `{"legalese":{"terms":true},"webcash":["e`...)
+	buf = keep.Append(buf)
+	buf = append(buf, ":secret:"...)
+	buf = append(buf, arena.keep...)
+	buf = append(buf, `","e`...)
+	buf = subsidy.Append(buf)
+	buf = append(buf, ":secret:"...)
+	buf = append(buf, arena.subsidy[:]...)
+	buf = append(buf, `"],"subsidy":["e`...)
+	buf = subsidy.Append(buf)
+	buf = append(buf, ":secret:"...)
+	buf = append(buf, arena.subsidy[:]...)
+	buf = append(buf, `"],"difficulty":`...)
+	buf = decimal.AppendUint(buf, uint64(difficulty))
+	buf = append(buf, `,"timestamp":`...)
+	buf = decimal.AppendUint(buf, uint64(now.Unix()))
+	buf = append(buf, '.')
+	// Microseconds, with trailing zeros removed
+	μsec := uint64(now.UnixMicro() % 1000000)
+	width := 6
+	for width > 1 && μsec%10 == 0 {
+		μsec /= 10
+		width--
+	}
+	buf = decimal.AppendPadded(buf, μsec, width)
+	buf = append(buf, `,"nonce":`...)
+	// Extend the prefix to be a multiple of 48 in size...
+	for len(buf)%48 != 47 {
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, '1')
+	// ...which becomes a multiple of 64 bytes when base64-encoded.
+	n := base64.StdEncoding.EncodedLen(len(buf))
+	base64.StdEncoding.Encode(arena.prefix[:n], buf)
+	return arena.prefix[:n]
+}
+
+// The numbers "000" through "999" concatenated together and encoded into
+// base64.  Any 3 ASCII digits encode to 4 base64 digits, so any number N in
+// this range can be encoded as nonce_table[4*N : 4*N+4].  Shared by all mining
+// threads, which only ever read from it.
+var nonce_table = []byte("" +
+	"MDAwMDAxMDAyMDAzMDA0MDA1MDA2MDA3MDA4MDA5MDEwMDExMDEyMDEzMDE0MDE1MDE2MDE3MDE4MDE5" +
+	"MDIwMDIxMDIyMDIzMDI0MDI1MDI2MDI3MDI4MDI5MDMwMDMxMDMyMDMzMDM0MDM1MDM2MDM3MDM4MDM5" +
+	"MDQwMDQxMDQyMDQzMDQ0MDQ1MDQ2MDQ3MDQ4MDQ5MDUwMDUxMDUyMDUzMDU0MDU1MDU2MDU3MDU4MDU5" +
+	"MDYwMDYxMDYyMDYzMDY0MDY1MDY2MDY3MDY4MDY5MDcwMDcxMDcyMDczMDc0MDc1MDc2MDc3MDc4MDc5" +
+	"MDgwMDgxMDgyMDgzMDg0MDg1MDg2MDg3MDg4MDg5MDkwMDkxMDkyMDkzMDk0MDk1MDk2MDk3MDk4MDk5" +
+	"MTAwMTAxMTAyMTAzMTA0MTA1MTA2MTA3MTA4MTA5MTEwMTExMTEyMTEzMTE0MTE1MTE2MTE3MTE4MTE5" +
+	"MTIwMTIxMTIyMTIzMTI0MTI1MTI2MTI3MTI4MTI5MTMwMTMxMTMyMTMzMTM0MTM1MTM2MTM3MTM4MTM5" +
+	"MTQwMTQxMTQyMTQzMTQ0MTQ1MTQ2MTQ3MTQ4MTQ5MTUwMTUxMTUyMTUzMTU0MTU1MTU2MTU3MTU4MTU5" +
+	"MTYwMTYxMTYyMTYzMTY0MTY1MTY2MTY3MTY4MTY5MTcwMTcxMTcyMTczMTc0MTc1MTc2MTc3MTc4MTc5" +
+	"MTgwMTgxMTgyMTgzMTg0MTg1MTg2MTg3MTg4MTg5MTkwMTkxMTkyMTkzMTk0MTk1MTk2MTk3MTk4MTk5" +
+	"MjAwMjAxMjAyMjAzMjA0MjA1MjA2MjA3MjA4MjA5MjEwMjExMjEyMjEzMjE0MjE1MjE2MjE3MjE4MjE5" +
+	"MjIwMjIxMjIyMjIzMjI0MjI1MjI2MjI3MjI4MjI5MjMwMjMxMjMyMjMzMjM0MjM1MjM2MjM3MjM4MjM5" +
+	"MjQwMjQxMjQyMjQzMjQ0MjQ1MjQ2MjQ3MjQ4MjQ5MjUwMjUxMjUyMjUzMjU0MjU1MjU2MjU3MjU4MjU5" +
+	"MjYwMjYxMjYyMjYzMjY0MjY1MjY2MjY3MjY4MjY5MjcwMjcxMjcyMjczMjc0Mjc1Mjc2Mjc3Mjc4Mjc5" +
+	"MjgwMjgxMjgyMjgzMjg0Mjg1Mjg2Mjg3Mjg4Mjg5MjkwMjkxMjkyMjkzMjk0Mjk1Mjk2Mjk3Mjk4Mjk5" +
+	"MzAwMzAxMzAyMzAzMzA0MzA1MzA2MzA3MzA4MzA5MzEwMzExMzEyMzEzMzE0MzE1MzE2MzE3MzE4MzE5" +
+	"MzIwMzIxMzIyMzIzMzI0MzI1MzI2MzI3MzI4MzI5MzMwMzMxMzMyMzMzMzM0MzM1MzM2MzM3MzM4MzM5" +
+	"MzQwMzQxMzQyMzQzMzQ0MzQ1MzQ2MzQ3MzQ4MzQ5MzUwMzUxMzUyMzUzMzU0MzU1MzU2MzU3MzU4MzU5" +
+	"MzYwMzYxMzYyMzYzMzY0MzY1MzY2MzY3MzY4MzY5MzcwMzcxMzcyMzczMzc0Mzc1Mzc2Mzc3Mzc4Mzc5" +
+	"MzgwMzgxMzgyMzgzMzg0Mzg1Mzg2Mzg3Mzg4Mzg5MzkwMzkxMzkyMzkzMzk0Mzk1Mzk2Mzk3Mzk4Mzk5" +
+	"NDAwNDAxNDAyNDAzNDA0NDA1NDA2NDA3NDA4NDA5NDEwNDExNDEyNDEzNDE0NDE1NDE2NDE3NDE4NDE5" +
+	"NDIwNDIxNDIyNDIzNDI0NDI1NDI2NDI3NDI4NDI5NDMwNDMxNDMyNDMzNDM0NDM1NDM2NDM3NDM4NDM5" +
+	"NDQwNDQxNDQyNDQzNDQ0NDQ1NDQ2NDQ3NDQ4NDQ5NDUwNDUxNDUyNDUzNDU0NDU1NDU2NDU3NDU4NDU5" +
+	"NDYwNDYxNDYyNDYzNDY0NDY1NDY2NDY3NDY4NDY5NDcwNDcxNDcyNDczNDc0NDc1NDc2NDc3NDc4NDc5" +
+	"NDgwNDgxNDgyNDgzNDg0NDg1NDg2NDg3NDg4NDg5NDkwNDkxNDkyNDkzNDk0NDk1NDk2NDk3NDk4NDk5" +
+	"NTAwNTAxNTAyNTAzNTA0NTA1NTA2NTA3NTA4NTA5NTEwNTExNTEyNTEzNTE0NTE1NTE2NTE3NTE4NTE5" +
+	"NTIwNTIxNTIyNTIzNTI0NTI1NTI2NTI3NTI4NTI5NTMwNTMxNTMyNTMzNTM0NTM1NTM2NTM3NTM4NTM5" +
+	"NTQwNTQxNTQyNTQzNTQ0NTQ1NTQ2NTQ3NTQ4NTQ5NTUwNTUxNTUyNTUzNTU0NTU1NTU2NTU3NTU4NTU5" +
+	"NTYwNTYxNTYyNTYzNTY0NTY1NTY2NTY3NTY4NTY5NTcwNTcxNTcyNTczNTc0NTc1NTc2NTc3NTc4NTc5" +
+	"NTgwNTgxNTgyNTgzNTg0NTg1NTg2NTg3NTg4NTg5NTkwNTkxNTkyNTkzNTk0NTk1NTk2NTk3NTk4NTk5" +
+	"NjAwNjAxNjAyNjAzNjA0NjA1NjA2NjA3NjA4NjA5NjEwNjExNjEyNjEzNjE0NjE1NjE2NjE3NjE4NjE5" +
+	"NjIwNjIxNjIyNjIzNjI0NjI1NjI2NjI3NjI4NjI5NjMwNjMxNjMyNjMzNjM0NjM1NjM2NjM3NjM4NjM5" +
+	"NjQwNjQxNjQyNjQzNjQ0NjQ1NjQ2NjQ3NjQ4NjQ5NjUwNjUxNjUyNjUzNjU0NjU1NjU2NjU3NjU4NjU5" +
+	"NjYwNjYxNjYyNjYzNjY0NjY1NjY2NjY3NjY4NjY5NjcwNjcxNjcyNjczNjc0Njc1Njc2Njc3Njc4Njc5" +
+	"NjgwNjgxNjgyNjgzNjg0Njg1Njg2Njg3Njg4Njg5NjkwNjkxNjkyNjkzNjk0Njk1Njk2Njk3Njk4Njk5" +
+	"NzAwNzAxNzAyNzAzNzA0NzA1NzA2NzA3NzA4NzA5NzEwNzExNzEyNzEzNzE0NzE1NzE2NzE3NzE4NzE5" +
+	"NzIwNzIxNzIyNzIzNzI0NzI1NzI2NzI3NzI4NzI5NzMwNzMxNzMyNzMzNzM0NzM1NzM2NzM3NzM4NzM5" +
+	"NzQwNzQxNzQyNzQzNzQ0NzQ1NzQ2NzQ3NzQ4NzQ5NzUwNzUxNzUyNzUzNzU0NzU1NzU2NzU3NzU4NzU5" +
+	"NzYwNzYxNzYyNzYzNzY0NzY1NzY2NzY3NzY4NzY5NzcwNzcxNzcyNzczNzc0Nzc1Nzc2Nzc3Nzc4Nzc5" +
+	"NzgwNzgxNzgyNzgzNzg0Nzg1Nzg2Nzg3Nzg4Nzg5NzkwNzkxNzkyNzkzNzk0Nzk1Nzk2Nzk3Nzk4Nzk5" +
+	"ODAwODAxODAyODAzODA0ODA1ODA2ODA3ODA4ODA5ODEwODExODEyODEzODE0ODE1ODE2ODE3ODE4ODE5" +
+	"ODIwODIxODIyODIzODI0ODI1ODI2ODI3ODI4ODI5ODMwODMxODMyODMzODM0ODM1ODM2ODM3ODM4ODM5" +
+	"ODQwODQxODQyODQzODQ0ODQ1ODQ2ODQ3ODQ4ODQ5ODUwODUxODUyODUzODU0ODU1ODU2ODU3ODU4ODU5" +
+	"ODYwODYxODYyODYzODY0ODY1ODY2ODY3ODY4ODY5ODcwODcxODcyODczODc0ODc1ODc2ODc3ODc4ODc5" +
+	"ODgwODgxODgyODgzODg0ODg1ODg2ODg3ODg4ODg5ODkwODkxODkyODkzODk0ODk1ODk2ODk3ODk4ODk5" +
+	"OTAwOTAxOTAyOTAzOTA0OTA1OTA2OTA3OTA4OTA5OTEwOTExOTEyOTEzOTE0OTE1OTE2OTE3OTE4OTE5" +
+	"OTIwOTIxOTIyOTIzOTI0OTI1OTI2OTI3OTI4OTI5OTMwOTMxOTMyOTMzOTM0OTM1OTM2OTM3OTM4OTM5" +
+	"OTQwOTQxOTQyOTQzOTQ0OTQ1OTQ2OTQ3OTQ4OTQ5OTUwOTUxOTUyOTUzOTU0OTU1OTU2OTU3OTU4OTU5" +
+	"OTYwOTYxOTYyOTYzOTY0OTY1OTY2OTY3OTY4OTY5OTcwOTcxOTcyOTczOTc0OTc1OTc2OTc3OTc4OTc5" +
+	"OTgwOTgxOTgyOTgzOTg0OTg1OTg2OTg3OTg4OTg5OTkwOTkxOTkyOTkzOTk0OTk1OTk2OTk3OTk4OTk5")
+
+func (m *Miner) mining_thread(ctx context.Context, id int, cpu int, thread_attempts *uint64) {
+	// Pin this thread to its assigned core, if there is one.
+	if cpu >= 0 {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		if err := set_thread_affinity(cpu); err != nil {
+			m.log("Error: unable to pin mining thread %d to CPU %d: %v", id, cpu, err)
+		}
+	}
+	// Drop this thread's priority.  The thread is never unlocked, so that it
+	// exits along with the goroutine instead of running others at idle
+	// priority.
+	if m.Idle {
+		runtime.LockOSThread()
+		if err := lower_thread_priority(); err != nil {
+			m.log("Error: unable to lower the priority of mining thread %d: %v", id, err)
+		}
+	}
+
+	// Close the JSON object: '}'
+	final := []byte("fQ==")
+
+	// All per-attempt state lives in the arena, which is allocated once when
+	// the thread starts and reused thereafter.
+	arena := new(mining_arena)
+
+Restart:
+	for {
+		select {
+		case <-ctx.Done():
+			m.log("closing mining thread %d", id)
+			return
+		default:
+		}
+
+		// Get the current difficulty
+		settings := m.Settings()
+
+		// If the difficulty is too high, wait a bit and try again
+		if settings.Difficulty > MaxDifficulty {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		if atomic.LoadUint32(&m.paused) != 0 {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		// Generate a random secret using the runtime's CSPRNG, unless
+		// KeepSecret supplies them.  We don't need to go to excessively
+		// paranoid lengths to ensure the secret has good entropy, as the
+		// secret is going to be redeemed immediately after the solution is
+		// submitted.  18 bytes is 144 bits of preimage security, or 72 bits
+		// of collision resistance, which is plenty.  Another secret is
+		// generated for the server subsidy.
+		if err := m.generate_secrets(arena); err != nil {
+			m.log("mining thread %d: failed to generate secrets: %v", id, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		keep_amount := settings.TotalReward - settings.ServerSubsidy
+
+		// Create the mining payload, a serialized JSON object.
+		// The miner won't get this far if the terms of service aren't agreed
+		// to, so we can safely hard-code acceptance here.
+		now := time.Now()
+		prefix := arena.build_prefix(keep_amount, settings.ServerSubsidy, settings.Difficulty, now)
+		// The prefix is a multiple of 64 bytes, the SHA256 block size, so we
+		// can compute a midstate
+		arena.midstate.Reset()
+		arena.midstate.Write(prefix)
+
+		// Statistics are accumulated locally and published in batches, so
+		// that their collection doesn't contend with hashing.  Apparent
+		// difficulty is only evaluated for hashes which already pass the
+		// 16-bit pre-filter below, which is one in every 65,536.
+		var best uint8
+		busy_since := time.Now()
+		W := int(atomic.LoadUint32(&m.batch_size))
+		hashes := arena.hashes[:W]
+		for i := 0; i < 1000; i++ {
+			// Abandon the payload as soon as another thread has claimed
+			// its keep secret, since any solution to it would be dropped.
+			if m.keep_claimed(arena) {
+				m.record_best_difficulty(best)
+				continue Restart
+			}
+			atomic.AddUint64(&m.attempts, 1000)
+			atomic.AddUint64(thread_attempts, 1000)
+			for j := 0; j < 1000; j += W {
+				// Compute W-many hashes at once
+				arena.midstate.hash_batch(nonce_table[4*i:], nonce_table[4*j:], final, hashes)
+
+				for k := 0; k < W; k++ {
+					if hashes[k][0] == 0 && hashes[k][1] == 0 {
+						if diff := webcash.ApparentDifficulty(hashes[k]); diff > best {
+							best = diff
+						}
+						if webcash.CheckProofOfWork(hashes[k], settings.Difficulty) {
+							// We found a solution!
+							payload := string(bytes.Join([][]byte{prefix, nonce_table[4*i : 4*i+4], nonce_table[4*(j+k) : 4*(j+k)+4], final}, []byte{}))
+							keep := webcash.SecretWebcash{
+								Secret: string(arena.keep),
+								Amount: keep_amount,
+							}
+							err := m.push_solution(ctx, arena, Solution{
+								Hash:       hashes[k],
+								Preimage:   payload,
+								Reward:     keep,
+								Difficulty: settings.Difficulty,
+								Timestamp:  now,
+							})
+							if err != nil {
+								m.log("closing mining thread %d", id)
+								return
+							}
+							// Any other valid solutions in this batch will
+							// conflict with the one that we have already found,
+							// since secrets may be used only once.
+							m.record_best_difficulty(best)
+							continue Restart
+						}
+					}
+				}
+			}
+			// Every 100,000 hashes, or about every few milliseconds,
+			// rest if throttled, and abandon the payload if the rewards
+			// have changed, as at the start of a new epoch, since any
+			// solution to it would be refused.
+			if i%100 == 99 {
+				m.throttle(&busy_since)
+				if current := m.Settings(); current.TotalReward != settings.TotalReward || current.ServerSubsidy != settings.ServerSubsidy {
+					m.record_best_difficulty(best)
+					continue Restart
+				}
+			}
+		}
+		m.record_best_difficulty(best)
+	}
+}
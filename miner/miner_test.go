@@ -0,0 +1,72 @@
+package miner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// With many more threads than the gap limit of recovery, and restarts, keep
+// secrets are still used in turn: each is asked for only once the one before
+// has been claimed, by exactly one solution.
+func TestKeepSecret(t *testing.T) {
+	var mutex sync.Mutex
+	next, asked := 0, 0
+	var claimed []string
+	settings := webcash.ProtocolSettings{Difficulty: 10, TotalReward: 2_000_000_00, ServerSubsidy: 1_000_000_00}
+
+	var solutions []Solution
+	for run := 0; run < 3; run++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		queue := NewSolutionQueue(1)
+		m := New(ctx, settings, queue)
+		m.KeepSecret = func() (string, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			asked++
+			return fmt.Sprintf("keep-%d", next), nil
+		}
+		m.ClaimSecret = func(secret string) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			claimed = append(claimed, secret)
+			next++
+			return nil
+		}
+		m.Resize(24, nil)
+		for len(solutions) < 4*(run+1) {
+			select {
+			case soln := <-queue.C():
+				solutions = append(solutions, soln)
+			case <-ctx.Done():
+				t.Fatal("timed out waiting for solutions")
+			}
+			// Restart some threads, as autotuning does.
+			m.Resize(12+len(solutions)%2*12, nil)
+		}
+		cancel()
+		m.Wait()
+		// A solution pushed as the miner stops claims its secret too.
+		select {
+		case soln := <-queue.C():
+			solutions = append(solutions, soln)
+		default:
+		}
+	}
+
+	if len(claimed) != len(solutions) {
+		t.Fatalf("%d secrets claimed by %d solutions", len(claimed), len(solutions))
+	}
+	for i, soln := range solutions {
+		if want := fmt.Sprintf("keep-%d", i); soln.Reward.Secret != want || claimed[i] != want {
+			t.Errorf("solution %d has the secret %q, and claimed %q, expected %q", i, soln.Reward.Secret, claimed[i], want)
+		}
+	}
+	if asked > len(claimed)+3 {
+		t.Errorf("asked for a keep secret %d times, for %d solutions in 3 runs", asked, len(claimed))
+	}
+}
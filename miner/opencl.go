@@ -0,0 +1,372 @@
+//go:build opencl
+
+package miner
+
+/*
+#cgo !darwin LDFLAGS: -lOpenCL
+#cgo darwin LDFLAGS: -framework OpenCL
+#define CL_TARGET_OPENCL_VERSION 120
+#define CL_USE_DEPRECATED_OPENCL_1_2_APIS
+#include <stdlib.h>
+#ifdef __APPLE__
+#include <OpenCL/opencl.h>
+#else
+#include <CL/cl.h>
+#endif
+*/
+import "C"
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+//go:embed search.cl
+var search_kernel string
+
+// The most solutions a single kernel launch reports.  Any beyond the first
+// conflict with it anyway, since secrets may be used only once.
+const gpu_max_results = 64
+
+// The kernel launch duration the work size is adjusted toward: long enough
+// that launch overhead is negligible, short enough that new settings and
+// cancellation are noticed promptly.
+const (
+	gpu_launch_min = 50 * time.Millisecond
+	gpu_launch_max = 200 * time.Millisecond
+)
+
+// cl_error describes an OpenCL status code.
+type cl_error struct {
+	call   string
+	status C.cl_int
+}
+
+func (e cl_error) Error() string {
+	return fmt.Sprintf("%s: OpenCL error %d", e.call, int(e.status))
+}
+
+func cl_check(call string, status C.cl_int) error {
+	if status != C.CL_SUCCESS {
+		return cl_error{call, status}
+	}
+	return nil
+}
+
+// GPUDevices lists the OpenCL devices available for mining, of every
+// platform.  Devices are numbered in the order listed.
+func GPUDevices() ([]GPUDevice, error) {
+	var num_platforms C.cl_uint
+	if status := C.clGetPlatformIDs(0, nil, &num_platforms); status == C.CL_PLATFORM_NOT_FOUND_KHR || num_platforms == 0 {
+		return nil, nil
+	} else if err := cl_check("clGetPlatformIDs", status); err != nil {
+		return nil, err
+	}
+	platforms := make([]C.cl_platform_id, num_platforms)
+	if err := cl_check("clGetPlatformIDs", C.clGetPlatformIDs(num_platforms, &platforms[0], nil)); err != nil {
+		return nil, err
+	}
+
+	var devices []GPUDevice
+	for _, platform := range platforms {
+		var num_devices C.cl_uint
+		status := C.clGetDeviceIDs(platform, C.CL_DEVICE_TYPE_GPU|C.CL_DEVICE_TYPE_ACCELERATOR, 0, nil, &num_devices)
+		if status == C.CL_DEVICE_NOT_FOUND || num_devices == 0 {
+			continue
+		}
+		if err := cl_check("clGetDeviceIDs", status); err != nil {
+			return nil, err
+		}
+		ids := make([]C.cl_device_id, num_devices)
+		if err := cl_check("clGetDeviceIDs", C.clGetDeviceIDs(platform, C.CL_DEVICE_TYPE_GPU|C.CL_DEVICE_TYPE_ACCELERATOR, num_devices, &ids[0], nil)); err != nil {
+			return nil, err
+		}
+		platform_name := platform_info(platform, C.CL_PLATFORM_NAME)
+		for _, id := range ids {
+			var units C.cl_uint
+			C.clGetDeviceInfo(id, C.CL_DEVICE_MAX_COMPUTE_UNITS, C.size_t(unsafe.Sizeof(units)), unsafe.Pointer(&units), nil)
+			devices = append(devices, GPUDevice{
+				Index:        len(devices),
+				Name:         device_info(id, C.CL_DEVICE_NAME),
+				Platform:     platform_name,
+				ComputeUnits: int(units),
+				id:           unsafe.Pointer(id),
+			})
+		}
+	}
+	return devices, nil
+}
+
+func platform_info(platform C.cl_platform_id, param C.cl_platform_info) string {
+	var buf [256]C.char
+	if C.clGetPlatformInfo(platform, param, C.size_t(len(buf)), unsafe.Pointer(&buf[0]), nil) != C.CL_SUCCESS {
+		return "unknown"
+	}
+	return C.GoString(&buf[0])
+}
+
+func device_info(id C.cl_device_id, param C.cl_device_info) string {
+	var buf [256]C.char
+	if C.clGetDeviceInfo(id, param, C.size_t(len(buf)), unsafe.Pointer(&buf[0]), nil) != C.CL_SUCCESS {
+		return "unknown"
+	}
+	return C.GoString(&buf[0])
+}
+
+// A gpu_session holds the OpenCL objects used to mine on one device.
+type gpu_session struct {
+	context  C.cl_context
+	queue    C.cl_command_queue
+	program  C.cl_program
+	kernel   C.cl_kernel
+	midstate C.cl_mem
+	nonces   C.cl_mem
+	results  C.cl_mem
+}
+
+// open_gpu_session compiles the search kernel for a device and allocates its
+// buffers.
+func open_gpu_session(dev GPUDevice) (s *gpu_session, err error) {
+	id := C.cl_device_id(dev.id)
+	s = new(gpu_session)
+	defer func() {
+		if err != nil {
+			s.close()
+		}
+	}()
+
+	var status C.cl_int
+	s.context = C.clCreateContext(nil, 1, &id, nil, nil, &status)
+	if err := cl_check("clCreateContext", status); err != nil {
+		return nil, err
+	}
+	s.queue = C.clCreateCommandQueue(s.context, id, 0, &status)
+	if err := cl_check("clCreateCommandQueue", status); err != nil {
+		return nil, err
+	}
+
+	source := C.CString(search_kernel)
+	defer C.free(unsafe.Pointer(source))
+	s.program = C.clCreateProgramWithSource(s.context, 1, &source, nil, &status)
+	if err := cl_check("clCreateProgramWithSource", status); err != nil {
+		return nil, err
+	}
+	if status := C.clBuildProgram(s.program, 1, &id, nil, nil, nil); status != C.CL_SUCCESS {
+		var log [4096]C.char
+		C.clGetProgramBuildInfo(s.program, id, C.CL_PROGRAM_BUILD_LOG, C.size_t(len(log)), unsafe.Pointer(&log[0]), nil)
+		return nil, fmt.Errorf("%w:\n%s", cl_error{"clBuildProgram", status}, C.GoString(&log[0]))
+	}
+	name := C.CString("search")
+	defer C.free(unsafe.Pointer(name))
+	s.kernel = C.clCreateKernel(s.program, name, &status)
+	if err := cl_check("clCreateKernel", status); err != nil {
+		return nil, err
+	}
+
+	s.midstate = C.clCreateBuffer(s.context, C.CL_MEM_READ_ONLY, 8*4, nil, &status)
+	if err := cl_check("clCreateBuffer", status); err != nil {
+		return nil, err
+	}
+	s.nonces = C.clCreateBuffer(s.context, C.CL_MEM_READ_ONLY|C.CL_MEM_COPY_HOST_PTR, C.size_t(len(nonce_table)), unsafe.Pointer(&nonce_table[0]), &status)
+	if err := cl_check("clCreateBuffer", status); err != nil {
+		return nil, err
+	}
+	s.results = C.clCreateBuffer(s.context, C.CL_MEM_READ_WRITE, 4*(1+gpu_max_results), nil, &status)
+	if err := cl_check("clCreateBuffer", status); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// close releases whichever of the session's objects were created.
+func (s *gpu_session) close() {
+	for _, mem := range []C.cl_mem{s.results, s.nonces, s.midstate} {
+		if mem != nil {
+			C.clReleaseMemObject(mem)
+		}
+	}
+	if s.kernel != nil {
+		C.clReleaseKernel(s.kernel)
+	}
+	if s.program != nil {
+		C.clReleaseProgram(s.program)
+	}
+	if s.queue != nil {
+		C.clReleaseCommandQueue(s.queue)
+	}
+	if s.context != nil {
+		C.clReleaseContext(s.context)
+	}
+}
+
+// set_midstate uploads the midstate for subsequent searches.
+func (s *gpu_session) set_midstate(midstate [8]uint32) error {
+	return cl_check("clEnqueueWriteBuffer", C.clEnqueueWriteBuffer(s.queue, s.midstate, C.CL_TRUE, 0, C.size_t(unsafe.Sizeof(midstate)), unsafe.Pointer(&midstate[0]), 0, nil, nil))
+}
+
+// search runs the kernel over the nonces base*1000000 through
+// (base+count)*1000000 - 1, returning those whose hashes meet the
+// difficulty, relative to base*1000000.
+func (s *gpu_session) search(base, count uint32, bits uint64, difficulty uint8) ([]uint32, error) {
+	var results [1 + gpu_max_results]C.cl_uint
+	if err := cl_check("clEnqueueWriteBuffer", C.clEnqueueWriteBuffer(s.queue, s.results, C.CL_TRUE, 0, 4, unsafe.Pointer(&results[0]), 0, nil, nil)); err != nil {
+		return nil, err
+	}
+
+	c_base, c_bits, c_difficulty, c_max := C.cl_uint(base), C.cl_ulong(bits), C.cl_uint(difficulty), C.cl_uint(gpu_max_results)
+	for i, arg := range []struct {
+		size  uintptr
+		value unsafe.Pointer
+	}{
+		{unsafe.Sizeof(s.midstate), unsafe.Pointer(&s.midstate)},
+		{unsafe.Sizeof(s.nonces), unsafe.Pointer(&s.nonces)},
+		{unsafe.Sizeof(c_base), unsafe.Pointer(&c_base)},
+		{unsafe.Sizeof(c_bits), unsafe.Pointer(&c_bits)},
+		{unsafe.Sizeof(c_difficulty), unsafe.Pointer(&c_difficulty)},
+		{unsafe.Sizeof(s.results), unsafe.Pointer(&s.results)},
+		{unsafe.Sizeof(c_max), unsafe.Pointer(&c_max)},
+	} {
+		if err := cl_check("clSetKernelArg", C.clSetKernelArg(s.kernel, C.cl_uint(i), C.size_t(arg.size), arg.value)); err != nil {
+			return nil, err
+		}
+	}
+
+	global := C.size_t(count) * 1000000
+	if err := cl_check("clEnqueueNDRangeKernel", C.clEnqueueNDRangeKernel(s.queue, s.kernel, 1, nil, &global, nil, 0, nil, nil)); err != nil {
+		return nil, err
+	}
+	if err := cl_check("clEnqueueReadBuffer", C.clEnqueueReadBuffer(s.queue, s.results, C.CL_TRUE, 0, C.size_t(unsafe.Sizeof(results)), unsafe.Pointer(&results[0]), 0, nil, nil)); err != nil {
+		return nil, err
+	}
+
+	n := uint32(results[0])
+	if n > gpu_max_results {
+		n = gpu_max_results
+	}
+	found := make([]uint32, n)
+	for i := range found {
+		found[i] = uint32(results[1+i])
+	}
+	return found, nil
+}
+
+// StartGPU starts mining on an OpenCL device, alongside the CPU threads and
+// delivering solutions to the same queue.  It mines until the miner's context
+// is cancelled.  An error is returned if the device cannot be set up.
+func (m *Miner) StartGPU(dev GPUDevice) error {
+	session, err := open_gpu_session(dev)
+	if err != nil {
+		return fmt.Errorf("GPU %d (%s): %w", dev.Index, dev.Name, err)
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer session.close()
+		m.gpu_thread(m.ctx, dev, session)
+	}()
+	return nil
+}
+
+// gpu_thread mines on a GPU.  Payloads are built as for the CPU threads, but
+// with a third nonce digit group, since a GPU exhausts a million nonces in a
+// millisecond or so.  The number of digit groups searched per kernel launch
+// starts at one and is adapted to the device's speed.
+func (m *Miner) gpu_thread(ctx context.Context, dev GPUDevice, session *gpu_session) {
+	arena := new(mining_arena)
+	size := uint32(1)
+	var tail [16]byte
+
+Restart:
+	for {
+		select {
+		case <-ctx.Done():
+			m.log("closing GPU mining thread %d", dev.Index)
+			return
+		default:
+		}
+
+		settings := m.Settings()
+		if settings.Difficulty > MaxDifficulty {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		if atomic.LoadUint32(&m.paused) != 0 {
+			time.Sleep(time.Second)
+			continue
+		}
+		if err := m.generate_secrets(arena); err != nil {
+			m.log("GPU mining thread %d: failed to generate secrets: %v", dev.Index, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		keep_amount := settings.TotalReward - settings.ServerSubsidy
+		now := time.Now()
+		prefix := arena.build_prefix(keep_amount, settings.ServerSubsidy, settings.Difficulty, now)
+		arena.midstate.Reset()
+		arena.midstate.Write(prefix)
+		if err := session.set_midstate(arena.midstate.state()); err != nil {
+			m.log("Error: GPU %d: %v", dev.Index, err)
+			return
+		}
+		bits := uint64(len(prefix)+len(tail)) * 8
+
+		for base, count := uint32(0), size; base < 1000; base += count {
+			if count = size; base+count > 1000 {
+				count = 1000 - base
+			}
+			start := time.Now()
+			found, err := session.search(base, count, bits, settings.Difficulty)
+			if err != nil {
+				m.log("Error: GPU %d: %v", dev.Index, err)
+				return
+			}
+			atomic.AddUint64(&m.attempts, uint64(count)*1000000)
+			if elapsed := time.Since(start); elapsed < gpu_launch_min && size < 1000 && count == size {
+				size *= 2
+			} else if elapsed > gpu_launch_max && size > 1 {
+				size /= 2
+			}
+
+			// Check the GPU's work before trusting it with a report.
+			for _, nonce := range found {
+				gpu_tail(&tail, base*1000000+nonce)
+				var hash webcash.Uint256
+				arena.midstate.WriteSuffix(tail[:], &hash)
+				if !webcash.CheckProofOfWork(hash, settings.Difficulty) {
+					m.log("Error: GPU %d returned an invalid solution", dev.Index)
+					continue
+				}
+				m.record_best_difficulty(webcash.ApparentDifficulty(hash))
+				err := m.push_solution(ctx, arena, Solution{
+					Hash:       hash,
+					Preimage:   string(bytes.Join([][]byte{prefix, tail[:]}, nil)),
+					Reward:     webcash.SecretWebcash{Secret: string(arena.keep), Amount: keep_amount},
+					Difficulty: settings.Difficulty,
+					Timestamp:  now,
+				})
+				if err != nil {
+					m.log("closing GPU mining thread %d", dev.Index)
+					return
+				}
+				// Secrets may be used only once.
+				continue Restart
+			}
+
+			if ctx.Err() != nil {
+				continue Restart
+			}
+			// Solutions would be refused once the rewards change, and
+			// dropped once another has claimed the keep secret.
+			if current := m.Settings(); current.TotalReward != settings.TotalReward || current.ServerSubsidy != settings.ServerSubsidy || m.keep_claimed(arena) {
+				continue Restart
+			}
+		}
+	}
+}
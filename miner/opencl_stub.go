@@ -0,0 +1,17 @@
+//go:build !opencl
+
+package miner
+
+import "errors"
+
+var errNoOpenCL = errors.New("GPU mining is not available: gocash was built without OpenCL support (rebuild with -tags opencl)")
+
+// GPUDevices lists the OpenCL devices available for mining.
+func GPUDevices() ([]GPUDevice, error) {
+	return nil, errNoOpenCL
+}
+
+// StartGPU starts mining on an OpenCL device.
+func (m *Miner) StartGPU(dev GPUDevice) error {
+	return errNoOpenCL
+}
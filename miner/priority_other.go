@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package miner
+
+import (
+	"errors"
+	"runtime"
+)
+
+func lower_thread_priority() error {
+	return errors.New("lowering thread priority is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package miner
+
+import "syscall"
+
+// The nice value of mining threads run at idle priority.
+const idle_nice = 19
+
+// lower_thread_priority gives the calling OS thread the lowest scheduling
+// priority, so that it runs only when nothing else wants the CPU.  On Linux
+// this affects only the calling thread, on the BSDs and macOS the whole
+// process.  The caller must have locked its goroutine to the thread with
+// runtime.LockOSThread, and should leave it locked so that the thread is
+// discarded rather than reused when the goroutine exits.
+func lower_thread_priority() error {
+	// A who of zero refers to the calling thread, or process.
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, idle_nice)
+}
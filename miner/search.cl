@@ -0,0 +1,101 @@
+// OpenCL kernel for the GPU miner.  Each work item hashes one nonce: given the
+// SHA-256 midstate of the base64-encoded mining payload prefix, it completes
+// the hash over the final block, which holds three 4-byte base64 nonce digit
+// groups, the encoding of the closing brace, and the padding.  Work items
+// whose hash meets the difficulty append their global id to results, after a
+// count in results[0].
+
+__constant uint K[64] = {
+	0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+	0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+	0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+	0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+	0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+	0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+	0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+	0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+};
+
+#define ROTR(x, n) rotate((uint)(x), (uint)(32 - (n)))
+#define CH(x, y, z) bitselect((z), (y), (x))
+#define MAJ(x, y, z) bitselect((x), (y), (z) ^ (x))
+#define S0(x) (ROTR((x), 2) ^ ROTR((x), 13) ^ ROTR((x), 22))
+#define S1(x) (ROTR((x), 6) ^ ROTR((x), 11) ^ ROTR((x), 25))
+#define s0(x) (ROTR((x), 7) ^ ROTR((x), 18) ^ ((x) >> 3))
+#define s1(x) (ROTR((x), 17) ^ ROTR((x), 19) ^ ((x) >> 10))
+
+// The base64 encoding of "}", as a big-endian word.
+#define FINAL 0x66513d3d
+
+static uint load_be(__constant const uchar *p)
+{
+	return ((uint)p[0] << 24) | ((uint)p[1] << 16) | ((uint)p[2] << 8) | (uint)p[3];
+}
+
+// search hashes the nonces base*1000000 + get_global_id(0), each of which
+// selects the three digit groups of its message tail from nonces, the
+// numbers "000" through "999" in base64.  bits is the length of the whole
+// message in bits.
+__kernel void search(
+	__constant const uint *midstate,
+	__constant const uchar *nonces,
+	const uint base,
+	const ulong bits,
+	const uint difficulty,
+	__global uint *results,
+	const uint max_results)
+{
+	uint gid = get_global_id(0);
+	uint w[64];
+	uint a, b, c, d, e, f, g, h, t1, t2, zeros;
+	int i;
+
+	w[0] = load_be(nonces + 4 * (base + gid / 1000000));
+	w[1] = load_be(nonces + 4 * (gid / 1000 % 1000));
+	w[2] = load_be(nonces + 4 * (gid % 1000));
+	w[3] = FINAL;
+	w[4] = 0x80000000;
+	for (i = 5; i < 14; ++i) {
+		w[i] = 0;
+	}
+	w[14] = (uint)(bits >> 32);
+	w[15] = (uint)bits;
+	for (i = 16; i < 64; ++i) {
+		w[i] = s1(w[i - 2]) + w[i - 7] + s0(w[i - 15]) + w[i - 16];
+	}
+
+	a = midstate[0];
+	b = midstate[1];
+	c = midstate[2];
+	d = midstate[3];
+	e = midstate[4];
+	f = midstate[5];
+	g = midstate[6];
+	h = midstate[7];
+	for (i = 0; i < 64; ++i) {
+		t1 = h + S1(e) + CH(e, f, g) + K[i] + w[i];
+		t2 = S0(a) + MAJ(a, b, c);
+		h = g;
+		g = f;
+		f = e;
+		e = d + t1;
+		d = c;
+		c = b;
+		b = a;
+		a = t1 + t2;
+	}
+	a += midstate[0];
+	b += midstate[1];
+
+	// Only the leading bits matter.  clz(0) is 32.
+	zeros = clz(a);
+	if (zeros == 32) {
+		zeros += clz(b);
+	}
+	if (zeros >= difficulty) {
+		uint slot = atomic_inc(results);
+		if (slot < max_results) {
+			results[1 + slot] = gid;
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package miner
+
+import (
+	"crypto/sha256"
+	"unsafe"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/.. -I${SRCDIR}/../libsha2/include
+#cgo amd64 386 CFLAGS: -march=znver1
+#include "libsha2/include/sha2/sha256.h"
+#include "libsha2/lib/common.c"
+#include "libsha2/lib/compat/byteswap.c"
+#include "libsha2/lib/sha256.c"
+// ARM
+#include "libsha2/lib/sha256_armv8.c"
+// Intel
+#include "libsha2/lib/sha256_sse4.c"
+#include "libsha2/lib/sha256_sse41.c"
+#include "libsha2/lib/sha256_avx2.c"
+#include "libsha2/lib/sha256_shani.c"
+
+typedef struct sha256_ctx sha256_ctx_t;
+
+void sha256_write_and_finalize8(struct sha256_ctx* ctx, const unsigned char nonce1[4], const unsigned char nonce2[4], const unsigned char final[4], const unsigned char hashes[8*32])
+{
+	unsigned char blocks[8*64] = { 0 };
+	int i;
+	for (i = 0; i < 8; ++i) {
+		memcpy(blocks + 64*i + 0, nonce1, 4);
+		memcpy(blocks + 64*i + 4, nonce2 + 4*i, 4);
+		memcpy(blocks + 64*i + 8, final, 4);
+		blocks[i*64 + 12] = 0x80; // padding byte
+		WriteBE64(blocks + 64*i + 56, (ctx->bytes + 12) << 3);
+	}
+	sha256_midstate((struct sha256*)hashes, ctx->s, blocks, 8);
+}
+
+void sha256_finalize_copy(const struct sha256_ctx* ctx, const unsigned char* suffix, size_t len, unsigned char hash[32])
+{
+	struct sha256_ctx tmp = *ctx;
+	unsigned char pad[64] = { 0x80 };
+	unsigned char length[8];
+	uint64_t bits;
+	int i;
+	if (len) {
+		sha256_update(&tmp, suffix, len);
+	}
+	bits = tmp.bytes << 3;
+	// Pad with 0x80 followed by zeros, up to 8 bytes short of a block boundary
+	sha256_update(&tmp, pad, 1 + (119 - tmp.bytes % 64) % 64);
+	WriteBE64(length, bits);
+	sha256_update(&tmp, length, 8);
+	for (i = 0; i < 8; ++i) {
+		hash[4*i + 0] = tmp.s[i] >> 24;
+		hash[4*i + 1] = tmp.s[i] >> 16;
+		hash[4*i + 2] = tmp.s[i] >> 8;
+		hash[4*i + 3] = tmp.s[i];
+	}
+}
+
+void sha256_write_and_finalize_many(struct sha256_ctx* ctx, const unsigned char nonce1[4], const unsigned char nonce2[4], const unsigned char final[4], const unsigned char* hashes, unsigned int n)
+{
+	for (int k = 0; k < n; ++k) {
+		sha256_write_and_finalize8(ctx, nonce1, &nonce2[k*8*4], final, &hashes[k*8*32]);
+	}
+}
+*/
+import "C"
+
+// Algorithm returns the name of the SHA256 implementation selected for this
+// CPU by libsha2, or by SetImplementation.
+func Algorithm() string {
+	if use_go {
+		return "crypto/sha256 (pure Go)"
+	}
+	return C.GoString(C.sha256_auto_detect())
+}
+
+// Hasher is an incremental SHA-256 hasher backed by the optimized libsha2
+// implementation, or by crypto/sha256 if SetImplementation selects it when the
+// hasher is reset.  Unlike a crypto/sha256 digest, its state can be copied by value, so
+// a midstate computed over a shared prefix can be reused to hash many messages
+// which differ only in their trailing bytes.  This is the primitive which the
+// miner uses internally, exposed so that pool servers and verifiers can do the
+// same.  The zero value is not ready for use; call NewHasher or Reset.
+type Hasher struct {
+	ctx C.sha256_ctx_t
+	// Whether the state is instead that of crypto/sha256, in go_state.
+	is_go    bool
+	go_state [go_state_size]byte
+}
+
+// NewHasher returns a Hasher in its initial state.
+func NewHasher() *Hasher {
+	h := new(Hasher)
+	h.Reset()
+	return h
+}
+
+// Reset returns the hasher to its initial state.
+func (h *Hasher) Reset() {
+	h.is_go = use_go
+	if h.is_go {
+		h.save_go_digest(sha256.New())
+		return
+	}
+	C.sha256_init(&h.ctx)
+}
+
+// Size returns the number of bytes Sum will return.
+func (h *Hasher) Size() int { return 32 }
+
+// BlockSize returns the hash's underlying block size.  A midstate covers all
+// data written so far only when that is a multiple of BlockSize.
+func (h *Hasher) BlockSize() int { return 64 }
+
+// Write absorbs p into the hasher's state.  It never returns an error.
+func (h *Hasher) Write(p []byte) (int, error) {
+	if h.is_go {
+		d := h.go_digest()
+		d.Write(p)
+		h.save_go_digest(d)
+		return len(p), nil
+	}
+	if len(p) > 0 {
+		C.sha256_update(&h.ctx, unsafe.Pointer(&p[0]), C.size_t(len(p)))
+	}
+	return len(p), nil
+}
+
+// Clone returns an independent copy of the hasher's current state.
+func (h *Hasher) Clone() *Hasher {
+	clone := *h
+	return &clone
+}
+
+// WriteSuffix computes the hash of everything written so far followed by
+// suffix, and stores it in dst.  The hasher's own state is not modified, so
+// it can be called repeatedly with different suffixes.
+func (h *Hasher) WriteSuffix(suffix []byte, dst *webcash.Uint256) {
+	if h.is_go {
+		d := h.go_digest()
+		d.Write(suffix)
+		d.Sum(dst[:0])
+		return
+	}
+	var p *C.uchar
+	if len(suffix) > 0 {
+		p = (*C.uchar)(&suffix[0])
+	}
+	C.sha256_finalize_copy(&h.ctx, p, C.size_t(len(suffix)), (*C.uchar)(&dst[0]))
+}
+
+// SumInto stores the hash of everything written so far in dst, without
+// modifying the hasher's state.
+func (h *Hasher) SumInto(dst *webcash.Uint256) {
+	h.WriteSuffix(nil, dst)
+}
+
+// Sum appends the current hash to b and returns the resulting slice, as
+// required by hash.Hash.
+func (h *Hasher) Sum(b []byte) []byte {
+	var hash webcash.Uint256
+	h.SumInto(&hash)
+	return append(b, hash[:]...)
+}
+
+// state returns the eight words of the hash state, which is the midstate of
+// everything written so far if that is a multiple of BlockSize.
+func (h *Hasher) state() (s [8]uint32) {
+	if h.is_go {
+		return h.go_state_words()
+	}
+	for i := range s {
+		s[i] = uint32(h.ctx.s[i])
+	}
+	return s
+}
+
+// hash_batch computes, from the midstate in h, the hashes of each 12-byte
+// message tail nonce1[:4] || nonce2[4*i:4*i+4] || final[:4], storing the i'th
+// in hashes[i].  The number of hashes must be a multiple of 8.
+func (h *Hasher) hash_batch(nonce1, nonce2, final []byte, hashes []webcash.Uint256) {
+	if h.is_go {
+		h.go_hash_batch(nonce1, nonce2, final, hashes)
+		return
+	}
+	C.sha256_write_and_finalize_many(&h.ctx, (*C.uint8_t)(&nonce1[0]), (*C.uint8_t)(&nonce2[0]), (*C.uint8_t)(&final[0]), (*C.uint8_t)(&hashes[0][0]), C.uint(len(hashes)/8))
+}
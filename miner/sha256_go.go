@@ -0,0 +1,83 @@
+package miner
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"hash"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// Whether hashers use Go's crypto/sha256 rather than libsha2.  Set by
+// SetImplementation.
+var use_go bool
+
+// SetImplementation selects the SHA256 implementation of the hashers reset
+// from then on: "auto" for the one libsha2 selects for this CPU, or "go" for
+// Go's crypto/sha256, for a CPU on which libsha2 misbehaves, or to compare the
+// two.  It must be called before mining starts.
+func SetImplementation(name string) error {
+	switch name {
+	case "auto":
+		use_go = false
+	case "go":
+		use_go = true
+	default:
+		return fmt.Errorf("unknown SHA256 implementation %q: expected \"auto\" or \"go\"", name)
+	}
+	return nil
+}
+
+// The length of crypto/sha256's marshaled state: a 4-byte magic number, the
+// eight words of the hash state, the partial block and the length written.
+const go_state_size = 4 + 8*4 + 64 + 8
+
+// go_digest returns a crypto/sha256 digest in the state the hasher holds.  The
+// state is kept marshaled, rather than as the digest itself, so that a Hasher
+// can still be copied by value.
+func (h *Hasher) go_digest() hash.Hash {
+	d := sha256.New()
+	if err := d.(encoding.BinaryUnmarshaler).UnmarshalBinary(h.go_state[:]); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// save_go_digest stores the state of d in the hasher.
+func (h *Hasher) save_go_digest(d hash.Hash) {
+	state, err := d.(encoding.BinaryMarshaler).MarshalBinary()
+	if err == nil && len(state) != go_state_size {
+		err = fmt.Errorf("marshaled state is %d bytes, expected %d", len(state), go_state_size)
+	}
+	if err != nil {
+		panic(err)
+	}
+	copy(h.go_state[:], state)
+}
+
+// go_state_words returns the eight words of the hash state, as state does.
+func (h *Hasher) go_state_words() (s [8]uint32) {
+	for i := range s {
+		s[i] = binary.BigEndian.Uint32(h.go_state[4+4*i:])
+	}
+	return s
+}
+
+// go_hash_batch is hash_batch with crypto/sha256.
+func (h *Hasher) go_hash_batch(nonce1, nonce2, final []byte, hashes []webcash.Uint256) {
+	d := h.go_digest()
+	restore := d.(encoding.BinaryUnmarshaler)
+	var tail [12]byte
+	copy(tail[0:4], nonce1)
+	copy(tail[8:12], final)
+	for i := range hashes {
+		if err := restore.UnmarshalBinary(h.go_state[:]); err != nil {
+			panic(err)
+		}
+		copy(tail[4:8], nonce2[4*i:])
+		d.Write(tail[:])
+		d.Sum(hashes[i][:0])
+	}
+}
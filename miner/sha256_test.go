@@ -0,0 +1,170 @@
+package miner
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// The payload prefix used by the benchmarks, of the same shape and length as
+// a real one.
+func bench_prefix(b *testing.B) (*mining_arena, []byte) {
+	arena := new(mining_arena)
+	if err := arena.generate_secrets(); err != nil {
+		b.Fatal(err)
+	}
+	return arena, arena.build_prefix(20000000000000, 1000000000000, 28, time.Now())
+}
+
+// BenchmarkNaive hashes each attempt the way a simple miner would: formatting
+// the nonce, base64-encoding the whole payload and hashing it from scratch.
+func BenchmarkNaive(b *testing.B) {
+	_, prefix := bench_prefix(b)
+	raw, err := base64.StdEncoding.DecodeString(string(prefix))
+	if err != nil {
+		b.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		payload := string(raw) + strconv.Itoa(i%1000000) + "}"
+		sha256.Sum256([]byte(base64.StdEncoding.EncodeToString([]byte(payload))))
+	}
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "hashes/s")
+}
+
+// BenchmarkHashBatch hashes from a midstate in batches of each allowed size,
+// as the mining threads do.  Each op is one batch.
+func BenchmarkHashBatch(b *testing.B) {
+	for _, size := range []int{8, 40, DefaultBatchSize, max_batch_size} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			arena, prefix := bench_prefix(b)
+			arena.midstate.Reset()
+			arena.midstate.Write(prefix)
+			final := []byte("fQ==")
+			hashes := arena.hashes[:size]
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := time.Now()
+			for n := 0; n < b.N; n++ {
+				i, j := n%1000, n*size%1000
+				arena.midstate.hash_batch(nonce_table[4*i:], nonce_table[4*j:], final, hashes)
+			}
+			b.ReportMetric(float64(b.N*size)/time.Since(start).Seconds(), "hashes/s")
+		})
+	}
+}
+
+// for_each_implementation runs f as a subtest with each SHA256
+// implementation selected.
+func for_each_implementation(t *testing.T, f func(t *testing.T)) {
+	defer SetImplementation("auto")
+	for _, name := range []string{"auto", "go"} {
+		if err := SetImplementation(name); err != nil {
+			t.Fatal(err)
+		}
+		t.Run(name, f)
+	}
+}
+
+// The batched hashes must match those computed the simple way.
+func TestHashBatch(t *testing.T) {
+	for_each_implementation(t, func(t *testing.T) {
+		arena := new(mining_arena)
+		if err := arena.generate_secrets(); err != nil {
+			t.Fatal(err)
+		}
+		prefix := arena.build_prefix(20000000000000, 1000000000000, 28, time.Now())
+		arena.midstate.Reset()
+		arena.midstate.Write(prefix)
+		final := []byte("fQ==")
+		for _, size := range []int{8, 40, DefaultBatchSize, max_batch_size} {
+			hashes := arena.hashes[:size]
+			i, j := 123, 1000-size
+			arena.midstate.hash_batch(nonce_table[4*i:], nonce_table[4*j:], final, hashes)
+			for k := range hashes {
+				payload := string(prefix) + string(nonce_table[4*i:4*i+4]) + string(nonce_table[4*(j+k):4*(j+k)+4]) + string(final)
+				if want := webcash.Uint256(sha256.Sum256([]byte(payload))); hashes[k] != want {
+					t.Fatalf("size %d: hash %d is %v, want %v", size, k, hashes[k], want)
+				}
+			}
+		}
+	})
+}
+
+// Each way of finishing a hash, from every prefix length around the first
+// two block boundaries and with suffixes which cross them, must agree with
+// crypto/sha256.
+func TestHasher(t *testing.T) {
+	for_each_implementation(t, func(t *testing.T) {
+		data := make([]byte, 300)
+		for i := range data {
+			data[i] = byte(i*7 + 3)
+		}
+		lengths := []int{0, 1, 2, 12, 55, 56, 57, 63, 64, 65, 119, 120, 127, 128, 129, 192}
+		for _, n := range lengths {
+			h := NewHasher()
+			h.Write(data[:n])
+			want := webcash.Uint256(sha256.Sum256(data[:n]))
+			var got webcash.Uint256
+			h.SumInto(&got)
+			if got != want {
+				t.Errorf("prefix %d: SumInto is %v, want %v", n, got, want)
+			}
+			if sum := h.Sum([]byte("x")); string(sum) != "x"+string(want[:]) {
+				t.Errorf("prefix %d: Sum is %x, want 78%x", n, sum, want)
+			}
+			for _, m := range lengths {
+				if n+m > len(data) {
+					continue
+				}
+				h.WriteSuffix(data[n:n+m], &got)
+				if want := webcash.Uint256(sha256.Sum256(data[:n+m])); got != want {
+					t.Errorf("prefix %d, suffix %d: WriteSuffix is %v, want %v", n, m, got, want)
+				}
+			}
+			// Neither WriteSuffix nor SumInto changes the state.
+			h.SumInto(&got)
+			if got != want {
+				t.Errorf("prefix %d: state changed by WriteSuffix", n)
+			}
+		}
+	})
+}
+
+// A clone of a midstate carries on independently of the original.
+func TestHasherClone(t *testing.T) {
+	for_each_implementation(t, func(t *testing.T) {
+		prefix := []byte(strings.Repeat("webcash midstate ", 4))[:64]
+		h := NewHasher()
+		h.Write(prefix)
+		clone := h.Clone()
+		clone.Write([]byte("clone"))
+		h.Write([]byte("original"))
+		for _, c := range []struct {
+			name   string
+			h      *Hasher
+			suffix string
+		}{
+			{"original", h, "original"},
+			{"clone", clone, "clone"},
+		} {
+			var got webcash.Uint256
+			c.h.SumInto(&got)
+			if want := webcash.Uint256(sha256.Sum256(append(prefix[:64:64], c.suffix...))); got != want {
+				t.Errorf("%s: hash is %v, want %v", c.name, got, want)
+			}
+		}
+		h.Reset()
+		var got webcash.Uint256
+		h.SumInto(&got)
+		if want := webcash.Uint256(sha256.Sum256(nil)); got != want {
+			t.Errorf("after Reset: hash is %v, want %v", got, want)
+		}
+	})
+}
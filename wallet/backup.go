@@ -0,0 +1,50 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// Backup writes a copy of the wallet to path, everything in it encrypted with
+// passphrase whether or not the wallet itself is encrypted, then reads the copy
+// back to check that it decrypts to the same wallet.  The backup is a wallet
+// file as Save writes, so that restoring it is a matter of loading it, and it
+// holds the master secret even if the wallet keeps it in the keychain.  The
+// wallet itself is unchanged.
+func (w *Wallet) Backup(path string, passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return errors.New("a backup needs a passphrase")
+	}
+	backup := *w
+	backup.Keychain = ""
+	if err := backup.SetPassphrase(passphrase); err != nil {
+		return err
+	}
+	if err := backup.Save(path); err != nil {
+		return err
+	}
+
+	// Load wipes the passphrase it is given, which is the caller's.
+	restored, err := Load(path, func() ([]byte, error) { return append([]byte(nil), passphrase...), nil })
+	if err != nil {
+		return fmt.Errorf("unable to read back the backup: %w", err)
+	}
+	want, err := json.Marshal(&backup)
+	if err != nil {
+		return err
+	}
+	defer webcash.Wipe(want)
+	got, err := json.Marshal(restored)
+	if err != nil {
+		return err
+	}
+	defer webcash.Wipe(got)
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%s does not read back as the wallet written", path)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package wallet
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Webcash = []webcash.SecretWebcash{{Secret: "00ff", Amount: 1_000_000_00}}
+	w.SetLabel("00ff", "from Alice")
+	path := filepath.Join(dir, "backup.webcash")
+	pass := []byte("correct horse")
+	if err := w.Backup(path, pass); err != nil {
+		t.Fatal(err)
+	}
+	if string(pass) != "correct horse" {
+		t.Error("Backup wiped the caller's passphrase")
+	}
+	if w.Encrypted() {
+		t.Error("Backup left the wallet encrypted")
+	}
+	if _, err := Load(path, nil); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("loading the backup without a passphrase: got %v, expected ErrPassphraseRequired", err)
+	}
+	restored, err := Load(path, passphrase("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.MasterSecret != w.MasterSecret || restored.Balance() != w.Balance() || restored.Label("00ff") != "from Alice" {
+		t.Error("the backup does not restore to the same wallet")
+	}
+	if err := w.Backup(path, nil); err == nil {
+		t.Error("Backup without a passphrase succeeded")
+	}
+}
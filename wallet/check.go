@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/webcash"
+)
+
+// A CheckResult is what Check found, and what it did about it.
+type CheckResult struct {
+	// The number of secrets checked with the server.
+	Checked int
+	// The secrets found spent, moved from the wallet's webcash to Archived.
+	Archived []webcash.SecretWebcash
+	// The unconfirmed outputs found to exist, now held by the wallet.
+	Confirmed []webcash.SecretWebcash
+	// The unconfirmed payments found to exist, of the pay chain.  They were
+	// given away, as far as the wallet can tell, so they leave it rather than
+	// being held.
+	Paid []webcash.SecretWebcash
+	// The secrets which the server has no record of, or records a different
+	// amount for.  They are left as they are.
+	Mismatched []Discrepancy
+	// The chains whose depth was advanced past secrets the server knows of,
+	// with any unspent webcash found among them added to the wallet, except
+	// on the pay chain, whose webcash was given away.
+	Repaired []ChainRecovery
+}
+
+// Check checks the wallet against the server, as the reference client's check
+// does: spent secrets are archived, unconfirmed outputs which exist are
+// confirmed, except for payments, which leave the wallet, mismatches are
+// reported, and each chain's depth is moved past
+// any secrets beyond it which are in use, scanning until gap are unused, in
+// batches of batch.  The wallet is changed even if an error is returned, with
+// whatever was done before it.
+func (w *Wallet) Check(c *client.Client, gap, batch int) (CheckResult, error) {
+	var result CheckResult
+	if gap <= 0 || batch <= 0 {
+		return result, errors.New("gap limit and batch size must be positive")
+	}
+
+	payments, err := w.payments()
+	if err != nil {
+		return result, err
+	}
+	held := append(append([]webcash.SecretWebcash(nil), w.Webcash...), w.Unconfirmed...)
+	pks := make([]webcash.PublicWebcash, len(held))
+	for i, sk := range held {
+		pks[i] = webcash.FromSecret(sk)
+	}
+	statuses, err := health_check(c, pks, batch)
+	result.Checked = len(statuses)
+	var spent, confirmed, paid []webcash.SecretWebcash
+	for i, s := range statuses {
+		sk := held[i]
+		unconfirmed := i >= len(w.Webcash)
+		switch {
+		case s.Spent != nil && *s.Spent:
+			spent = append(spent, sk)
+		case unconfirmed && s.Spent != nil && s.Amount != nil && *s.Amount == sk.Amount:
+			// A payment whose replacement was made, but not saved, may
+			// already be in its recipient's hands.
+			if payments[sk.Secret] {
+				paid = append(paid, sk)
+			} else {
+				confirmed = append(confirmed, sk)
+			}
+		case unconfirmed:
+			// The replacement which would have created it may yet be
+			// found to have happened, so it stays unconfirmed.
+		case s.Spent == nil || s.Amount == nil || *s.Amount != sk.Amount:
+			result.Mismatched = append(result.Mismatched, Discrepancy{Held: sk, Status: s})
+		}
+	}
+	w.Webcash = remove_secrets(w.Webcash, spent)
+	w.Unconfirmed = remove_secrets(w.Unconfirmed, spent)
+	w.Unconfirmed = remove_secrets(w.Unconfirmed, confirmed)
+	w.Abandon(paid)
+	w.drop_labels(spent)
+	w.Archived = append(w.Archived, spent...)
+	w.Webcash = append(w.Webcash, confirmed...)
+	result.Archived, result.Confirmed, result.Paid = spent, confirmed, paid
+	if err != nil {
+		return result, err
+	}
+
+	if w.MasterSecret == "" {
+		return result, nil
+	}
+	if w.WalletDepths == nil {
+		w.WalletDepths = make(map[string]uint64)
+	}
+	holds := make(map[string]bool)
+	for _, list := range [][]webcash.SecretWebcash{w.Webcash, w.Unconfirmed} {
+		for _, sk := range list {
+			holds[sk.Secret] = true
+		}
+	}
+	for _, chain := range Chains {
+		before := w.WalletDepths[chain.String()]
+		scan, err := w.scan_chain(c, chain, before, gap, batch, holds, chain != Pay)
+		if err != nil {
+			return result, err
+		}
+		if scan.Depth > before || len(scan.Found) > 0 {
+			result.Repaired = append(result.Repaired, scan)
+		}
+	}
+	return result, nil
+}
+
+// payments returns the secrets of the pay chain below its depth, by which
+// Check tells the unconfirmed payments from the outputs the wallet keeps.
+func (w *Wallet) payments() (map[string]bool, error) {
+	payments := make(map[string]bool)
+	if w.MasterSecret == "" || len(w.Unconfirmed) == 0 {
+		return payments, nil
+	}
+	for depth := uint64(0); depth < w.WalletDepths[Pay.String()]; depth++ {
+		secret, err := w.DeriveSecret(Pay, depth)
+		if err != nil {
+			return nil, err
+		}
+		payments[secret] = true
+	}
+	return payments, nil
+}
@@ -0,0 +1,189 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// Errors from opening an encrypted wallet.
+var (
+	// The wallet is encrypted, and no passphrase was given.
+	ErrPassphraseRequired = errors.New("wallet is encrypted, and needs a passphrase")
+	// The passphrase does not decrypt the wallet.
+	ErrWrongPassphrase = errors.New("wrong passphrase, or the wallet file is damaged")
+	// The wallet has no key to seal with (see Seal).
+	ErrNotEncrypted = errors.New("wallet is not encrypted")
+)
+
+// The scrypt parameters for new keys, which take about 64 MiB and a fraction
+// of a second to derive.
+const (
+	scrypt_n = 1 << 16
+	scrypt_r = 8
+	scrypt_p = 1
+)
+
+// An encrypted wallet file holds the serialized wallet sealed with AES-256-GCM,
+// under a key derived from the passphrase with scrypt.  A reference client
+// cannot read it.
+type encrypted_file struct {
+	Version   string        `json:"version"`
+	Encrypted sealed_wallet `json:"encrypted"`
+}
+
+type sealed_wallet struct {
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// A wallet_key is the key a wallet is encrypted under, kept while it is open
+// so that saving doesn't need the passphrase again.
+type wallet_key struct {
+	n, r, p int
+	salt    []byte
+	aead    cipher.AEAD
+}
+
+// new_key derives a key from passphrase with the given salt and parameters.
+func new_key(passphrase, salt []byte, n, r, p int) (*wallet_key, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer webcash.Wipe(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &wallet_key{n: n, r: r, p: p, salt: salt, aead: aead}, nil
+}
+
+// SetPassphrase sets the passphrase the wallet is encrypted with when saved,
+// with a fresh salt.  An empty passphrase saves it unencrypted.
+func (w *Wallet) SetPassphrase(passphrase []byte) error {
+	if len(passphrase) == 0 {
+		w.key = nil
+		return nil
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := new_key(passphrase, salt, scrypt_n, scrypt_r, scrypt_p)
+	if err != nil {
+		return err
+	}
+	w.key = key
+	return nil
+}
+
+// Encrypted reports whether the wallet is encrypted when saved.
+func (w *Wallet) Encrypted() bool {
+	return w.key != nil
+}
+
+// Seal encrypts data under the wallet's key, for secrets which are kept outside
+// the wallet file but should be no easier to read, such as mining reports
+// awaiting submission.  Without a passphrase it fails with ErrNotEncrypted.
+// What is sealed can only be opened under the same passphrase: after it is
+// changed, Unseal fails with ErrWrongPassphrase.
+func (w *Wallet) Seal(plaintext []byte) ([]byte, error) {
+	if w.key == nil {
+		return nil, ErrNotEncrypted
+	}
+	nonce := make([]byte, w.key.aead.NonceSize(), w.key.aead.NonceSize()+len(plaintext)+w.key.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return w.key.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Unseal decrypts what Seal encrypted.
+func (w *Wallet) Unseal(sealed []byte) ([]byte, error) {
+	if w.key == nil {
+		return nil, ErrNotEncrypted
+	}
+	n := w.key.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, ErrWrongPassphrase
+	}
+	plaintext, err := w.key.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// seal encrypts the serialized wallet.
+func (k *wallet_key) seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(encrypted_file{
+		Version: Version,
+		Encrypted: sealed_wallet{
+			KDF:        "scrypt",
+			N:          k.n,
+			R:          k.r,
+			P:          k.p,
+			Salt:       k.salt,
+			Nonce:      nonce,
+			Ciphertext: k.aead.Seal(nil, nonce, plaintext, nil),
+		},
+	}, "", "  ")
+}
+
+// is_encrypted reports whether a wallet file's contents are encrypted.
+func is_encrypted(data []byte) bool {
+	var probe struct {
+		Encrypted *json.RawMessage `json:"encrypted"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.Encrypted != nil
+}
+
+// open decrypts the contents of an encrypted wallet file, returning the key
+// along with the serialized wallet.
+func open(data, passphrase []byte) (*wallet_key, []byte, error) {
+	var file encrypted_file
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, err
+	}
+	sealed := file.Encrypted
+	if sealed.KDF != "scrypt" {
+		return nil, nil, fmt.Errorf("unknown key derivation function %q", sealed.KDF)
+	}
+	// Bound the work a damaged file can ask for.
+	if sealed.N > 1<<20 || sealed.R > 32 || sealed.P > 16 {
+		return nil, nil, fmt.Errorf("scrypt parameters N=%d r=%d p=%d are out of range", sealed.N, sealed.R, sealed.P)
+	}
+	key, err := new_key(passphrase, sealed.Salt, sealed.N, sealed.R, sealed.P)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sealed.Nonce) != key.aead.NonceSize() {
+		return nil, nil, ErrWrongPassphrase
+	}
+	plaintext, err := key.aead.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return nil, nil, ErrWrongPassphrase
+	}
+	return key, plaintext, nil
+}
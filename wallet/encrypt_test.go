@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// passphrase returns a passphrase function for Load, which hands out a fresh
+// copy each time, as Load wipes it.
+func passphrase(s string) func() ([]byte, error) {
+	return func() ([]byte, error) { return []byte(s), nil }
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Webcash = []webcash.SecretWebcash{{Secret: "00ff", Amount: 1_000_000_00}}
+	if err := w.SetPassphrase([]byte("correct horse")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "wallet.webcash")
+	if err := w.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !is_encrypted(data) {
+		t.Fatal("the saved wallet is not encrypted")
+	}
+	for _, secret := range []string{w.MasterSecret, "00ff"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("the encrypted wallet holds %q in plaintext", secret)
+		}
+	}
+
+	loaded, err := Load(path, passphrase("correct horse"))
+	if err != nil {
+		t.Fatalf("loading with the passphrase: %v", err)
+	}
+	if loaded.MasterSecret != w.MasterSecret || loaded.Balance() != w.Balance() || !loaded.Encrypted() {
+		t.Errorf("loaded master secret %q, balance %v, encrypted %v; expected %q, %v, true", loaded.MasterSecret, loaded.Balance(), loaded.Encrypted(), w.MasterSecret, w.Balance())
+	}
+
+	// Saving a loaded wallet keeps it encrypted, under the same passphrase.
+	if err := loaded.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, passphrase("correct horse")); err != nil {
+		t.Errorf("loading after saving again: %v", err)
+	}
+
+	// An empty passphrase saves it decrypted.
+	loaded.SetPassphrase(nil)
+	if err := loaded.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := Load(path, nil); err != nil || plain.MasterSecret != w.MasterSecret {
+		t.Errorf("loading after decrypting: %v", err)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetPassphrase([]byte("correct horse")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "wallet.webcash")
+	if err := w.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path, passphrase("battery staple")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("loading with the wrong passphrase: got %v, expected ErrWrongPassphrase", err)
+	}
+	if _, err := Load(path, passphrase("")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("loading with an empty passphrase: got %v, expected ErrWrongPassphrase", err)
+	}
+	if _, err := Load(path, nil); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("loading without a passphrase: got %v, expected ErrPassphraseRequired", err)
+	}
+
+	// A damaged file fails as a wrong passphrase does, rather than loading.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged := bytes.Replace(data, []byte(`"ciphertext": "`), []byte(`"ciphertext": "AAAA`), 1)
+	if err := os.WriteFile(path, damaged, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, passphrase("correct horse")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("loading a damaged wallet: got %v, expected ErrWrongPassphrase", err)
+	}
+}
+
+func TestSeal(t *testing.T) {
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Seal([]byte("secret")); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("sealing without a passphrase: got %v, expected ErrNotEncrypted", err)
+	}
+	if err := w.SetPassphrase([]byte("correct horse")); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := w.Seal([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Error("the sealed data holds the plaintext")
+	}
+	if opened, err := w.Unseal(sealed); err != nil || string(opened) != "secret" {
+		t.Errorf("Unseal = %q, %v; expected \"secret\"", opened, err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := w.Unseal(sealed); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("unsealing damaged data: got %v, expected ErrWrongPassphrase", err)
+	}
+
+	// What is sealed under one passphrase can't be opened under another.
+	sealed[len(sealed)-1] ^= 1
+	if err := w.SetPassphrase([]byte("battery staple")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Unseal(sealed); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("unsealing under another passphrase: got %v, expected ErrWrongPassphrase", err)
+	}
+}
@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A Chain is one of the sequences of secrets derived from a wallet's master
+// secret, numbered as in the reference client.
+type Chain uint64
+
+const (
+	// Secrets handed out to receive payments.
+	Receive Chain = iota
+	// Secrets paid to others.
+	Pay
+	// Secrets for the change of payments, kept by the wallet.
+	Change
+	// Secrets for the miner's share of mining rewards.
+	Mining
+)
+
+// Chains are the chains, in order.
+var Chains = []Chain{Receive, Pay, Change, Mining}
+
+var chain_names = []string{"RECEIVE", "PAY", "CHANGE", "MINING"}
+
+// String returns the chain's name as used in the wallet's walletdepths.
+func (c Chain) String() string {
+	if int(c) < len(chain_names) {
+		return chain_names[c]
+	}
+	return fmt.Sprintf("CHAIN%d", uint64(c))
+}
+
+// ParseChain parses the name of a chain, in any case.
+func ParseChain(s string) (Chain, error) {
+	for i, name := range chain_names {
+		if strings.EqualFold(s, name) {
+			return Chain(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown chain %q: expected one of %s", s, strings.Join(chain_names, ", "))
+}
+
+// The wallet has no master secret to derive secrets from.
+var ErrNoMasterSecret = errors.New("wallet has no master secret")
+
+// The tag which domain-separates the derivation, which is hashed in twice as
+// in the tagged hashes of BIP 340.
+var derivation_tag = sha256.Sum256([]byte("webcashwalletv1"))
+
+// DeriveSecret returns the secret at the given depth of a chain: the hex
+// SHA-256 of the tag twice, the master secret, and the chain and depth as
+// 64-bit big-endian integers.  This is the reference client's scheme, so
+// either client derives the same secrets from the same master secret.
+func (w *Wallet) DeriveSecret(chain Chain, depth uint64) (string, error) {
+	if w.MasterSecret == "" {
+		return "", ErrNoMasterSecret
+	}
+	master, err := hex.DecodeString(w.MasterSecret)
+	if err != nil || len(master) != 32 {
+		return "", errors.New("master secret is not 64 hex digits")
+	}
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(chain))
+	binary.BigEndian.PutUint64(buf[8:], depth)
+	h := sha256.New()
+	h.Write(derivation_tag[:])
+	h.Write(derivation_tag[:])
+	h.Write(master)
+	h.Write(buf[:])
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NextSecret derives the next unused secret of a chain and advances the
+// chain's depth past it.  The wallet should be saved before the secret is
+// used, so that it is never handed out twice.
+func (w *Wallet) NextSecret(chain Chain) (string, error) {
+	depth := w.WalletDepths[chain.String()]
+	secret, err := w.DeriveSecret(chain, depth)
+	if err != nil {
+		return "", err
+	}
+	if w.WalletDepths == nil {
+		w.WalletDepths = make(map[string]uint64)
+	}
+	w.WalletDepths[chain.String()] = depth + 1
+	return secret, nil
+}
@@ -0,0 +1,73 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/maaku/gocash/internal/keychain"
+)
+
+// MoveToKeychain keeps the wallet's master secret in the platform's keychain
+// rather than in the wallet, from when it is next saved, so that the wallet's
+// file alone does not give away every secret derived from it.  The wallet is
+// then unusable without the keychain, except through a Backup, which holds the
+// master secret itself.
+func (w *Wallet) MoveToKeychain() error {
+	if w.Keychain != "" {
+		return nil
+	}
+	if w.MasterSecret == "" {
+		return ErrNoMasterSecret
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	account := hex.EncodeToString(id[:])
+	if err := keychain.Set(account, []byte(w.MasterSecret)); err != nil {
+		return fmt.Errorf("unable to keep the master secret in the keychain: %w", err)
+	}
+	// Make sure it can be read back before relying on it.
+	got, err := keychain.Get(account)
+	if err == nil && string(got) != w.MasterSecret {
+		err = errors.New("it reads back differently")
+	}
+	if err != nil {
+		keychain.Delete(account)
+		return fmt.Errorf("unable to keep the master secret in the keychain: %w", err)
+	}
+	w.Keychain = account
+	return nil
+}
+
+// MoveFromKeychain keeps the wallet's master secret in the wallet again, from
+// when it is next saved, returning the keychain account it was kept under.
+// It stays in the keychain until removed with RemoveKeychain, which should
+// wait until the wallet is saved.
+func (w *Wallet) MoveFromKeychain() string {
+	account := w.Keychain
+	w.Keychain = ""
+	return account
+}
+
+// RemoveKeychain removes the master secret kept under account from the
+// keychain, once the wallet it was moved back into is saved.
+func RemoveKeychain(account string) error {
+	return keychain.Delete(account)
+}
+
+// fetch_master fills in the master secret of a wallet just read which keeps
+// it in the keychain.
+func (w *Wallet) fetch_master() error {
+	if w.Keychain == "" {
+		return nil
+	}
+	master, err := keychain.Get(w.Keychain)
+	if err != nil {
+		return fmt.Errorf("unable to read the master secret from the keychain: %w", err)
+	}
+	w.MasterSecret = string(master)
+	return nil
+}
@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"sort"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// Label returns the label of a secret, or "" if it has none.
+func (w *Wallet) Label(secret string) string {
+	return w.Labels[secret]
+}
+
+// SetLabel sets the label of a secret, or with an empty label, removes it.
+func (w *Wallet) SetLabel(secret, label string) {
+	if label == "" {
+		delete(w.Labels, secret)
+		return
+	}
+	if w.Labels == nil {
+		w.Labels = make(map[string]string)
+	}
+	w.Labels[secret] = label
+}
+
+// LabelsOf returns the distinct labels of the given secrets, sorted.
+func (w *Wallet) LabelsOf(secrets []webcash.SecretWebcash) []string {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, sk := range secrets {
+		if label := w.Label(sk.Secret); label != "" && !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// common_label returns the label which all the secrets have, or "" if they
+// differ or any has none.
+func (w *Wallet) common_label(secrets []webcash.SecretWebcash) string {
+	if len(secrets) == 0 {
+		return ""
+	}
+	label := w.Label(secrets[0].Secret)
+	for _, sk := range secrets[1:] {
+		if w.Label(sk.Secret) != label {
+			return ""
+		}
+	}
+	return label
+}
+
+// drop_labels removes the labels of secrets which have left the wallet.
+func (w *Wallet) drop_labels(secrets []webcash.SecretWebcash) {
+	for _, sk := range secrets {
+		delete(w.Labels, sk.Secret)
+	}
+}
@@ -0,0 +1,48 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// TestReplacedLabels checks that the outputs kept from a replacement take the
+// label of its inputs, and that the inputs' labels go with them.
+func TestReplacedLabels(t *testing.T) {
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := webcash.SecretWebcash{Secret: "a", Amount: 3}
+	b := webcash.SecretWebcash{Secret: "b", Amount: 2}
+	w.Webcash = []webcash.SecretWebcash{a, b}
+	w.SetLabel("a", "from Alice")
+	w.SetLabel("b", "from Alice")
+
+	change, err := w.NewOutput(Change, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := w.NewOutput(Pay, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Replaced([]webcash.SecretWebcash{a, b}, []webcash.SecretWebcash{change}, []webcash.SecretWebcash{payment})
+	if w.Label(change.Secret) != "from Alice" || w.Label("a") != "" {
+		t.Errorf("the change is labelled %q, the spent input %q; expected the inputs' label, and none", w.Label(change.Secret), w.Label("a"))
+	}
+
+	// Inputs with different labels leave the change without one.
+	w.SetLabel(change.Secret, "mine")
+	w.Webcash = append(w.Webcash, webcash.SecretWebcash{Secret: "c", Amount: 1})
+	w.SetLabel("c", "from Bob")
+	merged, err := w.NewOutput(Change, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := append([]webcash.SecretWebcash(nil), w.Webcash...)
+	w.Replaced(inputs, []webcash.SecretWebcash{merged}, nil)
+	if w.Label(merged.Secret) != "" || len(w.Labels) != 0 {
+		t.Errorf("the merged output is labelled %q, with labels %v left; expected none", w.Label(merged.Secret), w.Labels)
+	}
+}
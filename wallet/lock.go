@@ -0,0 +1,51 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ErrInUse is returned when another process holds a wallet's lock.
+var ErrInUse = errors.New("wallet is in use by another process")
+
+// A Lock is an exclusive advisory lock on a wallet, so that two processes do
+// not change it at once.  It is taken on a file beside the wallet, since saving
+// replaces the wallet file itself.
+type Lock struct {
+	f *os.File
+}
+
+// LockWallet takes the lock on the wallet at path.  If another process holds
+// it, LockWallet waits for it to be released if wait is set, and otherwise
+// fails with an error matching ErrInUse.
+func LockWallet(path string, wait bool) (*Lock, error) {
+	name := path + ".lock"
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock_file(f, wait); err != nil {
+		f.Close()
+		if !errors.Is(err, ErrInUse) {
+			return nil, fmt.Errorf("unable to lock %s: %w", path, err)
+		}
+		if pid, rerr := os.ReadFile(name); rerr == nil && len(bytes.TrimSpace(pid)) > 0 {
+			return nil, fmt.Errorf("%s: %w (process %s)", path, ErrInUse, bytes.TrimSpace(pid))
+		}
+		return nil, fmt.Errorf("%s: %w", path, ErrInUse)
+	}
+	// Record the holder, for the error above.
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock.  The lock file is left in place, since removing
+// it would race with another process taking the lock.
+func (l *Lock) Unlock() error {
+	l.f.Truncate(0)
+	return l.f.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package wallet
+
+import "os"
+
+// lock_file does nothing, on platforms without file locking.
+func lock_file(f *os.File, wait bool) error {
+	return nil
+}
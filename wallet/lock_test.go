@@ -0,0 +1,27 @@
+package wallet
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default_wallet.webcash")
+	store, err := OpenStore(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The wallet is locked while the store is open.
+	if _, err := OpenStore(path, false); !errors.Is(err, ErrInUse) {
+		t.Errorf("opening the store twice: got %v, expected ErrInUse", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenStore(path, false)
+	if err != nil {
+		t.Fatalf("opening the store after closing it: %v", err)
+	}
+	reopened.Close()
+}
@@ -0,0 +1,26 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package wallet
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock_file takes an exclusive flock on f, waiting for it if wait is set.
+func lock_file(f *os.File, wait bool) error {
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	for {
+		switch err := syscall.Flock(int(f.Fd()), how); err {
+		case syscall.EINTR:
+			continue
+		case syscall.EWOULDBLOCK:
+			return ErrInUse
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package wallet
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var lock_file_ex = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// lock_file takes an exclusive lock on the first byte of f, waiting for it if
+// wait is set.
+func lock_file(f *os.File, wait bool) error {
+	const (
+		lockfile_fail_immediately = 0x1
+		lockfile_exclusive_lock   = 0x2
+		error_lock_violation      = syscall.Errno(33)
+	)
+	flags := uintptr(lockfile_exclusive_lock)
+	if !wait {
+		flags |= lockfile_fail_immediately
+	}
+	var overlapped syscall.Overlapped
+	r, _, err := lock_file_ex.Call(f.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r != 0 {
+		return nil
+	}
+	if err == error_lock_violation {
+		return ErrInUse
+	}
+	return err
+}
@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// The types of log entry.  Insert and payment are those of the reference
+// client.
+const (
+	LogMined   = "mined"
+	LogInsert  = "insert"
+	LogPayment = "payment"
+	LogMerge   = "merge"
+	LogRecover = "recover"
+	LogCheck   = "check"
+)
+
+// A LogEntry is a record of a transaction in the wallet's log.
+type LogEntry struct {
+	// What happened, one of the Log types above.
+	Type string `json:"type"`
+	// The amount of webcash involved.
+	Amount webcash.Amount `json:"amount"`
+	// The change to the balance.
+	Delta Delta `json:"delta"`
+	// The balance afterwards.
+	Balance webcash.Amount `json:"balance"`
+	// A note from the user, if any.
+	Memo string `json:"memo,omitempty"`
+	// The labels of the secrets spent or merged, if any.
+	Labels []string `json:"labels,omitempty"`
+	// When it happened.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// A Delta is a change to the balance, which unlike an Amount may be negative.
+type Delta int64
+
+func (d Delta) String() string {
+	if d < 0 {
+		return "-" + webcash.Amount(-d).String()
+	}
+	return "+" + webcash.Amount(d).String()
+}
+
+func (d Delta) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Delta) UnmarshalJSON(data []byte) error {
+	var inner string
+	if err := json.Unmarshal(data, &inner); err != nil {
+		inner = string(data)
+	}
+	negative := strings.HasPrefix(inner, "-")
+	amount, err := webcash.ParseAmount(strings.TrimLeft(inner, "+-"))
+	if err != nil {
+		return err
+	}
+	*d = Delta(amount)
+	if negative {
+		*d = -*d
+	}
+	return nil
+}
+
+// AddLog appends an entry to the wallet's log, with the wallet's balance, and
+// timestamped now if it isn't already.
+func (w *Wallet) AddLog(entry LogEntry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Balance = w.Balance()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	w.Log = append(w.Log, data)
+	return nil
+}
+
+// History returns the wallet's log, oldest first.  Entries written by the
+// reference client lack the delta and balance, and those which cannot be read
+// at all are left out.
+func (w *Wallet) History() []LogEntry {
+	entries := make([]LogEntry, 0, len(w.Log))
+	for _, data := range w.Log {
+		var entry struct {
+			LogEntry
+			// The reference client writes local times without a zone.
+			Timestamp string `json:"timestamp"`
+		}
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		if err != nil {
+			t, _ = time.ParseInLocation("2006-01-02 15:04:05.999999", entry.Timestamp, time.Local)
+		}
+		entry.LogEntry.Timestamp = t
+		entries = append(entries, entry.LogEntry)
+	}
+	return entries
+}
@@ -0,0 +1,59 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The schema of the wallets written, which counts the changes to what gocash
+// keeps in a wallet.  It is kept apart from Version, which is the reference
+// client's and stays as it expects.  A wallet without a schema is taken to be
+// of schema 0, as written by the reference client or an older gocash.
+const Schema = 2
+
+// ErrNewerSchema is returned for a wallet written by a newer gocash than this
+// one, which might lose what it doesn't know of if it saved the wallet.
+var ErrNewerSchema = errors.New("wallet was written by a newer version of gocash; upgrade gocash to open it")
+
+// A migration upgrades a wallet of the schema before it to its own.
+type migration func(w *Wallet)
+
+// The migrations, the i'th upgrading a wallet of schema i to schema i+1.
+// Changes to the wallet's contents append a migration and bump Schema.
+var migrations = []migration{
+	// The reference client leaves out chains it hasn't derived from yet,
+	// and may not record the legalese at all.
+	func(w *Wallet) {
+		if w.Legalese == nil {
+			w.Legalese = map[string]bool{"terms": false}
+		}
+		if w.WalletDepths == nil {
+			w.WalletDepths = make(map[string]uint64)
+		}
+		for _, chain := range Chains {
+			if _, ok := w.WalletDepths[chain.String()]; !ok {
+				w.WalletDepths[chain.String()] = 0
+			}
+		}
+	},
+	// The master secret may be kept in the keychain, leaving none in the
+	// wallet, which an older gocash would take for a wallet without one.
+	// Nothing needs upgrading.
+	func(w *Wallet) {},
+}
+
+// migrate upgrades a wallet just read to the current schema, in memory; the
+// upgraded wallet is written when it is next saved.  A wallet of a newer
+// schema fails with ErrNewerSchema.
+func (w *Wallet) migrate() error {
+	if w.Schema > Schema {
+		return fmt.Errorf("schema %d: %w", w.Schema, ErrNewerSchema)
+	}
+	if w.Schema < 0 {
+		return fmt.Errorf("invalid schema %d", w.Schema)
+	}
+	for ; w.Schema < Schema; w.Schema++ {
+		migrations[w.Schema](w)
+	}
+	return nil
+}
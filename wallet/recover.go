@@ -0,0 +1,114 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/webcash"
+)
+
+// The defaults for Recover: the number of unused secrets after which a chain
+// is taken to end, as in the reference client, and the number of secrets
+// checked per request.
+const (
+	DefaultGapLimit  = 20
+	DefaultBatchSize = 100
+)
+
+// A ChainRecovery is what Recover found on one chain.
+type ChainRecovery struct {
+	Chain Chain
+	// The number of the chain's secrets which the server knows of, spent or
+	// not.
+	Used int
+	// The unspent webcash found which the wallet did not already hold.  It
+	// is added to the wallet, except for unclaimed payments which are not
+	// swept.
+	Found []webcash.SecretWebcash
+	// Whether Found was added to the wallet.
+	Taken bool
+	// The chain's depth afterwards.
+	Depth uint64
+}
+
+// Recover rederives the secrets of each chain from the master secret, asks the
+// server which of them exist, and adds those unspent to the wallet.  A chain
+// is scanned until gap secrets in a row are unknown to the server, in batches
+// of batch secrets.  Each chain's depth is advanced past the last secret in
+// use, so that none is handed out again.  Unspent webcash on the pay chain was
+// given away, and has yet to be claimed by its recipient, so it is only taken
+// back if sweep is set, as with the reference client's --sweep-payments; it is
+// reported in Found either way.  The wallet is changed even if an error is
+// returned, with whatever was found before it.
+func (w *Wallet) Recover(c *client.Client, gap, batch int, sweep bool) ([]ChainRecovery, error) {
+	if gap <= 0 || batch <= 0 {
+		return nil, errors.New("gap limit and batch size must be positive")
+	}
+	if w.WalletDepths == nil {
+		w.WalletDepths = make(map[string]uint64)
+	}
+	held := make(map[string]bool)
+	for _, sk := range w.Webcash {
+		held[sk.Secret] = true
+	}
+
+	var results []ChainRecovery
+	for _, chain := range Chains {
+		result, err := w.scan_chain(c, chain, 0, gap, batch, held, chain != Pay || sweep)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// scan_chain scans a chain from the given depth, as Recover does, finding the
+// unspent webcash which is not in held.  If take is set, what is found is added
+// to the wallet, and to held.
+func (w *Wallet) scan_chain(c *client.Client, chain Chain, from uint64, gap, batch int, held map[string]bool, take bool) (ChainRecovery, error) {
+	result := ChainRecovery{Chain: chain, Taken: take}
+	var next uint64 // one past the last secret in use
+	for depth, unused := from, 0; unused < gap; depth += uint64(batch) {
+		secrets := make([]string, batch)
+		pks := make([]webcash.PublicWebcash, batch)
+		for i := range secrets {
+			secret, err := w.DeriveSecret(chain, depth+uint64(i))
+			if err != nil {
+				return result, err
+			}
+			secrets[i] = secret
+			// The server looks webcash up by hash alone, so any amount
+			// will do.
+			pks[i] = webcash.FromSecret(webcash.SecretWebcash{Secret: secret, Amount: 1_000_000_00})
+		}
+		status, err := c.HealthCheck(pks)
+		if err != nil {
+			return result, fmt.Errorf("%v chain at depth %d: %w", chain, depth, err)
+		}
+		for i, secret := range secrets {
+			s := status[pks[i].Hash]
+			if s.Spent == nil {
+				unused++
+				continue
+			}
+			unused = 0
+			result.Used++
+			next = depth + uint64(i) + 1
+			if !*s.Spent && s.Amount != nil && !held[secret] {
+				sk := webcash.SecretWebcash{Secret: secret, Amount: *s.Amount}
+				result.Found = append(result.Found, sk)
+				if take {
+					w.Webcash = append(w.Webcash, sk)
+					held[secret] = true
+				}
+			}
+		}
+	}
+	if next > w.WalletDepths[chain.String()] {
+		w.WalletDepths[chain.String()] = next
+	}
+	result.Depth = w.WalletDepths[chain.String()]
+	return result, nil
+}
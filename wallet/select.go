@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// A Strategy chooses which of the wallet's webcash to pay with.
+type Strategy interface {
+	// Choose returns secrets from the wallet totalling at least amount,
+	// which the balance is known to cover.
+	Choose(w *Wallet, amount webcash.Amount) []webcash.SecretWebcash
+}
+
+// strategy_func adapts a function to a Strategy.
+type strategy_func func(w *Wallet, amount webcash.Amount) []webcash.SecretWebcash
+
+func (f strategy_func) Choose(w *Wallet, amount webcash.Amount) []webcash.SecretWebcash {
+	return f(w, amount)
+}
+
+// The strategies, by name.
+var Strategies = map[string]Strategy{
+	// A secret of exactly the amount, and otherwise the largest secrets.
+	"fewest-inputs": strategy_func(fewest_inputs),
+	// A secret of exactly the amount, and otherwise whichever of the
+	// smallest secret covering it and the smallest secrets together leaves
+	// less change.
+	"minimize-change": strategy_func(minimize_change),
+	// The secrets the wallet has held longest.
+	"oldest-first": strategy_func(oldest_first),
+	// A single secret if one covers the amount, and otherwise secrets which
+	// were not mined before those which were, so that mined rewards, which
+	// the server can tell apart, are linked to each other and to the
+	// payment as little as possible.
+	"privacy": strategy_func(privacy),
+}
+
+// The strategy used unless another is chosen.
+const DefaultStrategy = "fewest-inputs"
+
+// ParseStrategy returns the strategy of the given name.
+func ParseStrategy(name string) (Strategy, error) {
+	if s, ok := Strategies[name]; ok {
+		return s, nil
+	}
+	names := make([]string, 0, len(Strategies))
+	for name := range Strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown coin selection strategy %q (strategies: %v)", name, names)
+}
+
+// Select chooses webcash from the wallet to pay amount with, as the strategy
+// has it.
+func (w *Wallet) Select(amount webcash.Amount, s Strategy) ([]webcash.SecretWebcash, error) {
+	if balance := w.Balance(); balance < amount {
+		return nil, fmt.Errorf("%w: paying %v from a balance of %v", webcash.ErrInsufficientFunds, amount, balance)
+	}
+	return s.Choose(w, amount), nil
+}
+
+func fewest_inputs(w *Wallet, amount webcash.Amount) []webcash.SecretWebcash {
+	if exact := exact_match(w.Webcash, amount); exact != nil {
+		return exact
+	}
+	return accumulate(sorted_secrets(w.Webcash, false), amount)
+}
+
+func minimize_change(w *Wallet, amount webcash.Amount) []webcash.SecretWebcash {
+	if exact := exact_match(w.Webcash, amount); exact != nil {
+		return exact
+	}
+	smallest := accumulate(sorted_secrets(w.Webcash, true), amount)
+	if single := smallest_covering(w.Webcash, amount); single != nil && single[0].Amount <= total(smallest) {
+		return single
+	}
+	return smallest
+}
+
+func oldest_first(w *Wallet, amount webcash.Amount) []webcash.SecretWebcash {
+	return accumulate(append([]webcash.SecretWebcash(nil), w.Webcash...), amount)
+}
+
+func privacy(w *Wallet, amount webcash.Amount) []webcash.SecretWebcash {
+	mined := w.mined_secrets()
+	var others, rewards []webcash.SecretWebcash
+	for _, sk := range w.Webcash {
+		if mined[sk.Secret] {
+			rewards = append(rewards, sk)
+		} else {
+			others = append(others, sk)
+		}
+	}
+	if single := smallest_covering(others, amount); single != nil {
+		return single
+	}
+	if single := smallest_covering(rewards, amount); single != nil {
+		return single
+	}
+	return accumulate(append(sorted_secrets(others, false), sorted_secrets(rewards, false)...), amount)
+}
+
+// mined_secrets returns the secrets of the mining chain derived so far.
+func (w *Wallet) mined_secrets() map[string]bool {
+	mined := make(map[string]bool)
+	for depth := uint64(0); depth < w.WalletDepths[Mining.String()]; depth++ {
+		secret, err := w.DeriveSecret(Mining, depth)
+		if err != nil {
+			break
+		}
+		mined[secret] = true
+	}
+	return mined
+}
+
+// exact_match returns a secret of exactly amount, if there is one.
+func exact_match(secrets []webcash.SecretWebcash, amount webcash.Amount) []webcash.SecretWebcash {
+	for _, sk := range secrets {
+		if sk.Amount == amount {
+			return []webcash.SecretWebcash{sk}
+		}
+	}
+	return nil
+}
+
+// smallest_covering returns the smallest secret of at least amount, if there
+// is one.
+func smallest_covering(secrets []webcash.SecretWebcash, amount webcash.Amount) []webcash.SecretWebcash {
+	var best []webcash.SecretWebcash
+	for _, sk := range secrets {
+		if sk.Amount >= amount && (best == nil || sk.Amount < best[0].Amount) {
+			best = []webcash.SecretWebcash{sk}
+		}
+	}
+	return best
+}
+
+// sorted_secrets returns a copy of secrets sorted by amount.
+func sorted_secrets(secrets []webcash.SecretWebcash, ascending bool) []webcash.SecretWebcash {
+	sorted := append([]webcash.SecretWebcash(nil), secrets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if ascending {
+			return sorted[i].Amount < sorted[j].Amount
+		}
+		return sorted[i].Amount > sorted[j].Amount
+	})
+	return sorted
+}
+
+// accumulate returns secrets from the start of the list until they total at
+// least amount.
+func accumulate(secrets []webcash.SecretWebcash, amount webcash.Amount) []webcash.SecretWebcash {
+	var sum webcash.Amount
+	for i, sk := range secrets {
+		sum += sk.Amount
+		if sum >= amount {
+			return secrets[:i+1]
+		}
+	}
+	return secrets
+}
+
+// total returns the total amount of secrets.
+func total(secrets []webcash.SecretWebcash) webcash.Amount {
+	var sum webcash.Amount
+	for _, sk := range secrets {
+		sum += sk.Amount
+	}
+	return sum
+}
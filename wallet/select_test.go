@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+func TestSelect(t *testing.T) {
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two mined secrets, of 5 and 1, and three received, of 2, 4 and 3, in
+	// the order the wallet came to hold them.
+	mined := func(amount webcash.Amount) webcash.SecretWebcash {
+		secret, err := w.NextSecret(Mining)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return webcash.SecretWebcash{Secret: secret, Amount: amount}
+	}
+	received := func(secret string, amount webcash.Amount) webcash.SecretWebcash {
+		return webcash.SecretWebcash{Secret: secret, Amount: amount}
+	}
+	w.Webcash = []webcash.SecretWebcash{
+		mined(5), received("two", 2), received("four", 4), mined(1), received("three", 3),
+	}
+
+	tests := []struct {
+		strategy string
+		amount   webcash.Amount
+		want     []webcash.Amount
+	}{
+		{"fewest-inputs", 4, []webcash.Amount{4}},
+		{"fewest-inputs", 6, []webcash.Amount{5, 4}},
+		{"fewest-inputs", 15, []webcash.Amount{5, 4, 3, 2, 1}},
+		{"minimize-change", 3, []webcash.Amount{3}},
+		{"minimize-change", 6, []webcash.Amount{1, 2, 3}},
+		{"minimize-change", 5, []webcash.Amount{5}},
+		{"oldest-first", 6, []webcash.Amount{5, 2}},
+		{"oldest-first", 1, []webcash.Amount{5}},
+		// A received secret covers it, so no mined one is used.
+		{"privacy", 4, []webcash.Amount{4}},
+		{"privacy", 5, []webcash.Amount{5}},
+		// Received secrets are used up before any mined one.
+		{"privacy", 10, []webcash.Amount{4, 3, 2, 5}},
+	}
+	for _, test := range tests {
+		s, err := ParseStrategy(test.strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := w.Select(test.amount, s)
+		if err != nil {
+			t.Errorf("%s of %v: %v", test.strategy, test.amount, err)
+			continue
+		}
+		amounts := make([]webcash.Amount, len(got))
+		for i, sk := range got {
+			amounts[i] = sk.Amount
+		}
+		if !equal_amounts(amounts, test.want) {
+			t.Errorf("%s of %v chose %v, expected %v", test.strategy, test.amount, amounts, test.want)
+		}
+		if total(got) < test.amount {
+			t.Errorf("%s of %v chose only %v", test.strategy, test.amount, total(got))
+		}
+	}
+
+	for name, s := range Strategies {
+		if _, err := w.Select(16, s); !errors.Is(err, webcash.ErrInsufficientFunds) {
+			t.Errorf("%s of more than the balance: got %v, expected ErrInsufficientFunds", name, err)
+		}
+	}
+	if _, err := ParseStrategy("largest-first"); err == nil {
+		t.Error("ParseStrategy accepted an unknown strategy")
+	}
+}
+
+func equal_amounts(a, b []webcash.Amount) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,488 @@
+//go:build sqlite
+
+package wallet
+
+/*
+#cgo LDFLAGS: -lsqlite3
+#include <stdlib.h>
+#include <sqlite3.h>
+
+// SQLITE_TRANSIENT is a cast which cgo cannot express.
+static int bind_text(sqlite3_stmt *stmt, int i, const char *text, int n) {
+	return sqlite3_bind_text(stmt, i, text, n, SQLITE_TRANSIENT);
+}
+*/
+import "C"
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"unsafe"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// The schema of the database.  Secrets are indexed by their public hash, so
+// that they can be looked up without loading the wallet; the archived secrets,
+// labels and watched webcash, which grow with the wallet, are kept a row each;
+// and the wallet's other fields are kept as JSON in the meta table.
+const sqlite_schema = `
+PRAGMA journal_mode = WAL;
+PRAGMA synchronous = FULL;
+PRAGMA busy_timeout = 5000;
+CREATE TABLE IF NOT EXISTS meta (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS depths (chain TEXT PRIMARY KEY, depth INTEGER NOT NULL);
+CREATE TABLE IF NOT EXISTS webcash (
+	secret TEXT PRIMARY KEY,
+	hash TEXT NOT NULL,
+	amount INTEGER NOT NULL,
+	unconfirmed INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webcash_hash ON webcash (hash);
+CREATE TABLE IF NOT EXISTS archived (secret TEXT PRIMARY KEY, amount INTEGER NOT NULL);
+CREATE TABLE IF NOT EXISTS labels (secret TEXT PRIMARY KEY, label TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS watched (hash TEXT PRIMARY KEY, amount INTEGER NOT NULL);
+CREATE TABLE IF NOT EXISTS log (id INTEGER PRIMARY KEY, entry TEXT NOT NULL);
+`
+
+// A SQLiteStore keeps a wallet in a SQLite database, for wallets of many
+// thousands of secrets.  Saving writes only what changed since the wallet was
+// loaded or last saved, in one transaction, and the database is in WAL mode
+// so that a crash part way through leaves the previous state.  Encryption is
+// not supported.
+type SQLiteStore struct {
+	path string
+	db   *C.sqlite3
+	lock *Lock
+
+	// What the database held as of the last load or save: the webcash by
+	// secret, and the rows of the archived, labels and watched tables.
+	saved    map[string]saved_secret
+	archived map[string]interface{}
+	labels   map[string]interface{}
+	watched  map[string]interface{}
+	// The number of log entries in the database.
+	logged int
+}
+
+type saved_secret struct {
+	amount      webcash.Amount
+	unconfirmed bool
+}
+
+// OpenSQLite opens the SQLite database at path, creating it if need be, and
+// locks it as OpenStore does.
+func OpenSQLite(path string, wait bool) (Store, error) {
+	lock, err := LockWallet(path, wait)
+	if err != nil {
+		return nil, err
+	}
+	// Create the file first, so that it and its journal are private.
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	f.Close()
+
+	s := &SQLiteStore{path: path, lock: lock}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if rc := C.sqlite3_open_v2(cpath, &s.db, C.SQLITE_OPEN_READWRITE|C.SQLITE_OPEN_FULLMUTEX, nil); rc != C.SQLITE_OK {
+		err := s.error()
+		C.sqlite3_close(s.db)
+		lock.Unlock()
+		return nil, err
+	}
+	if err := s.exec_script(sqlite_schema); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLiteStore) Path() string {
+	return s.path
+}
+
+func (s *SQLiteStore) Close() error {
+	defer s.lock.Unlock()
+	if rc := C.sqlite3_close(s.db); rc != C.SQLITE_OK {
+		return s.error()
+	}
+	return nil
+}
+
+// Lookup finds the webcash held by the wallet with the given public hash, as
+// of the last save, using the database's index rather than searching the
+// wallet.
+func (s *SQLiteStore) Lookup(hash webcash.Uint256) (webcash.SecretWebcash, bool, error) {
+	var sk webcash.SecretWebcash
+	found := false
+	err := s.query("SELECT secret, amount FROM webcash WHERE hash = ? AND unconfirmed = 0", []interface{}{hex.EncodeToString(hash[:])}, func(stmt *C.sqlite3_stmt) error {
+		sk = webcash.SecretWebcash{Secret: column_text(stmt, 0), Amount: webcash.Amount(C.sqlite3_column_int64(stmt, 1))}
+		found = true
+		return nil
+	})
+	return sk, found, err
+}
+
+func (s *SQLiteStore) Load(passphrase func() ([]byte, error)) (*Wallet, error) {
+	meta := make(map[string]string)
+	err := s.query("SELECT key, value FROM meta", nil, func(stmt *C.sqlite3_stmt) error {
+		meta[column_text(stmt, 0)] = column_text(stmt, 1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := meta["version"]; !ok {
+		return nil, fmt.Errorf("%s: %w", s.path, os.ErrNotExist)
+	}
+	w := &Wallet{
+		Version:      meta["version"],
+		MasterSecret: meta["master_secret"],
+		Keychain:     meta["keychain"],
+		WalletDepths: make(map[string]uint64),
+	}
+	if schema, ok := meta["schema"]; ok {
+		if w.Schema, err = strconv.Atoi(schema); err != nil {
+			return nil, fmt.Errorf("%s: schema: %w", s.path, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(meta["legalese"]), &w.Legalese); err != nil {
+		return nil, fmt.Errorf("%s: legalese: %w", s.path, err)
+	}
+	// Databases made by earlier versions kept the archived secrets, labels
+	// and watched webcash as JSON in the meta table.  They are moved to their
+	// tables by the next save.
+	if watched, ok := meta["watched"]; ok {
+		var codes []string
+		if err := json.Unmarshal([]byte(watched), &codes); err != nil {
+			return nil, fmt.Errorf("%s: watched: %w", s.path, err)
+		}
+		if w.Watched, err = parse_public(codes); err != nil {
+			return nil, fmt.Errorf("%s: watched: %w", s.path, err)
+		}
+	}
+	if archived, ok := meta["archived"]; ok {
+		var codes []string
+		if err := json.Unmarshal([]byte(archived), &codes); err != nil {
+			return nil, fmt.Errorf("%s: archived: %w", s.path, err)
+		}
+		if w.Archived, err = parse_secrets(codes); err != nil {
+			return nil, fmt.Errorf("%s: archived: %w", s.path, err)
+		}
+	}
+	if labels, ok := meta["labels"]; ok {
+		if err := json.Unmarshal([]byte(labels), &w.Labels); err != nil {
+			return nil, fmt.Errorf("%s: labels: %w", s.path, err)
+		}
+	}
+	if extra, ok := meta["extra"]; ok {
+		if err := json.Unmarshal([]byte(extra), &w.extra); err != nil {
+			return nil, fmt.Errorf("%s: extra: %w", s.path, err)
+		}
+	}
+
+	err = s.query("SELECT chain, depth FROM depths", nil, func(stmt *C.sqlite3_stmt) error {
+		w.WalletDepths[column_text(stmt, 0)] = uint64(C.sqlite3_column_int64(stmt, 1))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.saved = make(map[string]saved_secret)
+	err = s.query("SELECT secret, amount, unconfirmed FROM webcash ORDER BY rowid", nil, func(stmt *C.sqlite3_stmt) error {
+		sk := webcash.SecretWebcash{Secret: column_text(stmt, 0), Amount: webcash.Amount(C.sqlite3_column_int64(stmt, 1))}
+		unconfirmed := C.sqlite3_column_int64(stmt, 2) != 0
+		if unconfirmed {
+			w.Unconfirmed = append(w.Unconfirmed, sk)
+		} else {
+			w.Webcash = append(w.Webcash, sk)
+		}
+		s.saved[sk.Secret] = saved_secret{sk.Amount, unconfirmed}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.archived = make(map[string]interface{})
+	err = s.query("SELECT secret, amount FROM archived ORDER BY rowid", nil, func(stmt *C.sqlite3_stmt) error {
+		sk := webcash.SecretWebcash{Secret: column_text(stmt, 0), Amount: webcash.Amount(C.sqlite3_column_int64(stmt, 1))}
+		w.Archived = append(w.Archived, sk)
+		s.archived[sk.Secret] = int64(sk.Amount)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.labels = make(map[string]interface{})
+	err = s.query("SELECT secret, label FROM labels", nil, func(stmt *C.sqlite3_stmt) error {
+		secret, label := column_text(stmt, 0), column_text(stmt, 1)
+		w.SetLabel(secret, label)
+		s.labels[secret] = label
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.watched = make(map[string]interface{})
+	err = s.query("SELECT hash, amount FROM watched ORDER BY rowid", nil, func(stmt *C.sqlite3_stmt) error {
+		code, amount := column_text(stmt, 0), C.sqlite3_column_int64(stmt, 1)
+		pk := webcash.PublicWebcash{Amount: webcash.Amount(amount)}
+		hash, err := hex.DecodeString(code)
+		if err != nil || len(hash) != len(pk.Hash) {
+			return fmt.Errorf("%s: watched: bad hash %q", s.path, code)
+		}
+		copy(pk.Hash[:], hash)
+		w.Watch(pk)
+		s.watched[code] = int64(amount)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.query("SELECT entry FROM log ORDER BY id", nil, func(stmt *C.sqlite3_stmt) error {
+		w.Log = append(w.Log, json.RawMessage(column_text(stmt, 0)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logged = len(w.Log)
+	if err := w.migrate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	if err := w.fetch_master(); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	return w, nil
+}
+
+func (s *SQLiteStore) Save(w *Wallet) (err error) {
+	if w.Encrypted() {
+		return errors.New("the SQLite wallet store does not support encryption")
+	}
+	legalese, err := json.Marshal(w.Legalese)
+	if err != nil {
+		return err
+	}
+	extra, err := json.Marshal(w.extra)
+	if err != nil {
+		return err
+	}
+
+	master := w.MasterSecret
+	if w.Keychain != "" {
+		master = ""
+	}
+	if err := s.exec("BEGIN IMMEDIATE"); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			s.exec("ROLLBACK")
+		}
+	}()
+	for key, value := range map[string]string{
+		"version":       w.Version,
+		"schema":        strconv.Itoa(w.Schema),
+		"master_secret": master,
+		"keychain":      w.Keychain,
+		"legalese":      string(legalese),
+		"extra":         string(extra),
+	} {
+		if err := s.exec("INSERT OR REPLACE INTO meta (key, value) VALUES (?, ?)", key, value); err != nil {
+			return err
+		}
+	}
+	if err := s.exec("DELETE FROM meta WHERE key IN ('archived', 'labels', 'watched')"); err != nil {
+		return err
+	}
+	for chain, depth := range w.WalletDepths {
+		if err := s.exec("INSERT OR REPLACE INTO depths (chain, depth) VALUES (?, ?)", chain, int64(depth)); err != nil {
+			return err
+		}
+	}
+
+	// Write only the secrets which changed.
+	current := make(map[string]saved_secret, len(w.Webcash)+len(w.Unconfirmed))
+	for _, list := range []struct {
+		secrets     []webcash.SecretWebcash
+		unconfirmed bool
+	}{{w.Webcash, false}, {w.Unconfirmed, true}} {
+		for _, sk := range list.secrets {
+			state := saved_secret{sk.Amount, list.unconfirmed}
+			current[sk.Secret] = state
+			if old, ok := s.saved[sk.Secret]; ok && old == state {
+				continue
+			}
+			hash := webcash.FromSecret(sk).Hash
+			if err := s.exec("INSERT OR REPLACE INTO webcash (secret, hash, amount, unconfirmed) VALUES (?, ?, ?, ?)",
+				sk.Secret, hex.EncodeToString(hash[:]), int64(sk.Amount), bool_int(list.unconfirmed)); err != nil {
+				return err
+			}
+		}
+	}
+	for secret := range s.saved {
+		if _, ok := current[secret]; !ok {
+			if err := s.exec("DELETE FROM webcash WHERE secret = ?", secret); err != nil {
+				return err
+			}
+		}
+	}
+
+	var archived, labels, watched []sqlite_row
+	for _, sk := range w.Archived {
+		archived = append(archived, sqlite_row{sk.Secret, int64(sk.Amount)})
+	}
+	for secret, label := range w.Labels {
+		labels = append(labels, sqlite_row{secret, label})
+	}
+	for _, pk := range w.Watched {
+		watched = append(watched, sqlite_row{hex.EncodeToString(pk.Hash[:]), int64(pk.Amount)})
+	}
+	saved_archived, err := s.save_rows("archived", "secret", "amount", s.archived, archived)
+	if err != nil {
+		return err
+	}
+	saved_labels, err := s.save_rows("labels", "secret", "label", s.labels, labels)
+	if err != nil {
+		return err
+	}
+	saved_watched, err := s.save_rows("watched", "hash", "amount", s.watched, watched)
+	if err != nil {
+		return err
+	}
+
+	// The log is only appended to, unless it has been rewritten.
+	start := s.logged
+	if len(w.Log) < start {
+		if err := s.exec("DELETE FROM log"); err != nil {
+			return err
+		}
+		start = 0
+	}
+	for _, entry := range w.Log[start:] {
+		if err := s.exec("INSERT INTO log (entry) VALUES (?)", string(entry)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.exec("COMMIT"); err != nil {
+		return err
+	}
+	s.saved = current
+	s.archived, s.labels, s.watched = saved_archived, saved_labels, saved_watched
+	s.logged = len(w.Log)
+	return nil
+}
+
+// A sqlite_row is a row of the archived, labels or watched tables: its key,
+// and its value, a string or an int64.
+type sqlite_row struct {
+	key   string
+	value interface{}
+}
+
+// save_rows brings a table up to date with rows, in their order, writing only
+// those which changed since it held saved.  It returns what the table holds.
+func (s *SQLiteStore) save_rows(table, key, value string, saved map[string]interface{}, rows []sqlite_row) (map[string]interface{}, error) {
+	current := make(map[string]interface{}, len(rows))
+	for _, row := range rows {
+		current[row.key] = row.value
+		if old, ok := saved[row.key]; ok && old == row.value {
+			continue
+		}
+		if err := s.exec("INSERT OR REPLACE INTO "+table+" ("+key+", "+value+") VALUES (?, ?)", row.key, row.value); err != nil {
+			return nil, err
+		}
+	}
+	for k := range saved {
+		if _, ok := current[k]; !ok {
+			if err := s.exec("DELETE FROM "+table+" WHERE "+key+" = ?", k); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return current, nil
+}
+
+func bool_int(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// error returns the database's most recent error.
+func (s *SQLiteStore) error() error {
+	return fmt.Errorf("%s: %s", s.path, C.GoString(C.sqlite3_errmsg(s.db)))
+}
+
+// exec_script runs statements which take no parameters.
+func (s *SQLiteStore) exec_script(script string) error {
+	cscript := C.CString(script)
+	defer C.free(unsafe.Pointer(cscript))
+	if rc := C.sqlite3_exec(s.db, cscript, nil, nil, nil); rc != C.SQLITE_OK {
+		return s.error()
+	}
+	return nil
+}
+
+// exec runs a statement, binding args to its parameters.
+func (s *SQLiteStore) exec(statement string, args ...interface{}) error {
+	return s.query(statement, args, nil)
+}
+
+// query runs a statement, binding args to its parameters and calling row for
+// each row of the result.
+func (s *SQLiteStore) query(statement string, args []interface{}, row func(stmt *C.sqlite3_stmt) error) error {
+	cstatement := C.CString(statement)
+	defer C.free(unsafe.Pointer(cstatement))
+	var stmt *C.sqlite3_stmt
+	if rc := C.sqlite3_prepare_v2(s.db, cstatement, -1, &stmt, nil); rc != C.SQLITE_OK {
+		return s.error()
+	}
+	defer C.sqlite3_finalize(stmt)
+	for i, arg := range args {
+		var rc C.int
+		switch v := arg.(type) {
+		case string:
+			ctext := C.CString(v)
+			rc = C.bind_text(stmt, C.int(i+1), ctext, C.int(len(v)))
+			C.free(unsafe.Pointer(ctext))
+		case int64:
+			rc = C.sqlite3_bind_int64(stmt, C.int(i+1), C.sqlite3_int64(v))
+		default:
+			return fmt.Errorf("cannot bind %T to a statement", arg)
+		}
+		if rc != C.SQLITE_OK {
+			return s.error()
+		}
+	}
+	for {
+		switch C.sqlite3_step(stmt) {
+		case C.SQLITE_DONE:
+			return nil
+		case C.SQLITE_ROW:
+			if row != nil {
+				if err := row(stmt); err != nil {
+					return err
+				}
+			}
+		default:
+			return s.error()
+		}
+	}
+}
+
+// column_text returns a column of the current row as a string.
+func column_text(stmt *C.sqlite3_stmt, i int) string {
+	return C.GoString((*C.char)(unsafe.Pointer(C.sqlite3_column_text(stmt, C.int(i)))))
+}
@@ -0,0 +1,11 @@
+//go:build !sqlite
+
+package wallet
+
+import "errors"
+
+// OpenSQLite opens the SQLite database at path.  This build has no SQLite
+// support; build with -tags sqlite for it.
+func OpenSQLite(path string, wait bool) (Store, error) {
+	return nil, errors.New("this build of gocash has no SQLite support (build with -tags sqlite)")
+}
@@ -0,0 +1,181 @@
+//go:build sqlite
+
+package wallet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// reopen closes a SQLite store and loads its wallet afresh.
+func reopen(t *testing.T, store Store) (Store, *Wallet) {
+	t.Helper()
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenStore(store.Path(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := store.Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, w
+}
+
+func TestSQLiteStore(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "wallet.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { store.Close() }()
+	if _, err := store.Load(nil); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("loading before the first save: got %v, expected os.ErrNotExist", err)
+	}
+
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := webcash.SecretWebcash{Secret: "held", Amount: 5}
+	unconfirmed := webcash.SecretWebcash{Secret: "unconfirmed", Amount: 7}
+	w.Webcash = []webcash.SecretWebcash{held, {Secret: "other", Amount: 1}}
+	w.Unconfirmed = []webcash.SecretWebcash{unconfirmed}
+	w.Archived = []webcash.SecretWebcash{{Secret: "spent 1", Amount: 2}, {Secret: "spent 2", Amount: 3}}
+	w.SetLabel("held", "lunch")
+	w.SetLabel("spent 1", "rent")
+	w.Watch(webcash.FromSecret(held), webcash.FromSecret(webcash.SecretWebcash{Secret: "watched", Amount: 9}))
+	w.AddLog(LogEntry{Type: LogInsert, Amount: 5})
+	if err := store.Save(w); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(what string, loaded *Wallet) {
+		t.Helper()
+		if !reflect.DeepEqual(loaded.Webcash, w.Webcash) || !reflect.DeepEqual(loaded.Unconfirmed, w.Unconfirmed) {
+			t.Errorf("%s: webcash %v and %v, expected %v and %v", what, loaded.Webcash, loaded.Unconfirmed, w.Webcash, w.Unconfirmed)
+		}
+		if len(loaded.Archived) != 0 || len(w.Archived) != 0 {
+			if !reflect.DeepEqual(loaded.Archived, w.Archived) {
+				t.Errorf("%s: archived %v, expected %v", what, loaded.Archived, w.Archived)
+			}
+		}
+		if len(loaded.Labels) != 0 || len(w.Labels) != 0 {
+			if !reflect.DeepEqual(loaded.Labels, w.Labels) {
+				t.Errorf("%s: labels %v, expected %v", what, loaded.Labels, w.Labels)
+			}
+		}
+		if len(loaded.Watched) != 0 || len(w.Watched) != 0 {
+			if !reflect.DeepEqual(loaded.Watched, w.Watched) {
+				t.Errorf("%s: watched %v, expected %v", what, loaded.Watched, w.Watched)
+			}
+		}
+		if len(loaded.Log) != len(w.Log) {
+			t.Errorf("%s: %d log entries, expected %d", what, len(loaded.Log), len(w.Log))
+		}
+	}
+	store, loaded := reopen(t, store)
+	check("first save", loaded)
+
+	// Only webcash held and confirmed is found by hash.
+	finder := store.(Finder)
+	for _, test := range []struct {
+		sk   webcash.SecretWebcash
+		want bool
+	}{
+		{held, true},
+		{unconfirmed, false},
+		{webcash.SecretWebcash{Secret: "spent 1", Amount: 2}, false},
+		{webcash.SecretWebcash{Secret: "unknown", Amount: 1}, false},
+	} {
+		got, found, err := finder.Lookup(webcash.FromSecret(test.sk).Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != test.want || found && got != test.sk {
+			t.Errorf("Lookup(%s) = %v, %v, expected %v", test.sk.Secret, got, found, test.want)
+		}
+	}
+
+	// Each collection is brought up to date by the next save, including
+	// what was removed from it.
+	w = loaded
+	w.Webcash = w.Webcash[1:]
+	w.Archived = append(w.Archived[1:], held)
+	w.SetLabel("spent 1", "")
+	w.SetLabel("other", "change")
+	w.Watched = w.Watched[:1]
+	w.AddLog(LogEntry{Type: LogPayment, Amount: 5})
+	if err := store.Save(w); err != nil {
+		t.Fatal(err)
+	}
+	store, loaded = reopen(t, store)
+	check("second save", loaded)
+	if _, found, err := store.(Finder).Lookup(webcash.FromSecret(held).Hash); err != nil || found {
+		t.Errorf("Lookup of webcash no longer held = %v, %v", found, err)
+	}
+}
+
+// Databases made by earlier versions kept the archived secrets, labels and
+// watched webcash as JSON in the meta table.
+func TestSQLiteLegacyMeta(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "wallet.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { store.Close() }()
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(w); err != nil {
+		t.Fatal(err)
+	}
+	watched := webcash.FromSecret(webcash.SecretWebcash{Secret: "watched", Amount: 9})
+	for key, value := range map[string]string{
+		"archived": `["e0.00000002:secret:spent"]`,
+		"labels":   `{"spent": "rent"}`,
+		"watched":  `["` + watched.String() + `"]`,
+	} {
+		if err := store.(*SQLiteStore).exec("INSERT INTO meta (key, value) VALUES (?, ?)", key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(what string, loaded *Wallet) {
+		t.Helper()
+		if want := []webcash.SecretWebcash{{Secret: "spent", Amount: 2}}; !reflect.DeepEqual(loaded.Archived, want) {
+			t.Errorf("%s: archived %v, expected %v", what, loaded.Archived, want)
+		}
+		if loaded.Label("spent") != "rent" {
+			t.Errorf("%s: labels %v", what, loaded.Labels)
+		}
+		if want := []webcash.PublicWebcash{watched}; !reflect.DeepEqual(loaded.Watched, want) {
+			t.Errorf("%s: watched %v, expected %v", what, loaded.Watched, want)
+		}
+	}
+	store, loaded := reopen(t, store)
+	check("before saving", loaded)
+	if err := store.Save(loaded); err != nil {
+		t.Fatal(err)
+	}
+	store, loaded = reopen(t, store)
+	check("after saving", loaded)
+
+	// Once saved, they are kept only in their tables.
+	for _, table := range []string{"archived", "labels", "watched"} {
+		if err := store.(*SQLiteStore).exec("DELETE FROM " + table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, loaded = reopen(t, store)
+	if len(loaded.Archived) != 0 || len(loaded.Labels) != 0 || len(loaded.Watched) != 0 {
+		t.Errorf("the meta table still holds %v, %v and %v", loaded.Archived, loaded.Labels, loaded.Watched)
+	}
+}
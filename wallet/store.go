@@ -0,0 +1,160 @@
+package wallet
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// A Store is where a wallet is kept between runs.
+type Store interface {
+	// Path returns where the wallet is kept, for messages.
+	Path() string
+	// Load reads the wallet, as the package's Load does.  If there is no
+	// wallet, the error matches os.ErrNotExist.
+	Load(passphrase func() ([]byte, error)) (*Wallet, error)
+	// Save writes the wallet, so that a failure part way through leaves the
+	// previous one as it was.
+	Save(w *Wallet) error
+	// Close releases the store.
+	Close() error
+}
+
+// A Finder is a Store which keeps an index of the webcash in the wallet by
+// public hash, such as a SQLiteStore, so that it can be looked up without
+// searching the whole wallet.
+type Finder interface {
+	// Lookup returns the webcash held with the given public hash as of the
+	// last save, and whether there is any.
+	Lookup(hash webcash.Uint256) (webcash.SecretWebcash, bool, error)
+}
+
+// OpenStore opens the store at path: a SQLite database if its name ends in
+// ".db", and otherwise a wallet file in the reference client's format.  The
+// wallet is locked until the store is closed, so that no other process changes
+// it meanwhile; see LockWallet for wait.
+func OpenStore(path string, wait bool) (Store, error) {
+	if strings.HasSuffix(path, ".db") {
+		return OpenSQLite(path, wait)
+	}
+	lock, err := LockWallet(path, wait)
+	if err != nil {
+		return nil, err
+	}
+	return FileStore{Name: path, Backups: DefaultBackups, lock: lock}, nil
+}
+
+// The number of previous versions of a wallet file kept by default.
+const DefaultBackups = 3
+
+// A FileStore keeps a wallet in a file of the reference client's format, which
+// is rewritten in full on every save.  The previous versions are kept beside
+// it, the latest as Name.1, up to Name.<Backups>.
+type FileStore struct {
+	Name    string
+	Backups int
+
+	lock *Lock
+}
+
+func (s FileStore) Path() string {
+	return s.Name
+}
+
+func (s FileStore) Load(passphrase func() ([]byte, error)) (*Wallet, error) {
+	return Load(s.Name, passphrase)
+}
+
+func (s FileStore) Save(w *Wallet) error {
+	if err := s.rotate(); err != nil {
+		return fmt.Errorf("unable to keep the previous version of %s: %w", s.Name, err)
+	}
+	return w.Save(s.Name)
+}
+
+// rotate shifts the previous versions along one, the oldest dropping off the
+// end, and keeps the current file as the latest of them.  The current file
+// stays where it is until the save replaces it.
+func (s FileStore) rotate() error {
+	if _, err := os.Stat(s.Name); os.IsNotExist(err) {
+		return nil
+	}
+	for _, version := range s.Versions() {
+		if i, _ := strconv.Atoi(strings.TrimPrefix(version, s.Name+".")); i >= s.Backups {
+			if err := os.Remove(version); err != nil {
+				return err
+			}
+		}
+	}
+	if s.Backups <= 0 {
+		return nil
+	}
+	for i := s.Backups - 1; i >= 1; i-- {
+		if err := os.Rename(s.version(i), s.version(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if os.Link(s.Name, s.version(1)) == nil {
+		return nil
+	}
+	// Not every file system has hard links.
+	return copy_file(s.Name, s.version(1))
+}
+
+// version returns the name of the i'th previous version.
+func (s FileStore) version(i int) string {
+	return fmt.Sprintf("%s.%d", s.Name, i)
+}
+
+// Versions returns the names of the previous versions which exist, latest
+// first, including any beyond Backups which were kept under an earlier
+// setting.
+func (s FileStore) Versions() []string {
+	matches, _ := filepath.Glob(s.Name + ".*")
+	numbers := make([]int, 0, len(matches))
+	for _, match := range matches {
+		if i, err := strconv.Atoi(strings.TrimPrefix(match, s.Name+".")); err == nil && i > 0 && match == s.version(i) {
+			numbers = append(numbers, i)
+		}
+	}
+	sort.Ints(numbers)
+	versions := make([]string, len(numbers))
+	for j, i := range numbers {
+		versions[j] = s.version(i)
+	}
+	return versions
+}
+
+// copy_file copies src to dst, readable only by the user, synced to disk.
+func copy_file(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s FileStore) Close() error {
+	if s.lock == nil {
+		return nil
+	}
+	return s.lock.Unlock()
+}
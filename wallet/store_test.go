@@ -0,0 +1,62 @@
+package wallet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default_wallet.webcash")
+	store, err := OpenStore(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := store.Load(nil); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("loading before the first save: got %v, expected os.ErrNotExist", err)
+	}
+
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each save keeps the one before as a previous version, up to
+	// DefaultBackups of them.
+	for i := 1; i <= DefaultBackups+2; i++ {
+		w.Webcash = append(w.Webcash, webcash.SecretWebcash{Secret: "s", Amount: webcash.Amount(i)})
+		if err := store.Save(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	versions := store.(FileStore).Versions()
+	if len(versions) != DefaultBackups {
+		t.Fatalf("%d previous versions, expected %d", len(versions), DefaultBackups)
+	}
+	for i, version := range versions {
+		old, err := Load(version, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := DefaultBackups + 1 - i; len(old.Webcash) != want {
+			t.Errorf("%s holds %d secrets, expected %d", version, len(old.Webcash), want)
+		}
+	}
+	loaded, err := store.Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Webcash) != DefaultBackups+2 {
+		t.Errorf("loaded %d secrets, expected %d", len(loaded.Webcash), DefaultBackups+2)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("the wallet file has mode %v, expected 0600", info.Mode().Perm())
+	}
+}
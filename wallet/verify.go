@@ -0,0 +1,34 @@
+package wallet
+
+import (
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/webcash"
+)
+
+// A Discrepancy is a secret of the wallet's which the server disagrees about.
+type Discrepancy struct {
+	// The secret, as the wallet holds it.
+	Held webcash.SecretWebcash
+	// What the server has on record for it.
+	Status client.HealthStatus
+}
+
+// Verify asks the server about each of the wallet's confirmed secrets, in
+// batches of batch secrets, returning those it does not agree are unspent and
+// worth what the wallet says.  The wallet is not changed.
+func (w *Wallet) Verify(c *client.Client, batch int) ([]Discrepancy, error) {
+	pks := make([]webcash.PublicWebcash, len(w.Webcash))
+	for i, sk := range w.Webcash {
+		pks[i] = webcash.FromSecret(sk)
+	}
+	statuses, err := health_check(c, pks, batch)
+	var discrepancies []Discrepancy
+	for i, s := range statuses {
+		sk := w.Webcash[i]
+		if s.Spent != nil && !*s.Spent && s.Amount != nil && *s.Amount == sk.Amount {
+			continue
+		}
+		discrepancies = append(discrepancies, Discrepancy{Held: sk, Status: s})
+	}
+	return discrepancies, err
+}
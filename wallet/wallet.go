@@ -0,0 +1,393 @@
+// Package wallet reads and writes webcash wallets in the format of the Python
+// reference client's default_wallet.webcash, so that a wallet can be moved
+// between clients as is.
+package wallet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// The version of the wallet format written.
+const Version = "1.0"
+
+// A Wallet is the contents of a wallet file.  It is not safe for concurrent
+// use.
+type Wallet struct {
+	// The version of the wallet format.
+	Version string
+	// The schema of gocash's additions to it (see Schema).
+	Schema int
+	// The agreements accepted by the wallet's owner, e.g. "terms" for the
+	// server's terms of service.
+	Legalese map[string]bool
+	// The record of the wallet's transactions, one JSON object per entry.
+	// Entries written by other clients are kept as they are.
+	Log []json.RawMessage
+	// The webcash held by the wallet.
+	Webcash []webcash.SecretWebcash
+	// Secrets created for replacements which may not have reached the
+	// server.  They are kept until it is known whether they exist.
+	Unconfirmed []webcash.SecretWebcash
+	// Secrets found spent by Check, kept for the record.
+	Archived []webcash.SecretWebcash
+	// The master secret, 64 hex digits, from which the wallet's secrets are
+	// derived.
+	MasterSecret string
+	// The keychain account the master secret is kept under, if it is kept
+	// in the keychain rather than in the wallet (see MoveToKeychain).
+	Keychain string
+	// The number of secrets derived so far on each chain.
+	WalletDepths map[string]uint64
+	// The labels of the wallet's secrets, e.g. "from Alice", by secret.
+	Labels map[string]string
+	// The public webcash watched, by a watch-only wallet, which holds no
+	// secrets (see NewWatchOnly), or for webcash paid out which is kept an
+	// eye on, such as a paper wallet's.
+	Watched []webcash.PublicWebcash
+
+	// Fields which this package does not know of, kept so that saving the
+	// wallet does not lose them.
+	extra map[string]json.RawMessage
+	// The key the wallet is encrypted under, or nil.
+	key *wallet_key
+}
+
+// The wallet file, as serialized.
+type wallet_file struct {
+	Version      string            `json:"version"`
+	Schema       int               `json:"schema"`
+	Legalese     map[string]bool   `json:"legalese"`
+	Log          []json.RawMessage `json:"log"`
+	Webcash      []string          `json:"webcash"`
+	Unconfirmed  []string          `json:"unconfirmed"`
+	Archived     []string          `json:"archived,omitempty"`
+	MasterSecret string            `json:"master_secret"`
+	Keychain     string            `json:"keychain,omitempty"`
+	WalletDepths map[string]uint64 `json:"walletdepths"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Watched      []string          `json:"watched,omitempty"`
+}
+
+// New returns an empty wallet with a fresh master secret.
+func New() (*Wallet, error) {
+	var master [32]byte
+	if _, err := rand.Read(master[:]); err != nil {
+		return nil, fmt.Errorf("unable to generate master secret: %w", err)
+	}
+	w := &Wallet{
+		Version:      Version,
+		Schema:       Schema,
+		Legalese:     map[string]bool{"terms": false},
+		MasterSecret: hex.EncodeToString(master[:]),
+		WalletDepths: make(map[string]uint64),
+	}
+	for _, chain := range Chains {
+		w.WalletDepths[chain.String()] = 0
+	}
+	return w, nil
+}
+
+// Load reads the wallet file at path.  If it is encrypted, passphrase is called
+// for the passphrase to decrypt it with, which is wiped once the key is
+// derived, and the wallet stays encrypted when saved.  Without a passphrase
+// function, an encrypted wallet fails to load with ErrPassphraseRequired.
+func Load(path string, passphrase func() ([]byte, error)) (*Wallet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var key *wallet_key
+	if is_encrypted(data) {
+		if passphrase == nil {
+			return nil, fmt.Errorf("%s: %w", path, ErrPassphraseRequired)
+		}
+		pass, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		key, data, err = open(data, pass)
+		webcash.Wipe(pass)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer webcash.Wipe(data)
+	}
+	w := new(Wallet)
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := w.fetch_master(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	w.key = key
+	return w, nil
+}
+
+// Save writes the wallet to path, readable only by the user, and encrypted if
+// it has a passphrase.  It is written to a temporary file, synced to disk,
+// which then replaces the old one, so that a crash part way through leaves
+// either the old wallet or the new one, never a mix.
+func (w *Wallet) Save(path string) error {
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+	if w.key != nil {
+		plaintext := data
+		data, err = w.key.seal(plaintext)
+		webcash.Wipe(plaintext)
+		if err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".wallet-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	sync_dir(filepath.Dir(path))
+	return nil
+}
+
+// sync_dir syncs a directory to disk, so that a rename within it is durable.
+// Not every platform can, so failure is ignored.
+func sync_dir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// Balance returns the total of the webcash held by the wallet.
+func (w *Wallet) Balance() webcash.Amount {
+	var total webcash.Amount
+	for _, sk := range w.Webcash {
+		total += sk.Amount
+	}
+	return total
+}
+
+// NewOutput derives the next secret of a chain, holding amount, for the output
+// of a replacement.  It is listed as unconfirmed until the replacement is
+// settled by Replaced or Abandon, so that it is not lost if the replacement
+// happens but the wallet is not saved afterwards.
+func (w *Wallet) NewOutput(chain Chain, amount webcash.Amount) (webcash.SecretWebcash, error) {
+	secret, err := w.NextSecret(chain)
+	if err != nil {
+		return webcash.SecretWebcash{}, err
+	}
+	sk := webcash.SecretWebcash{Secret: secret, Amount: amount}
+	w.Unconfirmed = append(w.Unconfirmed, sk)
+	return sk, nil
+}
+
+// Replaced records a completed replacement: the inputs leave the wallet, the
+// kept and paid outputs leave the unconfirmed list, and the kept outputs join
+// the wallet's webcash.  If the inputs all had the same label, the kept
+// outputs without one of their own take it.
+func (w *Wallet) Replaced(inputs, kept, paid []webcash.SecretWebcash) {
+	label := w.common_label(inputs)
+	w.Webcash = remove_secrets(w.Webcash, inputs)
+	w.drop_labels(inputs)
+	w.Unconfirmed = remove_secrets(w.Unconfirmed, kept)
+	w.Abandon(paid)
+	w.Webcash = append(w.Webcash, kept...)
+	for _, sk := range kept {
+		if w.Label(sk.Secret) == "" {
+			w.SetLabel(sk.Secret, label)
+		}
+	}
+}
+
+// Abandon drops outputs from the unconfirmed list, once the replacement which
+// would have created them is known to have failed.
+func (w *Wallet) Abandon(outputs []webcash.SecretWebcash) {
+	w.Unconfirmed = remove_secrets(w.Unconfirmed, outputs)
+	w.drop_labels(outputs)
+}
+
+// Holds reports whether the wallet holds the given secret.
+func (w *Wallet) Holds(secret string) bool {
+	for _, sk := range w.Webcash {
+		if sk.Secret == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// remove_secrets returns list without the secrets in drop, reusing its storage.
+func remove_secrets(list, drop []webcash.SecretWebcash) []webcash.SecretWebcash {
+	if len(drop) == 0 {
+		return list
+	}
+	dropped := make(map[string]bool, len(drop))
+	for _, sk := range drop {
+		dropped[sk.Secret] = true
+	}
+	kept := list[:0]
+	for _, sk := range list {
+		if !dropped[sk.Secret] {
+			kept = append(kept, sk)
+		}
+	}
+	return kept
+}
+
+func (w *Wallet) MarshalJSON() ([]byte, error) {
+	file := wallet_file{
+		Version:      w.Version,
+		Schema:       w.Schema,
+		Legalese:     w.Legalese,
+		Log:          w.Log,
+		Webcash:      format_secrets(w.Webcash),
+		Unconfirmed:  format_secrets(w.Unconfirmed),
+		Archived:     format_secrets(w.Archived),
+		MasterSecret: w.MasterSecret,
+		Keychain:     w.Keychain,
+		WalletDepths: w.WalletDepths,
+		Labels:       w.Labels,
+		Watched:      format_public(w.Watched),
+	}
+	if file.Log == nil {
+		file.Log = []json.RawMessage{}
+	}
+	if w.Keychain != "" {
+		file.MasterSecret = ""
+	}
+	if len(w.extra) == 0 {
+		return json.Marshal(file)
+	}
+	// Merge in the unknown fields, letting the known ones take precedence.
+	data, err := json.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+	defer webcash.Wipe(data)
+	fields := make(map[string]json.RawMessage)
+	for k, v := range w.extra {
+		fields[k] = v
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+func (w *Wallet) UnmarshalJSON(data []byte) error {
+	var file wallet_file
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, known := range []string{"version", "schema", "legalese", "log", "webcash", "unconfirmed", "archived", "master_secret", "keychain", "walletdepths", "labels", "watched"} {
+		delete(fields, known)
+	}
+
+	webcashes, err := parse_secrets(file.Webcash)
+	if err != nil {
+		return fmt.Errorf("webcash: %w", err)
+	}
+	unconfirmed, err := parse_secrets(file.Unconfirmed)
+	if err != nil {
+		return fmt.Errorf("unconfirmed: %w", err)
+	}
+	archived, err := parse_secrets(file.Archived)
+	if err != nil {
+		return fmt.Errorf("archived: %w", err)
+	}
+	watched, err := parse_public(file.Watched)
+	if err != nil {
+		return fmt.Errorf("watched: %w", err)
+	}
+	if file.WalletDepths == nil {
+		file.WalletDepths = make(map[string]uint64)
+	}
+	*w = Wallet{
+		Version:      file.Version,
+		Schema:       file.Schema,
+		Legalese:     file.Legalese,
+		Log:          file.Log,
+		Webcash:      webcashes,
+		Unconfirmed:  unconfirmed,
+		Archived:     archived,
+		MasterSecret: file.MasterSecret,
+		Keychain:     file.Keychain,
+		WalletDepths: file.WalletDepths,
+		Labels:       file.Labels,
+		Watched:      watched,
+		extra:        fields,
+	}
+	return w.migrate()
+}
+
+// format_secrets returns the claim codes of secrets, as they are kept in the
+// wallet file.
+func format_secrets(secrets []webcash.SecretWebcash) []string {
+	codes := make([]string, len(secrets))
+	for i, sk := range secrets {
+		codes[i] = sk.String()
+	}
+	return codes
+}
+
+// parse_secrets parses claim codes as kept in the wallet file.
+func parse_secrets(codes []string) ([]webcash.SecretWebcash, error) {
+	secrets := make([]webcash.SecretWebcash, 0, len(codes))
+	for _, code := range codes {
+		sk, err := webcash.ParseSecretWebcash(code)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, sk)
+	}
+	return secrets, nil
+}
+
+// format_public returns the public webcash as kept in the wallet file.
+func format_public(pks []webcash.PublicWebcash) []string {
+	if len(pks) == 0 {
+		return nil
+	}
+	codes := make([]string, len(pks))
+	for i, pk := range pks {
+		codes[i] = pk.String()
+	}
+	return codes
+}
+
+// parse_public parses public webcash as kept in the wallet file.
+func parse_public(codes []string) ([]webcash.PublicWebcash, error) {
+	var pks []webcash.PublicWebcash
+	for _, code := range codes {
+		pk, err := webcash.ParsePublicWebcash(code)
+		if err != nil {
+			return nil, err
+		}
+		pks = append(pks, pk)
+	}
+	return pks, nil
+}
@@ -0,0 +1,175 @@
+package wallet
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maaku/gocash/webcash"
+)
+
+// A wallet as the reference client writes it, before it has derived from
+// every chain, with a field this package does not know of.
+const reference_wallet = `{
+  "version": "1.0",
+  "legalese": {"terms": true},
+  "log": [{"type": "insert", "amount": "1", "webcash": "e1:secret:aa", "memo": "", "timestamp": "2022-01-02 03:04:05.678"}],
+  "webcash": ["e1:secret:aa", "e0.5:secret:bb"],
+  "unconfirmed": [],
+  "master_secret": "abababababababababababababababababababababababababababababababab",
+  "walletdepths": {"RECEIVE": 1, "PAY": 2},
+  "future_field": {"kept": true}
+}`
+
+func TestReferenceWallet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default_wallet.webcash")
+	if err := os.WriteFile(path, []byte(reference_wallet), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Balance() != 1_500_000_00 || !w.Legalese["terms"] {
+		t.Errorf("loaded a balance of %v, terms accepted %v; expected 1.5 and true", w.Balance(), w.Legalese["terms"])
+	}
+	// It is upgraded to the current schema, with every chain's depth.
+	if w.Schema != Schema {
+		t.Errorf("loaded schema %d, expected %d", w.Schema, Schema)
+	}
+	for _, chain := range Chains {
+		if _, ok := w.WalletDepths[chain.String()]; !ok {
+			t.Errorf("no depth for the %v chain", chain)
+		}
+	}
+	if w.WalletDepths["PAY"] != 2 {
+		t.Errorf("PAY depth %d, expected 2", w.WalletDepths["PAY"])
+	}
+	if history := w.History(); len(history) != 1 || history[0].Type != LogInsert || history[0].Timestamp.IsZero() {
+		t.Errorf("history %+v, expected the reference client's insert", history)
+	}
+
+	// Saving it keeps what this package does not know of, and the fields
+	// the reference client reads.
+	if err := w.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"version", "legalese", "log", "webcash", "unconfirmed", "master_secret", "walletdepths", "future_field"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("saved wallet lacks %q", name)
+		}
+	}
+	var future struct{ Kept bool }
+	if err := json.Unmarshal(fields["future_field"], &future); err != nil || !future.Kept {
+		t.Errorf("future_field saved as %s", fields["future_field"])
+	}
+	again, err := Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Balance() != w.Balance() || again.MasterSecret != w.MasterSecret || len(again.Log) != 1 {
+		t.Error("the saved wallet does not load as the same wallet")
+	}
+}
+
+func TestNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet.webcash")
+	newer := strings.Replace(reference_wallet, `"version": "1.0",`, `"version": "1.0", "schema": 1000,`, 1)
+	if err := os.WriteFile(path, []byte(newer), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, nil); err == nil || !strings.Contains(err.Error(), ErrNewerSchema.Error()) {
+		t.Errorf("loading a wallet of a newer schema: got %v, expected ErrNewerSchema", err)
+	}
+}
+
+// TestDeriveSecret checks derivation against secrets worked out by hand from
+// the reference client's scheme, so that both clients find the same webcash.
+func TestDeriveSecret(t *testing.T) {
+	w := &Wallet{MasterSecret: strings.Repeat("ab", 32), WalletDepths: map[string]uint64{}}
+	tests := []struct {
+		chain Chain
+		depth uint64
+		want  string
+	}{
+		{Receive, 0, "61b5eb643164924b966246cbabf1f091f614690bdfe4efb35ffee3d15885989e"},
+		{Pay, 0, "94bbe2b806cb5e68332c32e95fdb8293ce3530e938f1714c20252998e471c394"},
+		{Mining, 7, "ef94aad1fddfb1c40f0afdbfe9d39ebad066dc7f4f1998432344d7fd7ea42fa3"},
+	}
+	for _, test := range tests {
+		got, err := w.DeriveSecret(test.chain, test.depth)
+		if err != nil || got != test.want {
+			t.Errorf("DeriveSecret(%v, %d) = %q, %v; expected %q", test.chain, test.depth, got, err, test.want)
+		}
+	}
+
+	first, err := w.NextSecret(Receive)
+	if err != nil || first != tests[0].want || w.WalletDepths["RECEIVE"] != 1 {
+		t.Errorf("NextSecret = %q, %v, depth %d; expected the secret at depth 0, and depth 1", first, err, w.WalletDepths["RECEIVE"])
+	}
+	if _, err := (&Wallet{}).DeriveSecret(Receive, 0); err != ErrNoMasterSecret {
+		t.Errorf("deriving without a master secret: got %v, expected ErrNoMasterSecret", err)
+	}
+}
+
+// TestReplaced checks how a replacement settles the wallet.
+func TestReplaced(t *testing.T) {
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := webcash.SecretWebcash{Secret: "a", Amount: 3}
+	b := webcash.SecretWebcash{Secret: "b", Amount: 2}
+	w.Webcash = []webcash.SecretWebcash{a, b}
+
+	change, err := w.NewOutput(Change, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := w.NewOutput(Pay, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Unconfirmed) != 2 {
+		t.Fatalf("%d unconfirmed outputs, expected 2", len(w.Unconfirmed))
+	}
+	w.Replaced([]webcash.SecretWebcash{a, b}, []webcash.SecretWebcash{change}, []webcash.SecretWebcash{payment})
+	if w.Balance() != 4 || !w.Holds(change.Secret) || w.Holds("a") || w.Holds(payment.Secret) || len(w.Unconfirmed) != 0 {
+		t.Errorf("after the replacement: balance %v, %d unconfirmed", w.Balance(), len(w.Unconfirmed))
+	}
+
+	// Outputs of a replacement which failed leave the unconfirmed list.
+	output, err := w.NewOutput(Receive, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Abandon([]webcash.SecretWebcash{output})
+	if w.Holds(output.Secret) || len(w.Unconfirmed) != 0 || w.Balance() != 4 {
+		t.Errorf("after abandoning an output: balance %v, %d unconfirmed", w.Balance(), len(w.Unconfirmed))
+	}
+}
+
+func TestHistory(t *testing.T) {
+	w, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Webcash = []webcash.SecretWebcash{{Secret: "a", Amount: 4}}
+	if err := w.AddLog(LogEntry{Type: LogPayment, Amount: 1, Delta: -1, Memo: "coffee"}); err != nil {
+		t.Fatal(err)
+	}
+	history := w.History()
+	if len(history) != 1 || history[0].Delta != -1 || history[0].Balance != 4 || history[0].Memo != "coffee" || history[0].Timestamp.IsZero() {
+		t.Errorf("history %+v, expected the payment, with a delta of -1 and a balance of 4", history)
+	}
+}
@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/webcash"
+)
+
+// ErrWatchOnly is returned for what needs secrets which a watch-only wallet
+// does not have.
+var ErrWatchOnly = errors.New("wallet is watch-only, and holds no secrets")
+
+// NewWatchOnly returns an empty watch-only wallet, which tracks whether public
+// webcash is spent without holding its secrets, e.g. for an auditor or a
+// monitoring box.  It has no master secret, so nothing can be spent or derived
+// from it.
+func NewWatchOnly() *Wallet {
+	return &Wallet{
+		Version:      Version,
+		Schema:       Schema,
+		Legalese:     map[string]bool{"terms": false},
+		WalletDepths: make(map[string]uint64),
+	}
+}
+
+// WatchOnly reports whether the wallet is watch-only.
+func (w *Wallet) WatchOnly() bool {
+	return w.MasterSecret == "" && len(w.Webcash) == 0 && len(w.Unconfirmed) == 0
+}
+
+// WatchOnlyCopy returns a watch-only wallet watching the public webcash of
+// everything w holds or watches.
+func (w *Wallet) WatchOnlyCopy() *Wallet {
+	c := NewWatchOnly()
+	c.Legalese = w.Legalese
+	for _, sk := range w.Webcash {
+		c.Watch(webcash.FromSecret(sk))
+	}
+	c.Watch(w.Watched...)
+	return c
+}
+
+// Watch adds public webcash to those watched, returning the number which were
+// not already.
+func (w *Wallet) Watch(pks ...webcash.PublicWebcash) int {
+	watched := make(map[webcash.Uint256]bool, len(w.Watched))
+	for _, pk := range w.Watched {
+		watched[pk.Hash] = true
+	}
+	added := 0
+	for _, pk := range pks {
+		if !watched[pk.Hash] {
+			watched[pk.Hash] = true
+			w.Watched = append(w.Watched, pk)
+			added++
+		}
+	}
+	return added
+}
+
+// CheckWatched asks the server about each of the watched public webcash, in
+// batches of batch, returning what it has on record for each in the order of
+// Watched.
+func (w *Wallet) CheckWatched(c *client.Client, batch int) ([]client.HealthStatus, error) {
+	return health_check(c, w.Watched, batch)
+}
+
+// health_check asks the server about public webcash, batch at a time,
+// returning what it has on record for each in order.  On an error, the
+// statuses of the batches before it are returned.
+func health_check(c *client.Client, pks []webcash.PublicWebcash, batch int) ([]client.HealthStatus, error) {
+	if batch <= 0 {
+		return nil, errors.New("batch size must be positive")
+	}
+	statuses := make([]client.HealthStatus, 0, len(pks))
+	for start := 0; start < len(pks); start += batch {
+		end := start + batch
+		if end > len(pks) {
+			end = len(pks)
+		}
+		status, err := c.HealthCheck(pks[start:end])
+		if err != nil {
+			return statuses, fmt.Errorf("checking webcash %d to %d: %w", start, end, err)
+		}
+		for _, pk := range pks[start:end] {
+			statuses = append(statuses, status[pk.Hash])
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,81 @@
+package webcash
+
+import "time"
+
+// An IssuanceSchedule describes how the server releases new webcash: the
+// mining reward halves at the end of each epoch, which lasts a fixed number
+// of mining reports, and the difficulty is adjusted so that reports arrive
+// at a target rate.
+type IssuanceSchedule struct {
+	// The number of accepted mining reports in each epoch.
+	ReportsPerEpoch uint64
+	// The interval between mining reports the server aims for.
+	ReportInterval time.Duration
+}
+
+// DefaultSchedule is the schedule of the reference server.
+var DefaultSchedule = IssuanceSchedule{
+	ReportsPerEpoch: 525_000,
+	ReportInterval:  10 * time.Second,
+}
+
+// An EpochProjection is the expected start and rewards of an epoch.
+type EpochProjection struct {
+	Epoch uint16
+	// When the epoch is expected to begin.  For the current epoch, this is
+	// the time the projection was made.
+	Start time.Time
+	// The amounts of a mining report during the epoch.
+	TotalReward   Amount
+	ServerSubsidy Amount
+}
+
+// MinerReward returns the part of the total reward which the miner keeps.
+func (p EpochProjection) MinerReward() Amount {
+	return p.TotalReward - p.ServerSubsidy
+}
+
+// Project predicts the current and next count-1 epochs from the server's
+// settings and the number of mining reports already made in the current
+// epoch.  The ratio of issuance to schedule is taken into account as the rate
+// at which reports are currently arriving; the server adjusts the difficulty
+// to bring it back toward 1, so projections further out are less certain.
+//
+// If the number of reports into the epoch is not known, pass zero: the start
+// times are then the latest the epochs could begin.
+func (s IssuanceSchedule) Project(settings ProtocolSettings, reports_into_epoch uint64, now time.Time, count int) []EpochProjection {
+	if count <= 0 || s.ReportsPerEpoch == 0 {
+		return nil
+	}
+	if reports_into_epoch > s.ReportsPerEpoch {
+		reports_into_epoch = s.ReportsPerEpoch
+	}
+	interval := s.ReportInterval
+	if settings.Ratio > 0 {
+		interval = time.Duration(float64(interval) / float64(settings.Ratio))
+	}
+	epoch_length := time.Duration(s.ReportsPerEpoch) * s.ReportInterval
+
+	projections := make([]EpochProjection, 0, count)
+	projections = append(projections, EpochProjection{
+		Epoch:         settings.Epoch,
+		Start:         now,
+		TotalReward:   settings.TotalReward,
+		ServerSubsidy: settings.ServerSubsidy,
+	})
+	// The rest of the current epoch is mined at the current rate, and later
+	// epochs at the target rate.
+	start := now.Add(time.Duration(s.ReportsPerEpoch-reports_into_epoch) * interval)
+	reward, subsidy := settings.TotalReward, settings.ServerSubsidy
+	for i := 1; i < count; i++ {
+		reward, subsidy = reward/2, subsidy/2
+		projections = append(projections, EpochProjection{
+			Epoch:         settings.Epoch + uint16(i),
+			Start:         start,
+			TotalReward:   reward,
+			ServerSubsidy: subsidy,
+		})
+		start = start.Add(epoch_length)
+	}
+	return projections
+}
@@ -0,0 +1,63 @@
+package webcash
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProject(t *testing.T) {
+	s := IssuanceSchedule{ReportsPerEpoch: 100, ReportInterval: 10 * time.Second}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	settings := func(ratio float32) ProtocolSettings {
+		return ProtocolSettings{Difficulty: 28, Ratio: ratio, TotalReward: 200_000_00, ServerSubsidy: 20_000_00, Epoch: 3}
+	}
+	// The offsets from now at which each projected epoch starts.
+	tests := []struct {
+		name     string
+		settings ProtocolSettings
+		reports  uint64
+		count    int
+		starts   []time.Duration
+	}{
+		{"current only", settings(1), 40, 1, []time.Duration{0}},
+		{"on schedule", settings(1), 40, 3, []time.Duration{0, 600 * time.Second, 1600 * time.Second}},
+		// Reports arriving twice as fast finish the current epoch in half the
+		// time, but later epochs are expected at the target rate.
+		{"ahead of schedule", settings(2), 40, 3, []time.Duration{0, 300 * time.Second, 1300 * time.Second}},
+		{"behind schedule", settings(0.5), 40, 2, []time.Duration{0, 1200 * time.Second}},
+		// Without a ratio, the target rate is assumed.
+		{"no ratio", settings(0), 40, 2, []time.Duration{0, 600 * time.Second}},
+		// Not knowing how far into the epoch, the next is at its latest.
+		{"unknown reports", settings(1), 0, 2, []time.Duration{0, 1000 * time.Second}},
+		{"epoch complete", settings(1), 100, 2, []time.Duration{0, 0}},
+		{"more reports than an epoch", settings(1), 250, 2, []time.Duration{0, 0}},
+	}
+	for _, test := range tests {
+		got := s.Project(test.settings, test.reports, now, test.count)
+		if len(got) != len(test.starts) {
+			t.Errorf("%s: %d projections, expected %d", test.name, len(got), len(test.starts))
+			continue
+		}
+		reward, subsidy := test.settings.TotalReward, test.settings.ServerSubsidy
+		for i, p := range got {
+			if p.Epoch != test.settings.Epoch+uint16(i) {
+				t.Errorf("%s: projection %d is of epoch %d, expected %d", test.name, i, p.Epoch, test.settings.Epoch+uint16(i))
+			}
+			if want := now.Add(test.starts[i]); !p.Start.Equal(want) {
+				t.Errorf("%s: epoch %d starts at %v, expected %v", test.name, p.Epoch, p.Start, want)
+			}
+			// The rewards halve with each epoch.
+			if p.TotalReward != reward || p.ServerSubsidy != subsidy || p.MinerReward() != reward-subsidy {
+				t.Errorf("%s: epoch %d rewards %v and %v, expected %v and %v", test.name, p.Epoch, p.TotalReward, p.ServerSubsidy, reward, subsidy)
+			}
+			reward, subsidy = reward/2, subsidy/2
+		}
+	}
+
+	if got := s.Project(settings(1), 40, now, 0); got != nil {
+		t.Errorf("Project of no epochs = %v", got)
+	}
+	if got := (IssuanceSchedule{}).Project(settings(1), 40, now, 2); got != nil {
+		t.Errorf("Project of an empty schedule = %v", got)
+	}
+}
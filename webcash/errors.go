@@ -0,0 +1,18 @@
+package webcash
+
+import "errors"
+
+// Errors which callers may want to handle specially.  Failures are wrapped
+// with context, so compare against these with errors.Is.
+var (
+	// A webcash amount could not be parsed.
+	ErrInvalidAmount = errors.New("invalid amount")
+	// A secret or public webcash could not be parsed.
+	ErrInvalidWebcash = errors.New("invalid webcash")
+	// The available webcash is worth less than the amount requested.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// A webcash has already been spent, or was never issued.
+	ErrSecretAlreadySpent = errors.New("secret already spent")
+	// The server's terms of service have not been accepted.
+	ErrTermsNotAccepted = errors.New("terms of service not accepted")
+)
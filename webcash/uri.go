@@ -0,0 +1,122 @@
+package webcash
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The scheme of webcash URIs.
+const URIScheme = "webcash"
+
+// A URI is a link to hand webcash from one application to another, of the form
+//
+//	webcash:<claim code>?memo=<memo>&expires=<RFC 3339 time>
+//
+// for a payment, or without the claim code, and with an amount, for a request
+// to be paid:
+//
+//	webcash:?amount=<amount>&memo=<memo>&expires=<RFC 3339 time>
+type URI struct {
+	// The webcash paid, or nil for a request for payment.
+	Webcash *SecretWebcash
+	// The amount requested, or for a payment, that of the webcash.  Zero if
+	// a request leaves the amount up to the payer.
+	Amount Amount
+	// A note for the recipient, if any.
+	Memo string
+	// When the link stops being good, or the zero time if it doesn't.
+	Expires time.Time
+}
+
+// ErrExpired is returned for a URI used after it has expired.
+var ErrExpired = errors.New("webcash link has expired")
+
+// NewPaymentURI returns the URI of a payment of sk.
+func NewPaymentURI(sk SecretWebcash, memo string, expires time.Time) URI {
+	return URI{Webcash: &sk, Amount: sk.Amount, Memo: memo, Expires: expires}
+}
+
+func (u URI) String() string {
+	query := url.Values{}
+	opaque := ""
+	if u.Webcash != nil {
+		opaque = u.Webcash.String()
+	} else if u.Amount != 0 {
+		query.Set("amount", u.Amount.String())
+	}
+	if u.Memo != "" {
+		query.Set("memo", u.Memo)
+	}
+	if !u.Expires.IsZero() {
+		query.Set("expires", u.Expires.UTC().Format(time.RFC3339))
+	}
+	s := URIScheme + ":" + opaque
+	if len(query) > 0 {
+		s += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+	return s
+}
+
+// IsURI reports whether s looks like a webcash URI rather than a bare claim
+// code.
+func IsURI(s string) bool {
+	s = strings.TrimSpace(s)
+	return len(s) > len(URIScheme) && strings.EqualFold(s[:len(URIScheme)+1], URIScheme+":")
+}
+
+// ParseURI parses a webcash URI.  It does not check whether the URI has
+// expired; see Check.
+func ParseURI(s string) (URI, error) {
+	s = strings.TrimSpace(s)
+	if !IsURI(s) {
+		return URI{}, fmt.Errorf("%w: expected a %q URI", ErrInvalidWebcash, URIScheme+":")
+	}
+	rest := s[len(URIScheme)+1:]
+	rest = strings.TrimPrefix(rest, "//")
+	code, raw_query, _ := strings.Cut(rest, "?")
+	query, err := url.ParseQuery(raw_query)
+	if err != nil {
+		return URI{}, fmt.Errorf("%w: %v", ErrInvalidWebcash, err)
+	}
+
+	var u URI
+	if code != "" {
+		if code, err = url.PathUnescape(code); err != nil {
+			return URI{}, fmt.Errorf("%w: %v", ErrInvalidWebcash, err)
+		}
+		sk, err := ParseSecretWebcash(code)
+		if err != nil {
+			return URI{}, err
+		}
+		u.Webcash = &sk
+		u.Amount = sk.Amount
+	}
+	if amount := query.Get("amount"); amount != "" {
+		a, err := ParseAmount(strings.TrimPrefix(amount, "e"))
+		if err != nil {
+			return URI{}, err
+		}
+		if u.Webcash != nil && a != u.Amount {
+			return URI{}, fmt.Errorf("%w: the amount %v is not that of the webcash, %v", ErrInvalidWebcash, a, u.Amount)
+		}
+		u.Amount = a
+	}
+	u.Memo = query.Get("memo")
+	if expires := query.Get("expires"); expires != "" {
+		if u.Expires, err = time.Parse(time.RFC3339, expires); err != nil {
+			return URI{}, fmt.Errorf("%w: expires: %v", ErrInvalidWebcash, err)
+		}
+	}
+	return u, nil
+}
+
+// Check returns ErrExpired if the URI has expired by now.
+func (u URI) Check(now time.Time) error {
+	if !u.Expires.IsZero() && now.After(u.Expires) {
+		return fmt.Errorf("%w (at %s)", ErrExpired, u.Expires.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
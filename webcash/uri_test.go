@@ -0,0 +1,124 @@
+package webcash
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const test_secret = "e1.5:secret:8e2c4e6f0b0b9a3d5b9cfe2a2c0e7f1a3b4c5d6e7f8091a2b3c4d5e6f708192a"
+
+// same_uri reports whether two URIs are the same, their expiry times being
+// the same instant.
+func same_uri(a, b URI) bool {
+	if (a.Webcash == nil) != (b.Webcash == nil) || a.Webcash != nil && *a.Webcash != *b.Webcash {
+		return false
+	}
+	return a.Amount == b.Amount && a.Memo == b.Memo && a.Expires.Equal(b.Expires)
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	sk, err := ParseSecretWebcash(test_secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Date(2026, 12, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	tests := []URI{
+		NewPaymentURI(sk, "", time.Time{}),
+		NewPaymentURI(sk, "thanks for lunch", expires),
+		NewPaymentURI(sk, "a&b=c?d #e %f +g ☕", time.Time{}),
+		{},
+		{Amount: 1},
+		{Amount: 12_500_000_00, Memo: "rent", Expires: expires},
+		{Memo: "tip jar"},
+	}
+	for _, u := range tests {
+		s := u.String()
+		got, err := ParseURI(s)
+		if err != nil {
+			t.Errorf("ParseURI(%q): %v", s, err)
+			continue
+		}
+		if !same_uri(got, u) {
+			t.Errorf("ParseURI(%q) = %+v, expected %+v", s, got, u)
+		}
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	sk, err := ParseSecretWebcash(test_secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		s    string
+		want URI
+		err  bool
+	}{
+		// An empty opaque part is a request which leaves everything to the
+		// payer.
+		{"webcash:", URI{}, false},
+		{"webcash:?", URI{}, false},
+		{"webcash:?amount=2", URI{Amount: 2_000_000_00}, false},
+		{"webcash:?amount=e2", URI{Amount: 2_000_000_00}, false},
+		{"webcash:" + test_secret, URI{Webcash: &sk, Amount: sk.Amount}, false},
+		{"  webcash:" + test_secret + "\n", URI{Webcash: &sk, Amount: sk.Amount}, false},
+		// The // some applications put after the scheme is ignored.
+		{"webcash://" + test_secret, URI{Webcash: &sk, Amount: sk.Amount}, false},
+		{"webcash://?amount=2", URI{Amount: 2_000_000_00}, false},
+		// The scheme is not case sensitive.
+		{"WebCash:" + test_secret, URI{Webcash: &sk, Amount: sk.Amount}, false},
+		{"WEBCASH://?memo=hi", URI{Memo: "hi"}, false},
+		{"webcash:" + test_secret + "?memo=lunch%20money&expires=2026-12-01T00:00:00Z", URI{Webcash: &sk, Amount: sk.Amount, Memo: "lunch money", Expires: expires}, false},
+		{"webcash:?expires=2026-12-01T01:00:00%2B01:00", URI{Expires: expires}, false},
+		// An amount alongside a claim code must be that of the webcash.
+		{"webcash:" + test_secret + "?amount=1.5", URI{Webcash: &sk, Amount: sk.Amount}, false},
+		{"webcash:" + test_secret + "?amount=2", URI{}, true},
+		{"webcash:" + test_secret + "?amount=-1", URI{}, true},
+		{"webcash:?amount=lots", URI{}, true},
+		{"webcash:?expires=tomorrow", URI{}, true},
+		{"webcash:?expires=2026-12-01", URI{}, true},
+		{"webcash:?memo=%zz", URI{}, true},
+		{"webcash:e1.5:secret:%zz", URI{}, true},
+		{"webcash:e1.5:public:8e2c4e6f0b0b9a3d5b9cfe2a2c0e7f1a3b4c5d6e7f8091a2b3c4d5e6f708192a", URI{}, true},
+		{"webcash:hello", URI{}, true},
+		{"webcash", URI{}, true},
+		{"webcashe1:secret:abc", URI{}, true},
+		{test_secret, URI{}, true},
+		{"bitcoin:?amount=1", URI{}, true},
+		{"", URI{}, true},
+	}
+	for _, test := range tests {
+		got, err := ParseURI(test.s)
+		if test.err {
+			if err == nil {
+				t.Errorf("ParseURI(%q) = %+v; expected an error", test.s, got)
+			}
+			continue
+		}
+		if err != nil || !same_uri(got, test.want) {
+			t.Errorf("ParseURI(%q) = %+v, %v; expected %+v", test.s, got, err, test.want)
+		}
+	}
+}
+
+func TestURICheck(t *testing.T) {
+	u, err := ParseURI("webcash:?amount=1&expires=2026-12-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	if err := u.Check(expires.Add(-time.Second)); err != nil {
+		t.Errorf("Check before the expiry: %v", err)
+	}
+	if err := u.Check(expires); err != nil {
+		t.Errorf("Check at the expiry: %v", err)
+	}
+	if err := u.Check(expires.Add(time.Second)); !errors.Is(err, ErrExpired) {
+		t.Errorf("Check after the expiry = %v; expected ErrExpired", err)
+	}
+	if err := (URI{Amount: 1}).Check(time.Now()); err != nil {
+		t.Errorf("Check of a URI which doesn't expire: %v", err)
+	}
+}
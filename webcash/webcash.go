@@ -0,0 +1,252 @@
+// Package webcash defines the basic types of the webcash protocol: amounts,
+// secret and public webcash, and proof-of-work checks.
+package webcash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/maaku/gocash/internal/decimal"
+)
+
+type Uint256 [32]byte
+
+func (hash Uint256) String() string {
+	// encode as hex
+	return fmt.Sprintf("0x%x", hash[:])
+}
+
+func ApparentDifficulty(hash Uint256) uint8 {
+	diff := 0
+	for i := 0; i < 32; i++ {
+		c := hash[i]
+		if c == 0 {
+			diff += 8
+			continue
+		}
+		diff += bits.LeadingZeros8(c)
+		break
+	}
+	return uint8(diff)
+}
+
+func CheckProofOfWork(hash Uint256, difficulty uint8) bool {
+	for i := 0; i < int(difficulty/8); i++ {
+		if hash[i] != 0 {
+			return false
+		}
+	}
+	if difficulty%8 != 0 {
+		if hash[difficulty/8]>>(8-difficulty%8) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type Amount uint64
+
+func (amt Amount) String() string {
+	return string(amt.Append(nil))
+}
+
+// Append appends the decimal representation of amt to dst, with trailing
+// fractional zeros removed, and returns the extended buffer.  Unlike String,
+// it does not allocate if dst has sufficient capacity.
+func (amt Amount) Append(dst []byte) []byte {
+	integer := uint64(amt) / 1_000_000_00
+	fraction := uint64(amt) % 1_000_000_00
+	dst = decimal.AppendUint(dst, integer)
+	if fraction != 0 {
+		// Remove trailing zeros
+		width := 8
+		for fraction%10 == 0 {
+			fraction /= 10
+			width--
+		}
+		dst = append(dst, '.')
+		dst = decimal.AppendPadded(dst, fraction, width)
+	}
+	return dst
+}
+
+func (amt Amount) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%s\"", amt)), nil
+}
+
+func (amt *Amount) UnmarshalJSON(data []byte) error {
+	var inner string
+	if err := json.Unmarshal(data, &inner); err != nil {
+		// Must not be wrapped as a string
+		inner = string(data)
+	}
+	parsed, err := ParseAmount(inner)
+	if err != nil {
+		return err
+	}
+	*amt = parsed
+	return nil
+}
+
+// The largest amount, in units of 10^-8 webcash.  Amounts are signed 64-bit
+// integers on the server, so no more can be represented there.
+const MaxAmount Amount = math.MaxInt64
+
+// ParseAmount parses a decimal amount of webcash, with up to 8 fractional
+// digits, e.g. "12.5".  Amounts greater than MaxAmount fail.
+func ParseAmount(s string) (Amount, error) {
+	if strings.ContainsRune(s, '.') {
+		parts := strings.Split(s, ".")
+		if len(parts) != 2 || len(parts[1]) > 8 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+		for _, rune := range parts[1] {
+			if !unicode.IsDigit(rune) {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+			}
+		}
+		for i := len(parts[1]); i < 8; i++ {
+			parts[1] += "0"
+		}
+		integer, err := strconv.ParseUint(parts[0], 10, 63)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+		fraction, err := strconv.ParseUint(parts[1], 10, 63)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+		if integer > uint64(MaxAmount)/1_000_000_00 || integer*1_000_000_00 > uint64(MaxAmount)-fraction {
+			return 0, fmt.Errorf("%w: %q is more than the most webcash there can be", ErrInvalidAmount, s)
+		}
+		return Amount(integer*1_000_000_00 + fraction), nil
+	}
+	integer, err := strconv.ParseUint(s, 10, 63)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if integer > uint64(MaxAmount)/1_000_000_00 {
+		return 0, fmt.Errorf("%w: %q is more than the most webcash there can be", ErrInvalidAmount, s)
+	}
+	return Amount(integer * 1_000_000_00), nil
+}
+
+type SecretWebcash struct {
+	// The actual secret, typically a 64-character hex string but in principle
+	// any Unicode string value.
+	Secret string `json:"secret"`
+	// The amount of Webcash held by the secret.
+	Amount Amount `json:"amount"`
+}
+
+func (sk SecretWebcash) String() string {
+	return fmt.Sprintf("e%v:secret:%s", sk.Amount, sk.Secret)
+}
+
+// Bytes returns the claim code as String does, but in a buffer of its own,
+// which the caller can Wipe once it is written out.
+func (sk SecretWebcash) Bytes() []byte {
+	b := make([]byte, 0, len("e:secret:")+24+len(sk.Secret))
+	b = append(b, 'e')
+	b = append(b, sk.Amount.String()...)
+	b = append(b, ":secret:"...)
+	return append(b, sk.Secret...)
+}
+
+// Wipe overwrites a buffer which held secrets, so that they don't linger in
+// memory, core dumps or swap.  It is best effort: strings, such as the
+// Secret of a SecretWebcash, are immutable and cannot be wiped, and copies
+// made along the way are out of reach.
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// ParseSecretWebcash parses a claim code of the form "e<amount>:secret:<secret>".
+func ParseSecretWebcash(s string) (SecretWebcash, error) {
+	amount, secret, err := parse_webcash(s, "secret")
+	if err != nil {
+		return SecretWebcash{}, err
+	}
+	return SecretWebcash{Secret: secret, Amount: amount}, nil
+}
+
+// parse_webcash splits a serialized webcash of the given kind, "secret" or
+// "public", into its amount and value.
+func parse_webcash(s, kind string) (Amount, string, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 3)
+	if len(parts) != 3 || parts[1] != kind || !strings.HasPrefix(parts[0], "e") {
+		return 0, "", fmt.Errorf("%w: expected \"e<amount>:%s:<value>\"", ErrInvalidWebcash, kind)
+	}
+	amount, err := ParseAmount(parts[0][1:])
+	if err != nil {
+		return 0, "", fmt.Errorf("%w: %v", ErrInvalidWebcash, err)
+	}
+	if amount == 0 {
+		return 0, "", fmt.Errorf("%w: amount must be positive", ErrInvalidWebcash)
+	}
+	if parts[2] == "" {
+		return 0, "", fmt.Errorf("%w: empty %s", ErrInvalidWebcash, kind)
+	}
+	return amount, parts[2], nil
+}
+
+type PublicWebcash struct {
+	// The public hash, a 32-byte SHA-256 hash of the secret string.
+	Hash Uint256 `json:"hash"`
+	// The amount of Webcash held by the secret.
+	Amount Amount `json:"amount"`
+}
+
+// String returns the public webcash in the form used by the server API,
+// "e<amount>:public:<hash>" with the hash in hex.
+func (pk PublicWebcash) String() string {
+	return fmt.Sprintf("e%v:public:%x", pk.Amount, pk.Hash[:])
+}
+
+// ParsePublicWebcash parses a public webcash of the form
+// "e<amount>:public:<hash>".
+func ParsePublicWebcash(s string) (PublicWebcash, error) {
+	amount, value, err := parse_webcash(s, "public")
+	if err != nil {
+		return PublicWebcash{}, err
+	}
+	hash, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil || len(hash) != 32 {
+		return PublicWebcash{}, fmt.Errorf("%w: hash must be 64 hex digits", ErrInvalidWebcash)
+	}
+	var pk PublicWebcash
+	copy(pk.Hash[:], hash)
+	pk.Amount = amount
+	return pk, nil
+}
+
+// FromSecret converts a SecretWebcash to a PublicWebcash.
+func FromSecret(sk SecretWebcash) PublicWebcash {
+	return PublicWebcash{
+		Hash:   sha256.Sum256([]byte(sk.Secret)),
+		Amount: sk.Amount,
+	}
+}
+
+type ProtocolSettings struct {
+	// The number of leading bits which must be zero for a work candidate to be
+	// accepted by the server.
+	Difficulty uint8 `json:"difficulty_target_bits"`
+	// The ratio of initial issuance distributed to expected amount.
+	Ratio float32 `json:"ratio"`
+	// The amount the miner is allowed to claim.
+	TotalReward Amount `json:"mining_amount"`
+	// The amount which is surrendered to the server operator.
+	ServerSubsidy Amount `json:"mining_subsidy_amount"`
+	// The number of subsidy adjustment periods which have elapsed.
+	Epoch uint16 `json:"epoch"`
+}
@@ -0,0 +1,59 @@
+package webcash
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Amount
+		err  bool
+	}{
+		{"0", 0, false},
+		{"1", 1_000_000_00, false},
+		{"12.5", 12_500_000_00, false},
+		{"0.00000001", 1, false},
+		{"1.", 1_000_000_00, false},
+		{"92233720368", 92233720368_000_000_00, false},
+		{"92233720368.54775807", MaxAmount, false},
+		{"92233720368.54775808", 0, true},
+		{"92233720369", 0, true},
+		{"184467440737.09551617", 0, true},
+		{"184467440737", 0, true},
+		{"9223372036854775807", 0, true},
+		{"9223372036854775808", 0, true},
+		{"0.000000001", 0, true},
+		{".5", 0, true},
+		{"-1", 0, true},
+		{"+1", 0, true},
+		{"1.2.3", 0, true},
+		{"1.-2", 0, true},
+		{"1e3", 0, true},
+		{"", 0, true},
+	}
+	for _, test := range tests {
+		got, err := ParseAmount(test.s)
+		if test.err {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("ParseAmount(%q) = %d, %v; expected ErrInvalidAmount", test.s, got, err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ParseAmount(%q) = %d, %v; expected %d", test.s, got, err, test.want)
+		}
+	}
+}
+
+// TestAmountRoundTrip checks that formatting an amount and parsing it back
+// gives the same amount, up to the largest.
+func TestAmountRoundTrip(t *testing.T) {
+	for _, amt := range []Amount{0, 1, 10, 1_000_000_00, 1_000_000_01, 12_345_678_90, MaxAmount - 1, MaxAmount} {
+		got, err := ParseAmount(amt.String())
+		if err != nil || got != amt {
+			t.Errorf("ParseAmount(%q) = %d, %v; expected %d", amt.String(), got, err, amt)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package webcashtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// An Interaction is a single recorded request and the server's response.
+type Interaction struct {
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	RequestBody  string `json:"request_body,omitempty"`
+	Status       int    `json:"status"`
+	ResponseBody string `json:"response_body"`
+}
+
+// A Cassette is a sequence of recorded interactions with a server, which can
+// be saved to a file and replayed later, offline.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette saved by Save.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to a file as indented JSON.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// A Recorder is an http.RoundTripper which passes requests through to another
+// transport, recording each exchange.  Webcash secrets in request and
+// response bodies, including those inside mining report preimages, are
+// replaced with random ones before they are recorded, so that a cassette of
+// real traffic gives away nothing of value.  A given secret is always
+// replaced by the same substitute, so flows spanning several requests still
+// read consistently.
+type Recorder struct {
+	inner http.RoundTripper
+
+	mutex    sync.Mutex
+	cassette Cassette
+	redacted map[string]string
+}
+
+// NewRecorder records requests made through inner, or through
+// http.DefaultTransport if inner is nil.  To record a client's traffic:
+//
+//	c.HTTPClient.Transport = webcashtest.NewRecorder(c.HTTPClient.Transport)
+func NewRecorder(inner http.RoundTripper) *Recorder {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	return &Recorder{
+		inner:    inner,
+		redacted: make(map[string]string),
+	}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var request_body []byte
+	if req.Body != nil {
+		var err error
+		request_body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(request_body))
+	}
+
+	resp, err := r.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	response_body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(response_body))
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		RequestBody:  string(r.redact(request_body)),
+		Status:       resp.StatusCode,
+		ResponseBody: string(r.redact(response_body)),
+	})
+	return resp, nil
+}
+
+// Cassette returns a copy of the interactions recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return &Cassette{
+		Interactions: append([]Interaction(nil), r.cassette.Interactions...),
+	}
+}
+
+var (
+	secret_pattern   = regexp.MustCompile(`(e[0-9.]+:secret:)([^"\s,\]}]+)`)
+	preimage_pattern = regexp.MustCompile(`("preimage"\s*:\s*")([A-Za-z0-9+/=]+)(")`)
+)
+
+// redact replaces the secrets in body with substitutes.  Must be called with
+// the mutex held.
+func (r *Recorder) redact(body []byte) []byte {
+	body = preimage_pattern.ReplaceAllFunc(body, func(match []byte) []byte {
+		parts := preimage_pattern.FindSubmatch(match)
+		preimage, err := base64.StdEncoding.DecodeString(string(parts[2]))
+		if err != nil {
+			return match
+		}
+		encoded := base64.StdEncoding.EncodeToString(r.redact_secrets(preimage))
+		return []byte(string(parts[1]) + encoded + string(parts[3]))
+	})
+	return r.redact_secrets(body)
+}
+
+func (r *Recorder) redact_secrets(body []byte) []byte {
+	return secret_pattern.ReplaceAllFunc(body, func(match []byte) []byte {
+		parts := secret_pattern.FindSubmatch(match)
+		secret := string(parts[2])
+		substitute, ok := r.redacted[secret]
+		if !ok {
+			substitute = NewSecret(0).Secret
+			r.redacted[secret] = substitute
+		}
+		return []byte(string(parts[1]) + substitute)
+	})
+}
+
+// A Replayer is an http.RoundTripper which answers requests from a cassette,
+// without any network access.  Each request is answered with the first
+// interaction not yet replayed which has the same method and path.  Request
+// bodies are not compared, since secrets will differ from run to run.
+type Replayer struct {
+	mutex    sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer replays the interactions of cassette.  To replay a client's
+// traffic:
+//
+//	c.HTTPClient = &http.Client{Transport: webcashtest.NewReplayer(cassette)}
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{
+		cassette: cassette,
+		used:     make([]bool, len(cassette.Interactions)),
+	}
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Method != req.Method || interaction.Path != req.URL.Path {
+			continue
+		}
+		r.used[i] = true
+		header := make(http.Header)
+		if strings.HasPrefix(strings.TrimSpace(interaction.ResponseBody), "{") {
+			header.Set("Content-Type", "application/json")
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode:    interaction.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(interaction.ResponseBody)),
+			ContentLength: int64(len(interaction.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("webcashtest: no recorded interaction left for %s %s", req.Method, req.URL.Path)
+}
+
+// Remaining reports how many interactions have not yet been replayed.
+func (r *Replayer) Remaining() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,72 @@
+//go:build e2e
+
+package webcashtest
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRecordReplay records a mining and payment flow against the mock server,
+// then replays it offline and checks the client sees the same responses.
+func TestRecordReplay(t *testing.T) {
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	recorder := NewRecorder(h.Client.HTTPClient.Transport)
+	h.Client.HTTPClient.Transport = recorder
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	mined, err := h.Mine(ctx)
+	if err != nil {
+		t.Fatalf("mining failed: %v", err)
+	}
+	claimed, err := h.Insert(mined)
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if _, err := h.Check(claimed); err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := recorder.Cassette().Save(path); err != nil {
+		t.Fatalf("saving cassette: %v", err)
+	}
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("loading cassette: %v", err)
+	}
+	for _, interaction := range cassette.Interactions {
+		for _, secret := range []string{mined.Secret, claimed.Secret} {
+			if strings.Contains(interaction.RequestBody, secret) || strings.Contains(interaction.ResponseBody, secret) {
+				t.Errorf("%s %s: secret was recorded", interaction.Method, interaction.Path)
+			}
+		}
+	}
+
+	// Replay against a closed server, so any request which is not answered
+	// from the cassette fails.
+	h.Close()
+	replayer := NewReplayer(cassette)
+	h.Client.HTTPClient = &http.Client{Transport: replayer}
+	if _, err := h.Mine(ctx); err != nil {
+		t.Fatalf("replayed mining failed: %v", err)
+	}
+	if _, err := h.Insert(mined); err != nil {
+		t.Fatalf("replayed insert failed: %v", err)
+	}
+	if _, err := h.Check(claimed); err != nil {
+		t.Fatalf("replayed health check failed: %v", err)
+	}
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("%d interactions were not replayed", n)
+	}
+}
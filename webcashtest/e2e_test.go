@@ -0,0 +1,158 @@
+//go:build e2e
+
+package webcashtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+// TestMineInsertPayCheck drives webcash through its whole life cycle: mined,
+// claimed, partly paid on, and finally checked against the server's ledger.
+func TestMineInsertPayCheck(t *testing.T) {
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	mined, err := h.Mine(ctx)
+	if err != nil {
+		t.Fatalf("mining failed: %v", err)
+	}
+	if want := DefaultSettings.TotalReward - DefaultSettings.ServerSubsidy; mined.Amount != want {
+		t.Fatalf("mined %v, expected %v", mined.Amount, want)
+	}
+
+	claimed, err := h.Insert(mined)
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if _, err := h.Insert(mined); !errors.Is(err, webcash.ErrSecretAlreadySpent) {
+		t.Fatalf("claiming mined webcash twice: got %v, expected ErrSecretAlreadySpent", err)
+	}
+
+	payment, change, err := h.Pay(claimed, claimed.Amount/4)
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	if payment.Amount+change.Amount != claimed.Amount {
+		t.Fatalf("payment %v plus change %v does not equal input %v", payment.Amount, change.Amount, claimed.Amount)
+	}
+
+	statuses, err := h.Check(mined, claimed, payment, change, NewSecret(1))
+	if err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+	for i, want := range []*bool{ptr(true), ptr(true), ptr(false), ptr(false), nil} {
+		got := statuses[i].Spent
+		if (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("webcash %d: spent=%v, expected %v", i, fmt_bool(got), fmt_bool(want))
+		}
+	}
+}
+
+// TestMineIntoWalletRecover mines into a wallet, pays from it, and recovers
+// the wallet from its master secret: the mined webcash is found on the mining
+// chain, along with the change of the payment.
+func TestMineIntoWalletRecover(t *testing.T) {
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if _, err := h.MineIntoWallet(ctx); err != nil {
+			t.Fatalf("mining failed: %v", err)
+		}
+	}
+	mined := 2 * (DefaultSettings.TotalReward - DefaultSettings.ServerSubsidy)
+	if h.Wallet.Balance() != mined {
+		t.Fatalf("mined a balance of %v, expected %v", h.Wallet.Balance(), mined)
+	}
+	privacy, err := wallet.ParseStrategy("privacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := h.Spend(mined/4, privacy)
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	if _, err := h.Insert(payment); err != nil {
+		t.Fatalf("the recipient could not claim the payment: %v", err)
+	}
+
+	recovered, results, err := h.RecoverWallet(false)
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if recovered.Balance() != mined-payment.Amount {
+		t.Errorf("recovered a balance of %v, expected %v", recovered.Balance(), mined-payment.Amount)
+	}
+	for _, result := range results {
+		if result.Chain == wallet.Mining && result.Used < 2 {
+			t.Errorf("the mining chain has %d secrets in use, expected at least 2", result.Used)
+		}
+	}
+}
+
+func ptr(b bool) *bool { return &b }
+
+func fmt_bool(b *bool) string {
+	if b == nil {
+		return "unknown"
+	}
+	if *b {
+		return "true"
+	}
+	return "false"
+}
+
+// TestMineManyWorkersRecover mines into a wallet with more threads than the
+// gap limit of recovery, over several runs of the miner, and recovers all of
+// it: no secrets of the mining chain are skipped.
+func TestMineManyWorkersRecover(t *testing.T) {
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	h.Workers = 2 * wallet.DefaultGapLimit
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	const runs = 3
+	for i := 0; i < runs; i++ {
+		if _, err := h.MineIntoWallet(ctx); err != nil {
+			t.Fatalf("mining failed: %v", err)
+		}
+	}
+	if depth := h.Wallet.WalletDepths[wallet.Mining.String()]; depth != runs {
+		t.Errorf("the mining chain is at depth %d after %d solutions", depth, runs)
+	}
+
+	recovered, results, err := h.RecoverWallet(false)
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if recovered.Balance() != h.Wallet.Balance() {
+		t.Errorf("recovered a balance of %v, expected %v", recovered.Balance(), h.Wallet.Balance())
+	}
+	for _, result := range results {
+		if result.Chain == wallet.Mining && result.Used != runs {
+			t.Errorf("the mining chain has %d secrets in use, expected %d", result.Used, runs)
+		}
+	}
+}
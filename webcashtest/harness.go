@@ -0,0 +1,260 @@
+package webcashtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/miner"
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+// A Harness runs a mock server, a miner and a wallet in-process, and drives the
+// protocol flows which span them: mining webcash, claiming it with a
+// replacement, paying part of it on, checking what the server has on record,
+// and recovering the wallet from its master secret.  The flows with "Wallet"
+// in their names, and Receive and Spend, go through the wallet as the gocash
+// commands do; the others handle bare secrets.
+type Harness struct {
+	Server *Server
+	Client *client.Client
+	// The wallet, with a fresh master secret.
+	Wallet *wallet.Wallet
+	// The number of mining threads to run, or zero for one per CPU.
+	Workers int
+
+	// Held while the miner derives secrets from the wallet.
+	mutex sync.Mutex
+}
+
+// NewHarness starts a harness around a fresh mock server and an empty wallet.
+// The caller should call Close when finished with it.
+func NewHarness() (*Harness, error) {
+	w, err := wallet.New()
+	if err != nil {
+		return nil, err
+	}
+	server := NewServer()
+	return &Harness{
+		Server: server,
+		Client: server.Client(),
+		Wallet: w,
+	}, nil
+}
+
+// Close shuts down the mock server.
+func (h *Harness) Close() {
+	h.Server.Close()
+}
+
+// Mine runs the miner against the server's current settings until it finds a
+// solution, submits it as a mining report, and returns the mined webcash.
+func (h *Harness) Mine(ctx context.Context) (webcash.SecretWebcash, error) {
+	return h.mine(ctx, nil)
+}
+
+// MineIntoWallet mines as Mine does, with the miner's share drawn from the
+// wallet's mining chain, and deposits it in the wallet.
+func (h *Harness) MineIntoWallet(ctx context.Context) (webcash.SecretWebcash, error) {
+	sk, err := h.mine(ctx, func(m *miner.Miner) {
+		// As gocash does, the next secret of the chain is only taken from
+		// it once a solution claims it.
+		m.KeepSecret = func() (string, error) {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+			return h.Wallet.DeriveSecret(wallet.Mining, h.Wallet.WalletDepths[wallet.Mining.String()])
+		}
+		m.ClaimSecret = func(string) error {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+			_, err := h.Wallet.NextSecret(wallet.Mining)
+			return err
+		}
+	})
+	if err == nil {
+		h.Wallet.Webcash = append(h.Wallet.Webcash, sk)
+	}
+	return sk, err
+}
+
+// mine is Mine, with the miner set up by setup if it is not nil.
+func (h *Harness) mine(ctx context.Context, setup func(m *miner.Miner)) (webcash.SecretWebcash, error) {
+	settings, err := h.Client.Target()
+	if err != nil {
+		return webcash.SecretWebcash{}, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	// Without room in the queue, a solution is only pushed, claiming its
+	// keep secret, once it is received, so none is left behind when the
+	// miner is stopped after the first.
+	solutions := miner.NewSolutionQueue(0)
+	m := miner.New(ctx, settings, solutions)
+	if setup != nil {
+		setup(m)
+	}
+	m.Resize(h.Workers, nil)
+	defer m.Wait()
+	defer cancel()
+
+	select {
+	case soln := <-solutions.C():
+		resp, err := h.Client.SubmitMiningReport(client.MiningReport{
+			Hash:     soln.Hash,
+			Preimage: soln.Preimage,
+		})
+		if err != nil {
+			return webcash.SecretWebcash{}, err
+		}
+		if !resp.Accepted() {
+			return webcash.SecretWebcash{}, fmt.Errorf("mining report rejected (%d): %s", resp.StatusCode, resp.Error)
+		}
+		return soln.Reward, nil
+	case <-ctx.Done():
+		return webcash.SecretWebcash{}, ctx.Err()
+	}
+}
+
+// Insert claims received webcash by replacing it with a fresh secret of the
+// same value, which is returned.
+func (h *Harness) Insert(sk webcash.SecretWebcash) (webcash.SecretWebcash, error) {
+	fresh := NewSecret(sk.Amount)
+	if err := h.Client.Replace([]webcash.SecretWebcash{sk}, []webcash.SecretWebcash{fresh}); err != nil {
+		return webcash.SecretWebcash{}, err
+	}
+	return fresh, nil
+}
+
+// Pay splits amount off of sk into a fresh secret for the recipient, returning
+// it along with the change.
+func (h *Harness) Pay(sk webcash.SecretWebcash, amount webcash.Amount) (payment, change webcash.SecretWebcash, err error) {
+	if amount == 0 {
+		return payment, change, errors.New("payment must be positive")
+	}
+	if amount >= sk.Amount {
+		return payment, change, fmt.Errorf("%w: paying %v from %v", webcash.ErrInsufficientFunds, amount, sk.Amount)
+	}
+	payment = NewSecret(amount)
+	change = NewSecret(sk.Amount - amount)
+	err = h.Client.Replace([]webcash.SecretWebcash{sk}, []webcash.SecretWebcash{payment, change})
+	return payment, change, err
+}
+
+// Check returns the server's record of each secret, indexed like sks.
+func (h *Harness) Check(sks ...webcash.SecretWebcash) ([]client.HealthStatus, error) {
+	pks := make([]webcash.PublicWebcash, len(sks))
+	for i, sk := range sks {
+		pks[i] = webcash.FromSecret(sk)
+	}
+	results, err := h.Client.HealthCheck(pks)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]client.HealthStatus, len(sks))
+	for i, pk := range pks {
+		statuses[i] = results[pk.Hash]
+	}
+	return statuses, nil
+}
+
+// FundWallet adds webcash of the given amount to the wallet, from its mining
+// chain, as though it had been mined into it, but without the work.
+func (h *Harness) FundWallet(amount webcash.Amount) (webcash.SecretWebcash, error) {
+	secret, err := h.Wallet.NextSecret(wallet.Mining)
+	if err != nil {
+		return webcash.SecretWebcash{}, err
+	}
+	sk := webcash.SecretWebcash{Secret: secret, Amount: amount}
+	h.Server.Fund(sk)
+	h.Wallet.Webcash = append(h.Wallet.Webcash, sk)
+	return sk, nil
+}
+
+// Receive claims webcash given to the wallet, as `gocash insert` does,
+// returning the wallet's secret which replaces it.
+func (h *Harness) Receive(sk webcash.SecretWebcash) (webcash.SecretWebcash, error) {
+	output, err := h.Wallet.NewOutput(wallet.Receive, sk.Amount)
+	if err != nil {
+		return webcash.SecretWebcash{}, err
+	}
+	err = h.replace([]webcash.SecretWebcash{sk}, []webcash.SecretWebcash{output}, nil)
+	return output, err
+}
+
+// Spend pays amount out of the wallet, as `gocash pay` does, with inputs chosen
+// by strategy and the change kept, returning the payment.
+func (h *Harness) Spend(amount webcash.Amount, strategy wallet.Strategy) (webcash.SecretWebcash, error) {
+	inputs, err := h.Wallet.Select(amount, strategy)
+	if err != nil {
+		return webcash.SecretWebcash{}, err
+	}
+	var total webcash.Amount
+	for _, sk := range inputs {
+		total += sk.Amount
+	}
+	payment, err := h.Wallet.NewOutput(wallet.Pay, amount)
+	if err != nil {
+		return webcash.SecretWebcash{}, err
+	}
+	var kept []webcash.SecretWebcash
+	if total > amount {
+		change, err := h.Wallet.NewOutput(wallet.Change, total-amount)
+		if err != nil {
+			return webcash.SecretWebcash{}, err
+		}
+		kept = append(kept, change)
+	}
+	err = h.replace(inputs, kept, []webcash.SecretWebcash{payment})
+	return payment, err
+}
+
+// replace replaces inputs with the outputs derived for them, settling the
+// wallet as the gocash commands do: the outputs stay unconfirmed unless the
+// server is known to have made the replacement or refused it.
+func (h *Harness) replace(inputs, kept, paid []webcash.SecretWebcash) error {
+	outputs := append(append([]webcash.SecretWebcash(nil), kept...), paid...)
+	if err := h.Client.Replace(inputs, outputs); err != nil {
+		var refused *client.ServerError
+		if errors.As(err, &refused) && refused.StatusCode < 500 {
+			h.Wallet.Abandon(outputs)
+		}
+		return err
+	}
+	h.Wallet.Replaced(inputs, kept, paid)
+	return nil
+}
+
+// CheckWallet checks the wallet against the server, as `gocash check` does.
+func (h *Harness) CheckWallet() (wallet.CheckResult, error) {
+	return h.Wallet.Check(h.Client, wallet.DefaultGapLimit, wallet.DefaultBatchSize)
+}
+
+// RecoverWallet recovers a fresh wallet from the master secret of the
+// harness's, as `gocash recover` does, sweeping unclaimed payments back if
+// sweep is set.  The harness's wallet is unchanged.
+func (h *Harness) RecoverWallet(sweep bool) (*wallet.Wallet, []wallet.ChainRecovery, error) {
+	w, err := wallet.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.MasterSecret = h.Wallet.MasterSecret
+	results, err := w.Recover(h.Client, wallet.DefaultGapLimit, wallet.DefaultBatchSize, sweep)
+	return w, results, err
+}
+
+// NewSecret returns webcash of the given amount with a random secret.
+func NewSecret(amount webcash.Amount) webcash.SecretWebcash {
+	var buf [32]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(err)
+	}
+	return webcash.SecretWebcash{
+		Secret: hex.EncodeToString(buf[:]),
+		Amount: amount,
+	}
+}
@@ -0,0 +1,422 @@
+// Package webcashtest provides an in-process webcash server for hermetic
+// testing of code which talks to the webcash API.
+package webcashtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/maaku/gocash/client"
+	"github.com/maaku/gocash/webcash"
+)
+
+// The terms of service served by default.
+const DefaultTerms = "These are the terms of service of the webcashtest mock server."
+
+// DefaultSettings are the protocol settings served by default.  The difficulty
+// is low enough that a single CPU finds a solution in well under a second.
+var DefaultSettings = webcash.ProtocolSettings{
+	Difficulty:    16,
+	Ratio:         1.0,
+	TotalReward:   200_000 * 1_000_000_00,
+	ServerSubsidy: 10_000 * 1_000_000_00,
+	Epoch:         0,
+}
+
+// A Response is a canned reply to a single request.
+type Response struct {
+	// The HTTP status code.  If zero, 200 is used.
+	Status int
+	// The response body.  A string or []byte is sent verbatim; anything else
+	// is serialized as JSON.
+	Body interface{}
+}
+
+// A Request is a record of a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// Server is a mock webcash server.  By default it behaves like a small but
+// real server, keeping a ledger of webcash created by mining reports and
+// replacements, so that flows spanning several API calls can be exercised.
+// Any endpoint's behavior can be overridden, either for the next few requests
+// with Enqueue or permanently with Handle.
+type Server struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	terms    string
+	settings webcash.ProtocolSettings
+	ledger   map[webcash.Uint256]*ledger_entry
+	reports  uint64
+	queued   map[string][]Response
+	handlers map[string]http.HandlerFunc
+	requests []Request
+}
+
+type ledger_entry struct {
+	amount webcash.Amount
+	spent  bool
+}
+
+// NewServer starts a mock server with the default terms and settings.  The
+// caller should call Close when finished with it.
+func NewServer() *Server {
+	s := &Server{
+		terms:    DefaultTerms,
+		settings: DefaultSettings,
+		ledger:   make(map[webcash.Uint256]*ledger_entry),
+		queued:   make(map[string][]Response),
+		handlers: map[string]http.HandlerFunc{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/terms/text", s.dispatch(s.serve_terms))
+	mux.HandleFunc("/api/v1/target", s.dispatch(s.serve_target))
+	mux.HandleFunc("/api/v1/mining_report", s.dispatch(s.serve_mining_report))
+	mux.HandleFunc("/api/v1/replace", s.dispatch(s.serve_replace))
+	mux.HandleFunc("/api/v1/health_check", s.dispatch(s.serve_health_check))
+	mux.HandleFunc("/api/v1/stats", s.dispatch(s.serve_stats))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Client returns a client configured to talk to this server.
+func (s *Server) Client() *client.Client {
+	return &client.Client{
+		Server:     s.URL,
+		HTTPClient: s.Server.Client(),
+	}
+}
+
+// SetTerms changes the terms of service text.
+func (s *Server) SetTerms(terms string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.terms = terms
+}
+
+// Settings returns the protocol settings currently being served.
+func (s *Server) Settings() webcash.ProtocolSettings {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.settings
+}
+
+// SetSettings changes the protocol settings served by /api/v1/target, and
+// used to validate mining reports.
+func (s *Server) SetSettings(settings webcash.ProtocolSettings) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.settings = settings
+}
+
+// Enqueue arranges for the next request to path to receive resp instead of
+// the default behavior.  Responses queued for the same path are used in order.
+func (s *Server) Enqueue(path string, resp Response) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queued[path] = append(s.queued[path], resp)
+}
+
+// Handle replaces the default behavior for path with handler, until Handle is
+// called again with a nil handler.  Enqueued responses still take precedence.
+func (s *Server) Handle(path string, handler http.HandlerFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if handler == nil {
+		delete(s.handlers, path)
+	} else {
+		s.handlers[path] = handler
+	}
+}
+
+// Requests returns every request received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Fund adds unspent webcash to the ledger, as though it had been mined.
+func (s *Server) Fund(sk webcash.SecretWebcash) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ledger[webcash.FromSecret(sk).Hash] = &ledger_entry{amount: sk.Amount}
+}
+
+// Lookup reports the amount and spent status of the webcash with the given
+// public hash, and whether the ledger knows of it at all.
+func (s *Server) Lookup(hash webcash.Uint256) (amount webcash.Amount, spent bool, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entry, ok := s.ledger[hash]
+	if !ok {
+		return 0, false, false
+	}
+	return entry.amount, entry.spent, true
+}
+
+// dispatch wraps a default handler with request recording and the overrides
+// configured through Enqueue and Handle.
+func (s *Server) dispatch(default_handler func(w http.ResponseWriter, body []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mutex.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Body: body})
+		var queued *Response
+		if q := s.queued[r.URL.Path]; len(q) > 0 {
+			queued = &q[0]
+			s.queued[r.URL.Path] = q[1:]
+		}
+		handler := s.handlers[r.URL.Path]
+		s.mutex.Unlock()
+
+		switch {
+		case queued != nil:
+			write_response(w, *queued)
+		case handler != nil:
+			handler(w, r)
+		default:
+			default_handler(w, body)
+		}
+	}
+}
+
+func write_response(w http.ResponseWriter, resp Response) {
+	var body []byte
+	switch v := resp.Body.(type) {
+	case string:
+		body = []byte(v)
+	case []byte:
+		body = v
+	default:
+		body, _ = json.Marshal(v)
+		w.Header().Set("Content-Type", "application/json")
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func write_error(w http.ResponseWriter, status int, msg string) {
+	write_response(w, Response{Status: status, Body: map[string]string{"error": msg}})
+}
+
+func (s *Server) serve_terms(w http.ResponseWriter, body []byte) {
+	s.mutex.Lock()
+	terms := s.terms
+	s.mutex.Unlock()
+	write_response(w, Response{Body: terms})
+}
+
+func (s *Server) serve_target(w http.ResponseWriter, body []byte) {
+	write_response(w, Response{Body: s.Settings()})
+}
+
+func (s *Server) serve_stats(w http.ResponseWriter, body []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	write_response(w, Response{Body: client.ServerStats{
+		MiningReports: s.reports,
+		Epoch:         s.settings.Epoch,
+		Difficulty:    s.settings.Difficulty,
+	}})
+}
+
+func (s *Server) serve_mining_report(w http.ResponseWriter, body []byte) {
+	var report struct {
+		Preimage string          `json:"preimage"`
+		Work     json.Number     `json:"work"`
+		Legalese map[string]bool `json:"legalese"`
+	}
+	if err := json.Unmarshal(body, &report); err != nil {
+		write_error(w, http.StatusBadRequest, "Malformed mining report.")
+		return
+	}
+	if !report.Legalese["terms"] {
+		write_error(w, http.StatusBadRequest, "Didn't accept terms.")
+		return
+	}
+
+	// The work must be the hash of the preimage, and meet the difficulty.
+	hash := webcash.Uint256(sha256.Sum256([]byte(report.Preimage)))
+	if new(big.Int).SetBytes(hash[:]).String() != report.Work.String() {
+		write_error(w, http.StatusBadRequest, "Work does not match preimage.")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !webcash.CheckProofOfWork(hash, s.settings.Difficulty) {
+		write_error(w, http.StatusBadRequest, "Proof of work does not meet the difficulty.")
+		return
+	}
+
+	preimage, err := base64.StdEncoding.DecodeString(report.Preimage)
+	if err != nil {
+		write_error(w, http.StatusBadRequest, "Preimage is not base64.")
+		return
+	}
+	var payload struct {
+		Webcash    []string `json:"webcash"`
+		Subsidy    []string `json:"subsidy"`
+		Difficulty uint8    `json:"difficulty"`
+	}
+	if err := json.Unmarshal(preimage, &payload); err != nil {
+		write_error(w, http.StatusBadRequest, "Preimage is not a JSON object.")
+		return
+	}
+	if payload.Difficulty < s.settings.Difficulty {
+		write_error(w, http.StatusBadRequest, "Difficulty too low.")
+		return
+	}
+
+	var outputs []webcash.SecretWebcash
+	var total webcash.Amount
+	for _, code := range payload.Webcash {
+		sk, err := webcash.ParseSecretWebcash(code)
+		if err != nil {
+			write_error(w, http.StatusBadRequest, "Invalid webcash in preimage.")
+			return
+		}
+		if _, ok := s.ledger[webcash.FromSecret(sk).Hash]; ok {
+			write_error(w, http.StatusBadRequest, "Didn't use a new secret value.")
+			return
+		}
+		outputs = append(outputs, sk)
+		total += sk.Amount
+	}
+	if total != s.settings.TotalReward {
+		write_error(w, http.StatusBadRequest, "Mining amount is incorrect.")
+		return
+	}
+	for _, sk := range outputs {
+		s.ledger[webcash.FromSecret(sk).Hash] = &ledger_entry{amount: sk.Amount}
+	}
+	s.reports++
+
+	write_response(w, Response{Body: map[string]interface{}{
+		"status":            "success",
+		"difficulty_target": s.settings.Difficulty,
+	}})
+}
+
+func (s *Server) serve_replace(w http.ResponseWriter, body []byte) {
+	var request struct {
+		Inputs   []string        `json:"webcashes"`
+		Outputs  []string        `json:"new_webcashes"`
+		Legalese map[string]bool `json:"legalese"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		write_error(w, http.StatusBadRequest, "Malformed replace request.")
+		return
+	}
+	if !request.Legalese["terms"] {
+		write_error(w, http.StatusBadRequest, "Didn't accept terms.")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var inputs, outputs []webcash.PublicWebcash
+	var in_total, out_total webcash.Amount
+	seen := make(map[webcash.Uint256]bool)
+	for _, code := range request.Inputs {
+		sk, err := webcash.ParseSecretWebcash(code)
+		if err != nil {
+			write_error(w, http.StatusBadRequest, "Invalid input webcash.")
+			return
+		}
+		pk := webcash.FromSecret(sk)
+		entry, ok := s.ledger[pk.Hash]
+		if !ok || entry.spent || entry.amount != pk.Amount || seen[pk.Hash] {
+			write_error(w, http.StatusBadRequest, "Can't replace with invalid or spent webcash.")
+			return
+		}
+		seen[pk.Hash] = true
+		inputs = append(inputs, pk)
+		in_total += pk.Amount
+	}
+	for _, code := range request.Outputs {
+		sk, err := webcash.ParseSecretWebcash(code)
+		if err != nil {
+			write_error(w, http.StatusBadRequest, "Invalid output webcash.")
+			return
+		}
+		pk := webcash.FromSecret(sk)
+		if _, ok := s.ledger[pk.Hash]; ok || seen[pk.Hash] {
+			write_error(w, http.StatusBadRequest, "Output webcash already exists.")
+			return
+		}
+		seen[pk.Hash] = true
+		outputs = append(outputs, pk)
+		out_total += pk.Amount
+	}
+	if len(inputs) == 0 || len(outputs) == 0 {
+		write_error(w, http.StatusBadRequest, "Inputs and outputs must not be empty.")
+		return
+	}
+	if in_total != out_total {
+		write_error(w, http.StatusBadRequest, "Inputs and outputs must have the same value.")
+		return
+	}
+
+	for _, pk := range inputs {
+		s.ledger[pk.Hash].spent = true
+	}
+	for _, pk := range outputs {
+		s.ledger[pk.Hash] = &ledger_entry{amount: pk.Amount}
+	}
+	write_response(w, Response{Body: map[string]string{"status": "success"}})
+}
+
+func (s *Server) serve_health_check(w http.ResponseWriter, body []byte) {
+	var request []string
+	if err := json.Unmarshal(body, &request); err != nil {
+		write_error(w, http.StatusBadRequest, "Malformed health check request.")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	type result struct {
+		Spent  *bool           `json:"spent"`
+		Amount *webcash.Amount `json:"amount"`
+	}
+	results := make(map[string]result)
+	for _, code := range request {
+		pk, err := webcash.ParsePublicWebcash(code)
+		if err != nil {
+			write_error(w, http.StatusBadRequest, "Invalid public webcash.")
+			return
+		}
+		// Unknown webcash is reported with null fields.
+		var r result
+		if entry, ok := s.ledger[pk.Hash]; ok {
+			spent, amount := entry.spent, entry.amount
+			r = result{Spent: &spent, Amount: &amount}
+		}
+		results[code] = r
+	}
+	write_response(w, Response{Body: map[string]interface{}{
+		"status":  "success",
+		"results": results,
+	}})
+}
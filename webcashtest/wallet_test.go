@@ -0,0 +1,288 @@
+package webcashtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maaku/gocash/wallet"
+	"github.com/maaku/gocash/webcash"
+)
+
+// These flows drive the wallet against the mock server without mining, which
+// is left to the e2e tests, by funding the wallet directly.
+
+func strategy(t *testing.T, name string) wallet.Strategy {
+	s, err := wallet.ParseStrategy(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// TestWalletInsertPayCheck claims webcash into the wallet, pays some of it on,
+// and checks that the wallet and the server agree afterwards.
+func TestWalletInsertPayCheck(t *testing.T) {
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if _, err := h.FundWallet(10_000_000_00); err != nil {
+		t.Fatal(err)
+	}
+	received := NewSecret(5_000_000_00)
+	h.Server.Fund(received)
+	claimed, err := h.Receive(received)
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if !h.Wallet.Holds(claimed.Secret) || h.Wallet.Balance() != 15_000_000_00 {
+		t.Fatalf("after insert: balance %v, expected 15", h.Wallet.Balance())
+	}
+	// Claiming it again is refused, and leaves nothing behind.
+	if _, err := h.Receive(received); !errors.Is(err, webcash.ErrSecretAlreadySpent) {
+		t.Fatalf("inserting twice: got %v, expected ErrSecretAlreadySpent", err)
+	}
+	if h.Wallet.Balance() != 15_000_000_00 || len(h.Wallet.Unconfirmed) != 0 {
+		t.Fatalf("after a refused insert: balance %v with %d unconfirmed, expected 15 and none", h.Wallet.Balance(), len(h.Wallet.Unconfirmed))
+	}
+
+	payment, err := h.Spend(12_000_000_00, strategy(t, "fewest-inputs"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	if payment.Amount != 12_000_000_00 || h.Wallet.Balance() != 3_000_000_00 || len(h.Wallet.Unconfirmed) != 0 {
+		t.Fatalf("after paying 12: paid %v, balance %v with %d unconfirmed, expected 12, 3 and none", payment.Amount, h.Wallet.Balance(), len(h.Wallet.Unconfirmed))
+	}
+	if _, err := h.Spend(4_000_000_00, strategy(t, "fewest-inputs")); !errors.Is(err, webcash.ErrInsufficientFunds) {
+		t.Fatalf("overspending: got %v, expected ErrInsufficientFunds", err)
+	}
+	if _, err := h.Insert(payment); err != nil {
+		t.Fatalf("the recipient could not claim the payment: %v", err)
+	}
+
+	result, err := h.CheckWallet()
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(result.Archived) != 0 || len(result.Mismatched) != 0 || len(result.Confirmed) != 0 {
+		t.Errorf("check of a settled wallet archived %d, confirmed %d and found %d mismatched, expected none", len(result.Archived), len(result.Confirmed), len(result.Mismatched))
+	}
+	for _, chain := range result.Repaired {
+		t.Errorf("check of a settled wallet repaired the %v chain", chain.Chain)
+	}
+	if h.Wallet.Balance() != 3_000_000_00 {
+		t.Errorf("after check: balance %v, expected 3", h.Wallet.Balance())
+	}
+}
+
+// TestWalletCheckSettles checks that check settles what a lost response or
+// another copy of the wallet left unsettled.
+func TestWalletCheckSettles(t *testing.T) {
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	funds, err := h.FundWallet(10_000_000_00)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A replacement is made, but its response is lost, so the wallet
+	// still holds the input and the output is unconfirmed.
+	output, err := h.Wallet.NewOutput(wallet.Change, funds.Amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Client.Replace([]webcash.SecretWebcash{funds}, []webcash.SecretWebcash{output}); err != nil {
+		t.Fatal(err)
+	}
+	// Another copy of the wallet receives webcash beyond this one's depth.
+	other := *h.Wallet
+	other.WalletDepths = map[string]uint64{}
+	for chain, depth := range h.Wallet.WalletDepths {
+		other.WalletDepths[chain] = depth
+	}
+	secret, err := other.NextSecret(wallet.Receive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elsewhere := webcash.SecretWebcash{Secret: secret, Amount: 2_000_000_00}
+	h.Server.Fund(elsewhere)
+	// And the wallet holds a secret which the server does not know of.
+	unknown := NewSecret(1_000_000_00)
+	h.Wallet.Webcash = append(h.Wallet.Webcash, unknown)
+
+	result, err := h.CheckWallet()
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(result.Archived) != 1 || result.Archived[0] != funds {
+		t.Errorf("archived %v, expected the spent input", result.Archived)
+	}
+	if len(result.Confirmed) != 1 || result.Confirmed[0] != output {
+		t.Errorf("confirmed %v, expected the unconfirmed output", result.Confirmed)
+	}
+	if len(result.Mismatched) != 1 || result.Mismatched[0].Held != unknown {
+		t.Errorf("mismatched %v, expected the unknown secret", result.Mismatched)
+	}
+	if len(result.Repaired) != 1 || result.Repaired[0].Chain != wallet.Receive || len(result.Repaired[0].Found) != 1 {
+		t.Errorf("repaired %v, expected the receive chain, with one found", result.Repaired)
+	}
+	if depth := h.Wallet.WalletDepths[wallet.Receive.String()]; depth != other.WalletDepths[wallet.Receive.String()] {
+		t.Errorf("receive chain at depth %d, expected %d", depth, other.WalletDepths[wallet.Receive.String()])
+	}
+	if want := output.Amount + elsewhere.Amount + unknown.Amount; h.Wallet.Balance() != want || len(h.Wallet.Unconfirmed) != 0 {
+		t.Errorf("after check: balance %v with %d unconfirmed, expected %v and none", h.Wallet.Balance(), len(h.Wallet.Unconfirmed), want)
+	}
+	if !h.Wallet.Holds(elsewhere.Secret) || h.Wallet.Holds(funds.Secret) {
+		t.Error("check did not take the webcash found, or kept the spent input")
+	}
+}
+
+// TestWalletUnsavedPayment checks that a payment whose replacement was made,
+// but not saved, is left to its recipient by check rather than taken back.
+func TestWalletUnsavedPayment(t *testing.T) {
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	funds, err := h.FundWallet(10_000_000_00)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The wallet is saved with the outputs unconfirmed, the replacement is
+	// made and the payment handed out, but the save after it fails.
+	payment, err := h.Wallet.NewOutput(wallet.Pay, 4_000_000_00)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, err := h.Wallet.NewOutput(wallet.Change, 6_000_000_00)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Client.Replace([]webcash.SecretWebcash{funds}, []webcash.SecretWebcash{change, payment}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := h.CheckWallet()
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(result.Confirmed) != 1 || result.Confirmed[0] != change {
+		t.Errorf("confirmed %v, expected the change", result.Confirmed)
+	}
+	if len(result.Paid) != 1 || result.Paid[0] != payment {
+		t.Errorf("paid %v, expected the payment", result.Paid)
+	}
+	if h.Wallet.Holds(payment.Secret) || h.Wallet.Balance() != change.Amount || len(h.Wallet.Unconfirmed) != 0 {
+		t.Errorf("after check: balance %v with %d unconfirmed, expected %v and none, without the payment", h.Wallet.Balance(), len(h.Wallet.Unconfirmed), change.Amount)
+	}
+	if len(result.Repaired) != 0 {
+		t.Errorf("repaired %v, expected nothing", result.Repaired)
+	}
+	if _, err := h.Insert(payment); err != nil {
+		t.Errorf("the recipient could not claim the payment: %v", err)
+	}
+}
+
+// TestWalletRecover recovers a wallet from its master secret, which finds what
+// it holds but leaves payments which have yet to be claimed to their
+// recipients, unless it is asked to sweep them.
+func TestWalletRecover(t *testing.T) {
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if _, err := h.FundWallet(10_000_000_00); err != nil {
+		t.Fatal(err)
+	}
+	received := NewSecret(1_000_000_00)
+	h.Server.Fund(received)
+	if _, err := h.Receive(received); err != nil {
+		t.Fatal(err)
+	}
+	claimed, err := h.Spend(3_000_000_00, strategy(t, "minimize-change"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Insert(claimed); err != nil {
+		t.Fatal(err)
+	}
+	unclaimed, err := h.Spend(2_000_000_00, strategy(t, "privacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sweep := range []bool{false, true} {
+		recovered, results, err := h.RecoverWallet(sweep)
+		if err != nil {
+			t.Fatalf("sweep=%v: recover failed: %v", sweep, err)
+		}
+		want := h.Wallet.Balance()
+		if sweep {
+			want += unclaimed.Amount
+		}
+		if got := recovered.Balance(); got != want {
+			t.Errorf("sweep=%v: recovered a balance of %v, expected %v", sweep, got, want)
+		}
+		if recovered.Holds(unclaimed.Secret) != sweep {
+			t.Errorf("sweep=%v: holds the unclaimed payment: %v", sweep, !sweep)
+		}
+		for _, sk := range h.Wallet.Webcash {
+			if !recovered.Holds(sk.Secret) {
+				t.Errorf("sweep=%v: %v was not recovered", sweep, webcash.FromSecret(sk))
+			}
+		}
+		for _, result := range results {
+			if want := h.Wallet.WalletDepths[result.Chain.String()]; result.Depth != want {
+				t.Errorf("sweep=%v: %v chain recovered at depth %d, expected %d", sweep, result.Chain, result.Depth, want)
+			}
+			if result.Chain == wallet.Pay && (len(result.Found) != 1 || result.Taken != sweep) {
+				t.Errorf("sweep=%v: pay chain found %d unspent, taken=%v", sweep, len(result.Found), result.Taken)
+			}
+		}
+	}
+}
+
+// TestWalletRefusedReplace checks that a replacement the server refuses
+// leaves the wallet as it was, with no outputs left unconfirmed.
+func TestWalletRefusedReplace(t *testing.T) {
+	h, err := NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if _, err := h.FundWallet(10_000_000_00); err != nil {
+		t.Fatal(err)
+	}
+	h.Server.Enqueue("/api/v1/replace", Response{Status: 500, Body: map[string]string{"error": "Internal error."}})
+	if _, err := h.Spend(1_000_000_00, strategy(t, "fewest-inputs")); err == nil {
+		t.Fatal("payment succeeded despite a server error")
+	}
+	// A server error leaves it unknown whether the replacement happened.
+	if h.Wallet.Balance() != 10_000_000_00 || len(h.Wallet.Unconfirmed) != 2 {
+		t.Fatalf("after a server error: balance %v with %d unconfirmed, expected 10 and 2", h.Wallet.Balance(), len(h.Wallet.Unconfirmed))
+	}
+	result, err := h.CheckWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Confirmed) != 0 || len(result.Archived) != 0 || len(h.Wallet.Unconfirmed) != 2 {
+		t.Errorf("check confirmed %d and archived %d of a replacement which did not happen", len(result.Confirmed), len(result.Archived))
+	}
+
+	if _, err := h.Receive(NewSecret(1_000_000_00)); err == nil {
+		t.Fatal("inserting unknown webcash succeeded")
+	}
+	if h.Wallet.Balance() != 10_000_000_00 || len(h.Wallet.Unconfirmed) != 2 {
+		t.Errorf("after a refused insert: balance %v with %d unconfirmed, expected 10 and 2", h.Wallet.Balance(), len(h.Wallet.Unconfirmed))
+	}
+}